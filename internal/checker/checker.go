@@ -0,0 +1,109 @@
+// Copyright 2021 E99p1ant. All rights reserved.
+// Use of this source code is governed by an AGPL-style
+// license that can be found in the LICENSE file.
+
+package checker
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+
+	"github.com/pkg/errors"
+
+	"github.com/vidar-team/Cardinal/internal/db"
+)
+
+// Checker checks whether the service of the game box works normally.
+// The methods return *Error to report the check status, the other errors are regarded as the internal error of the checker.
+type Checker interface {
+	// PutFlag puts the flag of the current round into the service of the game box.
+	PutFlag(ctx context.Context, gameBox *db.GameBox, flag *db.Flag) error
+	// GetFlag checks the flag put into the service can be retrieved.
+	GetFlag(ctx context.Context, gameBox *db.GameBox, flag *db.Flag) error
+	// Check checks the functions of the service in the given round, which is the SLA check.
+	Check(ctx context.Context, gameBox *db.GameBox, round uint) error
+}
+
+// Error is the check error with the status reported by the checker.
+// The message is shown to the team owning the game box, while the debug output is only shown to the managers.
+type Error struct {
+	Status  db.CheckStatus
+	Message string
+	Debug   string
+}
+
+func (e *Error) Error() string {
+	if e.Message == "" {
+		return string(e.Status)
+	}
+	return fmt.Sprintf("%s: %s", e.Status, e.Message)
+}
+
+// StatusOf returns the check status of the error returned by the checker.
+func StatusOf(err error) db.CheckStatus {
+	if err == nil {
+		return db.CheckStatusOK
+	}
+
+	var checkErr *Error
+	if errors.As(err, &checkErr) {
+		return checkErr.Status
+	}
+	return db.CheckStatusError
+}
+
+const (
+	CheckerTCP  = "tcp"
+	CheckerExec = "exec"
+	CheckerHTTP = "http"
+)
+
+var (
+	checkersMu sync.RWMutex
+	checkers   = map[string]Checker{
+		CheckerTCP:  tcpChecker{},
+		CheckerExec: execChecker{},
+		CheckerHTTP: httpChecker{},
+	}
+)
+
+// Register registers the checker with the given name,
+// the existing checker with the same name will be replaced.
+func Register(name string, checker Checker) {
+	checkersMu.Lock()
+	defer checkersMu.Unlock()
+	checkers[name] = checker
+}
+
+var ErrCheckerNotExists = errors.New("checker does not exist")
+
+// Get returns the checker with the given name.
+func Get(name string) (Checker, error) {
+	checkersMu.RLock()
+	defer checkersMu.RUnlock()
+
+	checker, ok := checkers[name]
+	if !ok {
+		return nil, ErrCheckerNotExists
+	}
+	return checker, nil
+}
+
+// tcpChecker only checks the port of the game box is open.
+type tcpChecker struct{}
+
+func (tcpChecker) PutFlag(context.Context, *db.GameBox, *db.Flag) error { return nil }
+
+func (tcpChecker) GetFlag(context.Context, *db.GameBox, *db.Flag) error { return nil }
+
+func (tcpChecker) Check(ctx context.Context, gameBox *db.GameBox, _ uint) error {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(gameBox.IPAddress, strconv.Itoa(int(gameBox.Port))))
+	if err != nil {
+		return &Error{Status: db.CheckStatusDown, Message: "connection failed", Debug: err.Error()}
+	}
+	return conn.Close()
+}
@@ -0,0 +1,187 @@
+// Copyright 2021 E99p1ant. All rights reserved.
+// Use of this source code is governed by an AGPL-style
+// license that can be found in the LICENSE file.
+
+package checker
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/vidar-team/Cardinal/internal/conf"
+	"github.com/vidar-team/Cardinal/internal/db"
+)
+
+type testChecker struct {
+	calls   []string
+	failure string
+}
+
+func (c *testChecker) call(name string) error {
+	c.calls = append(c.calls, name)
+	if name == c.failure {
+		return errors.New("mumble")
+	}
+	return nil
+}
+
+func (c *testChecker) PutFlag(context.Context, *db.GameBox, *db.Flag) error { return c.call("put") }
+
+func (c *testChecker) GetFlag(context.Context, *db.GameBox, *db.Flag) error { return c.call("get") }
+
+func (c *testChecker) Check(context.Context, *db.GameBox, uint) error { return c.call("check") }
+
+func TestGet(t *testing.T) {
+	_, err := Get(CheckerTCP)
+	assert.Nil(t, err)
+	_, err = Get("not-exist")
+	assert.Equal(t, ErrCheckerNotExists, err)
+
+	checker := &testChecker{}
+	Register("test", checker)
+	got, err := Get("test")
+	assert.Nil(t, err)
+	assert.Equal(t, checker, got)
+}
+
+func TestTCPChecker(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	addr := listener.Addr().(*net.TCPAddr)
+
+	gameBox := &db.GameBox{IPAddress: "127.0.0.1", Port: uint(addr.Port)}
+	assert.Nil(t, tcpChecker{}.Check(context.Background(), gameBox, 1))
+
+	// The port is closed.
+	assert.Nil(t, listener.Close())
+	err = tcpChecker{}.Check(context.Background(), gameBox, 1)
+	assert.Equal(t, db.CheckStatusDown, StatusOf(err))
+}
+
+func TestCheck(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		flag      *db.Flag
+		failure   string
+		wantCalls []string
+		wantErr   string
+	}{
+		{name: "ok", flag: &db.Flag{}, wantCalls: []string{"put", "get", "check"}},
+		{name: "no flag", wantCalls: []string{"check"}},
+		{name: "put flag failed", flag: &db.Flag{}, failure: "put", wantCalls: []string{"put"}, wantErr: "put flag: mumble"},
+		{name: "get flag failed", flag: &db.Flag{}, failure: "get", wantCalls: []string{"put", "get"}, wantErr: "get flag: mumble"},
+		{name: "check failed", flag: &db.Flag{}, failure: "check", wantCalls: []string{"put", "get", "check"}, wantErr: "check: mumble"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			checker := &testChecker{failure: tc.failure}
+			err := check(context.Background(), 1, Target{
+				Checker: checker,
+				GameBox: &db.GameBox{},
+				Flag:    tc.flag,
+			})
+			if tc.wantErr == "" {
+				assert.Nil(t, err)
+			} else {
+				assert.EqualError(t, err, tc.wantErr)
+			}
+			assert.Equal(t, tc.wantCalls, checker.calls)
+		})
+	}
+}
+
+func TestCheckAll(t *testing.T) {
+	targets := make([]Target, 0, 20)
+	for i := uint(1); i <= 20; i++ {
+		targets = append(targets, Target{
+			Checker: &testChecker{},
+			GameBox: &db.GameBox{TeamID: i, ChallengeID: 1},
+			Flag:    &db.Flag{},
+		})
+	}
+
+	results := CheckAll(context.Background(), 3, targets)
+	assert.Len(t, results, 20)
+	for i, result := range results {
+		assert.Equal(t, uint(i+1), result.TeamID)
+		assert.Equal(t, uint(3), result.Round)
+		assert.Equal(t, db.CheckStatusOK, result.Status)
+	}
+}
+
+type checkerFunc func(ctx context.Context) error
+
+func (f checkerFunc) PutFlag(ctx context.Context, _ *db.GameBox, _ *db.Flag) error { return f(ctx) }
+
+func (f checkerFunc) GetFlag(ctx context.Context, _ *db.GameBox, _ *db.Flag) error { return f(ctx) }
+
+func (f checkerFunc) Check(ctx context.Context, _ *db.GameBox, _ uint) error { return f(ctx) }
+
+func TestCheckOne(t *testing.T) {
+	conf.Game.CheckerTimeout = 1
+	t.Cleanup(func() {
+		conf.Game.CheckerTimeout = 0
+	})
+
+	for _, tc := range []struct {
+		name               string
+		checker            checkerFunc
+		wantStatus         db.CheckStatus
+		wantPublicMessage  string
+		wantPrivateMessage string
+	}{
+		{
+			name:       "ok",
+			checker:    func(context.Context) error { return nil },
+			wantStatus: db.CheckStatusOK,
+		},
+		{
+			name: "corrupt",
+			checker: func(context.Context) error {
+				return &Error{Status: db.CheckStatusCorrupt, Message: "flag not found", Debug: "got d3ctf{other}"}
+			},
+			wantStatus:         db.CheckStatusCorrupt,
+			wantPublicMessage:  "flag not found",
+			wantPrivateMessage: "put flag: CORRUPT: flag not found\ngot d3ctf{other}",
+		},
+		{
+			name:               "internal error",
+			checker:            func(context.Context) error { return errors.New("checker crashed") },
+			wantStatus:         db.CheckStatusError,
+			wantPrivateMessage: "put flag: checker crashed",
+		},
+		{
+			name: "checker error",
+			checker: func(context.Context) error {
+				return &Error{Status: db.CheckStatusError, Message: "database is down"}
+			},
+			wantStatus:         db.CheckStatusError,
+			wantPrivateMessage: "put flag: ERROR: database is down",
+		},
+		{
+			name: "timeout",
+			checker: func(ctx context.Context) error {
+				<-ctx.Done()
+				return ctx.Err()
+			},
+			wantStatus:         db.CheckStatusDown,
+			wantPublicMessage:  "timeout",
+			wantPrivateMessage: "timeout: put flag: context deadline exceeded",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got := CheckOne(context.Background(), 2, Target{
+				Checker: tc.checker,
+				GameBox: &db.GameBox{TeamID: 1, ChallengeID: 1},
+				Flag:    &db.Flag{},
+			})
+			assert.Equal(t, tc.wantStatus, got.Status)
+			assert.Equal(t, tc.wantPublicMessage, got.PublicMessage)
+			assert.Equal(t, tc.wantPrivateMessage, got.PrivateMessage)
+			assert.Equal(t, uint(2), got.Round)
+		})
+	}
+}
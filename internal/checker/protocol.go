@@ -0,0 +1,267 @@
+// Copyright 2021 E99p1ant. All rights reserved.
+// Use of this source code is governed by an AGPL-style
+// license that can be found in the LICENSE file.
+
+package checker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/vidar-team/Cardinal/internal/db"
+)
+
+// The actions which the external checker is invoked for.
+const (
+	ActionPutFlag = "put"
+	ActionGetFlag = "get"
+	ActionCheck   = "check"
+)
+
+// Request is the parameters passed to the external checker. The flag is empty for the check action.
+type Request struct {
+	Action    string `json:"Action"`
+	IP        string `json:"IP"`
+	Port      uint   `json:"Port"`
+	Flag      string `json:"Flag"`
+	Round     uint   `json:"Round"`
+	TeamID    uint   `json:"TeamID"`
+	GameBoxID uint   `json:"GameBoxID"`
+}
+
+func newRequest(action string, gameBox *db.GameBox, flag *db.Flag, round uint) *Request {
+	req := &Request{
+		Action:    action,
+		IP:        gameBox.IPAddress,
+		Port:      gameBox.Port,
+		Round:     round,
+		TeamID:    gameBox.TeamID,
+		GameBoxID: gameBox.ID,
+	}
+	if flag != nil {
+		req.Flag = flag.Value
+		req.Round = flag.Round
+	}
+	return req
+}
+
+// Response is the result reported by the external checker. The message is shown to the team owning
+// the game box, e.g. "flag not found", the debug output is only shown to the managers.
+type Response struct {
+	Status  db.CheckStatus `json:"Status"`
+	Message string         `json:"Message"`
+	Debug   string         `json:"Debug"`
+}
+
+// parseResponse parses the JSON response of the external checker, and returns the error of the status.
+func parseResponse(data []byte) error {
+	var resp Response
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return errors.Wrapf(err, "unmarshal response %q", data)
+	}
+
+	switch resp.Status {
+	case db.CheckStatusOK:
+		return nil
+	case db.CheckStatusMumble, db.CheckStatusCorrupt, db.CheckStatusDown, db.CheckStatusError:
+		return &Error{Status: resp.Status, Message: resp.Message, Debug: resp.Debug}
+	default:
+		return errors.Errorf("unexpected status %q", resp.Status)
+	}
+}
+
+// execChecker runs the executable set in the checker config of the challenge for each action:
+//
+//	<command> <action> <ip> <port> [<flag>]
+//
+// The request is also passed by the environment variables CARDINAL_ACTION, CARDINAL_IP, CARDINAL_PORT,
+// CARDINAL_FLAG, CARDINAL_ROUND, CARDINAL_TEAM_ID and CARDINAL_GAMEBOX_ID. The executable prints the
+// response in JSON as the last line of the stdout, e.g. {"Status": "MUMBLE", "Message": "login failed"}.
+// The stderr is appended to the debug output of the response.
+type execChecker struct{}
+
+func (c execChecker) PutFlag(ctx context.Context, gameBox *db.GameBox, flag *db.Flag) error {
+	return c.run(ctx, gameBox, newRequest(ActionPutFlag, gameBox, flag, 0))
+}
+
+func (c execChecker) GetFlag(ctx context.Context, gameBox *db.GameBox, flag *db.Flag) error {
+	return c.run(ctx, gameBox, newRequest(ActionGetFlag, gameBox, flag, 0))
+}
+
+func (c execChecker) Check(ctx context.Context, gameBox *db.GameBox, round uint) error {
+	return c.run(ctx, gameBox, newRequest(ActionCheck, gameBox, nil, round))
+}
+
+func (execChecker) run(ctx context.Context, gameBox *db.GameBox, req *Request) error {
+	if gameBox.Challenge == nil {
+		return errors.New("challenge of the game box is not loaded")
+	}
+	command := strings.Fields(gameBox.Challenge.CheckerConfig)
+	if len(command) == 0 {
+		return errors.New("empty checker command")
+	}
+
+	args := append(command[1:], req.Action, req.IP, strconv.Itoa(int(req.Port)))
+	if req.Flag != "" {
+		args = append(args, req.Flag)
+	}
+
+	cmd := exec.CommandContext(ctx, command[0], args...)
+	cmd.Env = append(os.Environ(),
+		"CARDINAL_ACTION="+req.Action,
+		"CARDINAL_IP="+req.IP,
+		"CARDINAL_PORT="+strconv.Itoa(int(req.Port)),
+		"CARDINAL_FLAG="+req.Flag,
+		"CARDINAL_ROUND="+strconv.Itoa(int(req.Round)),
+		"CARDINAL_TEAM_ID="+strconv.Itoa(int(req.TeamID)),
+		"CARDINAL_GAMEBOX_ID="+strconv.Itoa(int(req.GameBoxID)),
+	)
+	stdout, stderr, runErr := runCommand(cmd)
+
+	lines := strings.Split(strings.TrimSpace(stdout.String()), "\n")
+	if last := strings.TrimSpace(lines[len(lines)-1]); last != "" {
+		err := parseResponse([]byte(last))
+		var checkErr *Error
+		if errors.As(err, &checkErr) && stderr.Len() != 0 {
+			checkErr.Debug = strings.TrimSpace(checkErr.Debug + "\n" + stderr.String())
+		}
+		return err
+	}
+	if runErr != nil {
+		return errors.Wrapf(runErr, "run checker: %s", strings.TrimSpace(stderr.String()))
+	}
+	return errors.New("empty checker response")
+}
+
+const (
+	// outputWaitDelay is the max duration to read the output after the checker exits.
+	outputWaitDelay = time.Second
+	// maxOutputSize is the max size of the stdout and the stderr of the command checker,
+	// the output beyond it is discarded.
+	maxOutputSize = 1 << 20
+)
+
+// runCommand runs the command and returns its stdout and stderr.
+//
+// The output is read through the pipes created by ourselves rather than exec.Cmd, so the pipes
+// can be closed after the checker exits, even if its child processes still hold the output.
+// Otherwise the checker will never return until the child processes exit.
+func runCommand(cmd *exec.Cmd) (stdout, stderr *bytes.Buffer, _ error) {
+	stdout, stderr = new(bytes.Buffer), new(bytes.Buffer)
+
+	stdoutReader, stdoutWriter, err := os.Pipe()
+	if err != nil {
+		return stdout, stderr, errors.Wrap(err, "create stdout pipe")
+	}
+	defer func() { _ = stdoutReader.Close() }()
+	stderrReader, stderrWriter, err := os.Pipe()
+	if err != nil {
+		_ = stdoutWriter.Close()
+		return stdout, stderr, errors.Wrap(err, "create stderr pipe")
+	}
+	defer func() { _ = stderrReader.Close() }()
+
+	cmd.Stdout = stdoutWriter
+	cmd.Stderr = stderrWriter
+	err = cmd.Start()
+	// The writers are inherited by the checker, the readers get EOF after all the processes holding them exit.
+	_ = stdoutWriter.Close()
+	_ = stderrWriter.Close()
+	if err != nil {
+		return stdout, stderr, err
+	}
+
+	copied := make(chan struct{}, 2)
+	go func() {
+		copyOutput(stdout, stdoutReader)
+		copied <- struct{}{}
+	}()
+	go func() {
+		copyOutput(stderr, stderrReader)
+		copied <- struct{}{}
+	}()
+
+	// The checker is killed when the context is done.
+	err = cmd.Wait()
+
+	timer := time.NewTimer(outputWaitDelay)
+	defer timer.Stop()
+	for i := 0; i < 2; i++ {
+		select {
+		case <-copied:
+		case <-timer.C:
+			// Interrupt the reading of the output held by the child processes.
+			_ = stdoutReader.Close()
+			_ = stderrReader.Close()
+			<-copied
+		}
+	}
+	return stdout, stderr, err
+}
+
+// copyOutput copies at most maxOutputSize bytes of the output into the buffer. The rest is still read
+// and discarded, so the checker is not blocked on writing to the full pipe.
+func copyOutput(buf *bytes.Buffer, r io.Reader) {
+	_, _ = io.Copy(buf, io.LimitReader(r, maxOutputSize))
+	_, _ = io.Copy(io.Discard, r)
+}
+
+// httpChecker posts the request in JSON to the URL set in the checker config of the challenge,
+// and the checker responds the JSON response with the status code 200.
+type httpChecker struct{}
+
+func (c httpChecker) PutFlag(ctx context.Context, gameBox *db.GameBox, flag *db.Flag) error {
+	return c.post(ctx, gameBox, newRequest(ActionPutFlag, gameBox, flag, 0))
+}
+
+func (c httpChecker) GetFlag(ctx context.Context, gameBox *db.GameBox, flag *db.Flag) error {
+	return c.post(ctx, gameBox, newRequest(ActionGetFlag, gameBox, flag, 0))
+}
+
+func (c httpChecker) Check(ctx context.Context, gameBox *db.GameBox, round uint) error {
+	return c.post(ctx, gameBox, newRequest(ActionCheck, gameBox, nil, round))
+}
+
+// maxResponseSize is the max size of the response body of the HTTP checker.
+const maxResponseSize = 1 << 20
+
+func (httpChecker) post(ctx context.Context, gameBox *db.GameBox, req *Request) error {
+	if gameBox.Challenge == nil {
+		return errors.New("challenge of the game box is not loaded")
+	}
+
+	body, err := json.Marshal(req)
+	if err != nil {
+		return errors.Wrap(err, "marshal request")
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, gameBox.Challenge.CheckerConfig, bytes.NewReader(body))
+	if err != nil {
+		return errors.Wrap(err, "new request")
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(httpReq)
+	if err != nil {
+		return errors.Wrap(err, "post")
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	if err != nil {
+		return errors.Wrap(err, "read response")
+	}
+	if resp.StatusCode != http.StatusOK {
+		return errors.Errorf("unexpected status code %d: %s", resp.StatusCode, bytes.TrimSpace(data))
+	}
+	return parseResponse(data)
+}
@@ -0,0 +1,189 @@
+// Copyright 2021 E99p1ant. All rights reserved.
+// Use of this source code is governed by an AGPL-style
+// license that can be found in the LICENSE file.
+
+package checker
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/vidar-team/Cardinal/internal/db"
+)
+
+// stubScript is a checker which stores the flag in a file, and reports the status set in the CHECK_STATUS file.
+const stubScript = `#!/bin/sh
+dir=$(dirname "$0")
+case "$1" in
+put)
+	echo "$4" > "$dir/flag"
+	echo "put $2:$3" >&2
+	echo '{"Status": "OK"}'
+	;;
+get)
+	if [ "$(cat "$dir/flag")" = "$CARDINAL_FLAG" ]; then
+		echo '{"Status": "OK"}'
+	else
+		echo "want $CARDINAL_FLAG" >&2
+		echo '{"Status": "CORRUPT", "Message": "flag not found", "Debug": "flag mismatch"}'
+	fi
+	;;
+check)
+	echo "checking round $CARDINAL_ROUND of game box $CARDINAL_GAMEBOX_ID"
+	printf '{"Status": "%s", "Message": "round %s"}\n' "$(cat "$dir/status")" "$CARDINAL_ROUND"
+	;;
+crash)
+	exit 1
+	;;
+orphan)
+	sleep 10 &
+	echo '{"Status": "OK"}'
+	;;
+hang)
+	sleep 10 &
+	sleep 10
+	;;
+esac
+`
+
+func TestExecChecker(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("The stub checker is a shell script")
+	}
+
+	dir := t.TempDir()
+	script := filepath.Join(dir, "checker.sh")
+	err := os.WriteFile(script, []byte(stubScript), 0755)
+	assert.Nil(t, err)
+	err = os.WriteFile(filepath.Join(dir, "status"), []byte("MUMBLE"), 0644)
+	assert.Nil(t, err)
+
+	ctx := context.Background()
+	gameBox := &db.GameBox{
+		TeamID:    1,
+		IPAddress: "127.0.0.1",
+		Port:      8080,
+		Challenge: &db.Challenge{CheckerConfig: script},
+	}
+	gameBox.ID = 3
+	flag := &db.Flag{Value: "d3ctf{exec}", Round: 2}
+
+	checker := execChecker{}
+	assert.Nil(t, checker.PutFlag(ctx, gameBox, flag))
+	assert.Nil(t, checker.GetFlag(ctx, gameBox, flag))
+
+	err = checker.GetFlag(ctx, gameBox, &db.Flag{Value: "d3ctf{other}", Round: 2})
+	assert.Equal(t, &Error{Status: db.CheckStatusCorrupt, Message: "flag not found", Debug: "flag mismatch\nwant d3ctf{other}"}, err)
+
+	err = checker.Check(ctx, gameBox, 2)
+	assert.Equal(t, &Error{Status: db.CheckStatusMumble, Message: "round 2"}, err)
+
+	// The checker crashed without response.
+	err = checker.run(ctx, gameBox, &Request{Action: "crash"})
+	assert.NotNil(t, err)
+	assert.Equal(t, db.CheckStatusError, StatusOf(err))
+
+	// The child process holding the output doesn't block the checker.
+	startAt := time.Now()
+	err = checker.run(ctx, gameBox, &Request{Action: "orphan"})
+	assert.Nil(t, err)
+	assert.Less(t, int64(time.Since(startAt)), int64(5*time.Second))
+
+	// The checker is killed when the context is done.
+	startAt = time.Now()
+	timeoutCtx, cancel := context.WithTimeout(ctx, 200*time.Millisecond)
+	defer cancel()
+	err = checker.run(timeoutCtx, gameBox, &Request{Action: "hang"})
+	assert.Equal(t, db.CheckStatusError, StatusOf(err))
+	assert.Less(t, int64(time.Since(startAt)), int64(5*time.Second))
+
+	// The checker is not executable.
+	gameBox.Challenge.CheckerConfig = filepath.Join(dir, "not-exist")
+	assert.Equal(t, db.CheckStatusError, StatusOf(checker.Check(ctx, gameBox, 2)))
+}
+
+func TestRunCommand(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("The command is a shell script")
+	}
+
+	// The output beyond the max size is discarded without blocking the command.
+	cmd := exec.Command("sh", "-c", "head -c 3145728 /dev/zero; echo error >&2")
+	stdout, stderr, err := runCommand(cmd)
+	assert.Nil(t, err)
+	assert.Equal(t, maxOutputSize, stdout.Len())
+	assert.Equal(t, "error\n", stderr.String())
+}
+
+func TestHTTPChecker(t *testing.T) {
+	var stored string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req Request
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		resp := Response{Status: db.CheckStatusOK}
+		switch req.Action {
+		case ActionPutFlag:
+			stored = req.Flag
+		case ActionGetFlag:
+			if req.Flag != stored {
+				resp = Response{Status: db.CheckStatusCorrupt, Message: "flag not found"}
+			}
+		case ActionCheck:
+			if req.Round == 3 {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			if req.IP != "127.0.0.1" || req.Port != 8080 || req.GameBoxID != 3 {
+				resp = Response{Status: db.CheckStatusDown}
+			}
+		}
+		_ = json.NewEncoder(w).Encode(resp)
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	gameBox := &db.GameBox{
+		TeamID:    1,
+		IPAddress: "127.0.0.1",
+		Port:      8080,
+		Challenge: &db.Challenge{CheckerConfig: server.URL},
+	}
+	gameBox.ID = 3
+	flag := &db.Flag{Value: "d3ctf{http}", Round: 2}
+
+	checker := httpChecker{}
+	assert.Nil(t, checker.PutFlag(ctx, gameBox, flag))
+	assert.Nil(t, checker.GetFlag(ctx, gameBox, flag))
+	assert.Nil(t, checker.Check(ctx, gameBox, 2))
+
+	err := checker.GetFlag(ctx, gameBox, &db.Flag{Value: "d3ctf{other}", Round: 2})
+	assert.Equal(t, &Error{Status: db.CheckStatusCorrupt, Message: "flag not found"}, err)
+
+	// The checker responds with an unexpected status code.
+	assert.Equal(t, db.CheckStatusError, StatusOf(checker.Check(ctx, gameBox, 3)))
+
+	gameBox.Port = 8081
+	assert.Equal(t, db.CheckStatusDown, StatusOf(checker.Check(ctx, gameBox, 2)))
+}
+
+func TestParseResponse(t *testing.T) {
+	assert.Nil(t, parseResponse([]byte(`{"Status": "OK"}`)))
+	assert.Equal(t, &Error{Status: db.CheckStatusError, Message: "database is down"}, parseResponse([]byte(`{"Status": "ERROR", "Message": "database is down"}`)))
+	assert.Equal(t, &Error{Status: db.CheckStatusDown, Message: "connection refused", Debug: "dial tcp: refused"}, parseResponse([]byte(`{"Status": "DOWN", "Message": "connection refused", "Debug": "dial tcp: refused"}`)))
+	assert.Equal(t, db.CheckStatusError, StatusOf(parseResponse([]byte(`{"Status": "UNKNOWN"}`))))
+	assert.Equal(t, db.CheckStatusError, StatusOf(parseResponse([]byte(`OK`))))
+}
@@ -0,0 +1,270 @@
+// Copyright 2021 E99p1ant. All rights reserved.
+// Use of this source code is governed by an AGPL-style
+// license that can be found in the LICENSE file.
+
+package checker
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	log "unknwon.dev/clog/v2"
+
+	"github.com/vidar-team/Cardinal/internal/conf"
+	"github.com/vidar-team/Cardinal/internal/db"
+	"github.com/vidar-team/Cardinal/internal/livelog"
+	"github.com/vidar-team/Cardinal/internal/misc/webhook"
+	"github.com/vidar-team/Cardinal/internal/timeutil"
+)
+
+const (
+	// DefaultTimeout is the default max duration to check the service of each game box.
+	DefaultTimeout = 10 * time.Second
+	// DefaultConcurrency is the default max number of the game boxes which are checked at the same time.
+	DefaultConcurrency = 10
+)
+
+// Target is the game box to be checked with the checker, the flag may be nil
+// if it has not been generated, then only the SLA check is run.
+type Target struct {
+	Checker Checker
+	GameBox *db.GameBox
+	Flag    *db.Flag
+}
+
+// Result is the check result of the game box.
+type Result struct {
+	GameBoxID   uint           `json:"GameBoxID"`
+	TeamID      uint           `json:"TeamID"`
+	ChallengeID uint           `json:"ChallengeID"`
+	Round       uint           `json:"Round"`
+	Status      db.CheckStatus `json:"Status"`
+	// PublicMessage is shown to the team owning the game box, PrivateMessage is only shown to the managers.
+	PublicMessage  string `json:"PublicMessage"`
+	PrivateMessage string `json:"PrivateMessage"`
+	Latency        int64  `json:"Latency"` // In milliseconds.
+}
+
+// Run checks all the visible game boxes whose challenge has a checker in the given round, saves the
+// check results, and checks down the game boxes which failed. It is called when a new round starts.
+func Run(ctx context.Context, round uint) error {
+	gameBoxes, err := db.GameBoxes.Get(ctx, db.GetGameBoxesOption{
+		Visible: true,
+	})
+	if err != nil {
+		return errors.Wrap(err, "get game boxes")
+	}
+
+	flags, _, err := db.Flags.Get(ctx, db.GetFlagOptions{
+		Round: round,
+	})
+	if err != nil {
+		return errors.Wrap(err, "get flags")
+	}
+	gameBoxFlags := make(map[uint]*db.Flag, len(flags))
+	for _, flag := range flags {
+		gameBoxFlags[flag.GameBoxID] = flag
+	}
+
+	targets := make([]Target, 0, len(gameBoxes))
+	for _, gameBox := range gameBoxes {
+		if gameBox.Challenge == nil || gameBox.Challenge.Checker == "" {
+			continue
+		}
+
+		checker, err := Get(gameBox.Challenge.Checker)
+		if err != nil {
+			log.Error("Failed to get checker %q of challenge %d: %v", gameBox.Challenge.Checker, gameBox.ChallengeID, err)
+			continue
+		}
+		targets = append(targets, Target{
+			Checker: checker,
+			GameBox: gameBox,
+			Flag:    gameBoxFlags[gameBox.ID],
+		})
+	}
+	if len(targets) == 0 {
+		return nil
+	}
+
+	results := CheckAll(ctx, round, targets)
+
+	resultOptions := make([]db.CreateCheckResultOptions, 0, len(results))
+	for _, result := range results {
+		resultOptions = append(resultOptions, db.CreateCheckResultOptions{
+			TeamID:         result.TeamID,
+			ChallengeID:    result.ChallengeID,
+			GameBoxID:      result.GameBoxID,
+			Round:          result.Round,
+			Status:         result.Status,
+			PublicMessage:  result.PublicMessage,
+			PrivateMessage: result.PrivateMessage,
+			Latency:        result.Latency,
+		})
+	}
+	if err := db.CheckResults.BatchCreate(ctx, resultOptions); err != nil {
+		return errors.Wrap(err, "create check results")
+	}
+
+	for i, result := range results {
+		if !result.Status.IsDown() {
+			continue
+		}
+		if _, err := Down(ctx, targets[i].GameBox, round); err != nil {
+			return errors.Wrapf(err, "check down game box %d", result.GameBoxID)
+		}
+	}
+	return nil
+}
+
+// MarkUp records the visible game boxes whose challenge has no checker as up in the given round if they were
+// not reported down, so that all the rounds are counted in the SLA of the challenges only checked by the reporters.
+// It is called when a round ends.
+func MarkUp(ctx context.Context, round uint) error {
+	gameBoxes, err := db.GameBoxes.Get(ctx, db.GetGameBoxesOption{
+		Visible: true,
+	})
+	if err != nil {
+		return errors.Wrap(err, "get game boxes")
+	}
+
+	unchecked := make([]*db.GameBox, 0, len(gameBoxes))
+	for _, gameBox := range gameBoxes {
+		if gameBox.Challenge != nil && gameBox.Challenge.Checker != "" {
+			continue
+		}
+		unchecked = append(unchecked, gameBox)
+	}
+
+	if err := db.CheckResults.MarkUp(ctx, round, unchecked); err != nil {
+		return errors.Wrap(err, "mark up")
+	}
+	return nil
+}
+
+// CheckAll checks the game boxes concurrently, the number of the game boxes checking at the same time
+// is limited by the configuration. The results are in the same order as the targets.
+func CheckAll(ctx context.Context, round uint, targets []Target) []*Result {
+	concurrency := conf.Game.CheckerConcurrency
+	if concurrency <= 0 {
+		concurrency = DefaultConcurrency
+	}
+
+	results := make([]*Result, len(targets))
+	semaphore := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, target := range targets {
+		i, target := i, target
+
+		wg.Add(1)
+		semaphore <- struct{}{}
+		go func() {
+			defer func() {
+				<-semaphore
+				wg.Done()
+			}()
+			results[i] = CheckOne(ctx, round, target)
+		}()
+	}
+	wg.Wait()
+	return results
+}
+
+// CheckOne puts and gets the flag, and checks the service of the game box within the timeout.
+// The game box is regarded as down if the checker does not finish in time.
+func CheckOne(ctx context.Context, round uint, target Target) *Result {
+	timeout := DefaultTimeout
+	if conf.Game.CheckerTimeout != 0 {
+		timeout = time.Duration(conf.Game.CheckerTimeout) * time.Second
+	}
+	checkCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	startAt := timeutil.Now()
+	err := check(checkCtx, round, target)
+	latency := timeutil.Now().Sub(startAt).Milliseconds()
+
+	result := &Result{
+		GameBoxID:   target.GameBox.ID,
+		TeamID:      target.GameBox.TeamID,
+		ChallengeID: target.GameBox.ChallengeID,
+		Round:       round,
+		Status:      StatusOf(err),
+		Latency:     latency,
+	}
+	if err == nil {
+		return result
+	}
+
+	var checkErr *Error
+	if result.Status == db.CheckStatusError && checkCtx.Err() == context.DeadlineExceeded {
+		result.Status = db.CheckStatusDown
+		result.PublicMessage = "timeout"
+		err = errors.Wrap(err, "timeout")
+	} else if errors.As(err, &checkErr) && result.Status.IsDown() {
+		// The message of the internal error is not shown to the team.
+		result.PublicMessage = checkErr.Message
+	}
+	result.PrivateMessage = err.Error()
+	if checkErr != nil && checkErr.Debug != "" {
+		result.PrivateMessage += "\n" + checkErr.Debug
+	}
+
+	if result.Status == db.CheckStatusError {
+		log.Error("Failed to check game box %d in round %d: %v", target.GameBox.ID, round, err)
+	} else {
+		log.Warn("Game box %d failed to pass the check of round %d: %v", target.GameBox.ID, round, err)
+	}
+	return result
+}
+
+func check(ctx context.Context, round uint, target Target) error {
+	if target.Flag != nil {
+		if err := target.Checker.PutFlag(ctx, target.GameBox, target.Flag); err != nil {
+			return errors.Wrap(err, "put flag")
+		}
+		if err := target.Checker.GetFlag(ctx, target.GameBox, target.Flag); err != nil {
+			return errors.Wrap(err, "get flag")
+		}
+	}
+	if err := target.Checker.Check(ctx, target.GameBox, round); err != nil {
+		return errors.Wrap(err, "check")
+	}
+	return nil
+}
+
+// Down checks down the game box in the given round.
+// It does nothing and returns false if the game box has been checked down in the round.
+func Down(ctx context.Context, gameBox *db.GameBox, round uint) (bool, error) {
+	_, err := db.Actions.Create(ctx, db.CreateActionOptions{
+		Type:      db.ActionTypeCheckDown,
+		GameBoxID: gameBox.ID,
+		Round:     round,
+	})
+	if err == db.ErrDuplicateAction {
+		return false, nil
+	} else if err != nil {
+		return false, errors.Wrap(err, "create action")
+	}
+
+	if err := db.GameBoxes.SetDown(ctx, gameBox.ID); err != nil {
+		return false, errors.Wrap(err, "set down")
+	}
+
+	go webhook.Add(webhook.CHECK_DOWN_HOOK, map[string]interface{}{"team": gameBox.TeamID, "gamebox": gameBox.ID})
+
+	var teamName, challengeTitle string
+	if gameBox.Team != nil {
+		teamName = gameBox.Team.Name
+	}
+	if gameBox.Challenge != nil {
+		challengeTitle = gameBox.Challenge.Title
+	}
+	_ = livelog.Stream.Write(livelog.GlobalStream, livelog.NewLine("check_down", map[string]interface{}{
+		"Team":      teamName,
+		"Challenge": challengeTitle,
+	}))
+	return true, nil
+}
@@ -5,12 +5,15 @@
 package clock
 
 import (
+	"context"
 	"math"
+	"sync"
 	"time"
 
 	"github.com/pkg/errors"
 
 	"github.com/vidar-team/Cardinal/internal/conf"
+	"github.com/vidar-team/Cardinal/internal/timeutil"
 )
 
 type Status int
@@ -35,7 +38,15 @@ type Clock struct {
 	RoundRemainDuration time.Duration
 	Status              Status
 
-	stopChan chan struct{}
+	mu       sync.RWMutex
+	handlers map[EventType][]EventHandler
+	// lastStatus and finishedRound are the game status and the finished round count
+	// which have been processed by the clock processor.
+	lastStatus    Status
+	finishedRound uint
+
+	stopChan  chan struct{}
+	resetChan chan struct{}
 }
 
 func Init() error {
@@ -43,46 +54,84 @@ func Init() error {
 		return ErrZeroRoundDuration
 	}
 
-	T = &Clock{
+	c := &Clock{
 		StartAt:       conf.Game.StartAt.In(time.Local),
 		EndAt:         conf.Game.EndAt.In(time.Local),
 		RoundDuration: time.Duration(conf.Game.RoundDuration) * time.Minute,
+		stopChan:      make(chan struct{}),
+		resetChan:     make(chan struct{}, 1),
 	}
 
 	restTime := make([][]time.Time, 0, len(conf.Game.PauseTime))
 	for _, t := range conf.Game.PauseTime {
 		restTime = append(restTime, []time.Time{t.StartAt.In(time.Local), t.EndAt.In(time.Local)})
 	}
-	T.RestTime = restTime
+	c.RestTime = restTime
+
+	// The time settings changed at runtime override the configuration file.
+	if err := c.load(context.Background()); err != nil {
+		return errors.Wrap(err, "load runtime settings")
+	}
+
+	if err := c.calculate(); err != nil {
+		return err
+	}
+
+	T = c
+	return nil
+}
+
+// State returns the game status and the round at the current time of the time source.
+func (c *Clock) State() (Status, uint) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	status, round, _ := c.stateAt(timeutil.Now())
+	return status, round
+}
+
+// FinishedRound returns the count of the rounds which have been finished by now.
+func (c *Clock) FinishedRound() uint {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	_, finished := c.roundsAt(timeutil.Now())
+	return finished
+}
 
+// calculate checks the time configuration, then sets the run time cycle
+// and the total round count of the game.
+func (c *Clock) calculate() error {
 	// Check timer configuration.
-	if err := T.checkConfig(); err != nil {
+	if err := c.checkConfig(); err != nil {
 		return errors.Wrap(err, "check config")
 	}
 
-	T.RestTime = combineDuration(T.RestTime)
+	c.RestTime = combineDuration(c.RestTime)
 
 	// Set competition run time cycle.
-	if len(T.RestTime) != 0 {
+	runTime := make([][]time.Time, 0, len(c.RestTime)+1)
+	if len(c.RestTime) != 0 {
 		// StartAt -> RestTime[0][Start]
-		T.RunTime = append(T.RunTime, []time.Time{T.StartAt, T.RestTime[0][0]})
-		for i := 0; i < len(T.RestTime)-1; i++ {
+		runTime = append(runTime, []time.Time{c.StartAt, c.RestTime[0][0]})
+		for i := 0; i < len(c.RestTime)-1; i++ {
 			// Runtime = RestHeadEnd -> RestNextBegin
-			T.RunTime = append(T.RunTime, []time.Time{T.RestTime[i][1], T.RestTime[i+1][0]})
+			runTime = append(runTime, []time.Time{c.RestTime[i][1], c.RestTime[i+1][0]})
 		}
 		// RestTime[Last][End] -> EndAt
-		T.RunTime = append(T.RunTime, []time.Time{T.RestTime[len(T.RestTime)-1][1], T.EndAt})
+		runTime = append(runTime, []time.Time{c.RestTime[len(c.RestTime)-1][1], c.EndAt})
 
 	} else {
-		T.RunTime = append(T.RunTime, []time.Time{T.StartAt, T.EndAt})
+		runTime = append(runTime, []time.Time{c.StartAt, c.EndAt})
 	}
+	c.RunTime = runTime
 
 	// Calculate total round count.
 	var totalTime time.Duration
-	for _, duration := range T.RunTime {
+	for _, duration := range c.RunTime {
 		totalTime += duration[1].Sub(duration[0])
 	}
-	T.TotalRound = uint(math.Ceil(totalTime.Minutes() / T.RoundDuration.Minutes()))
+	c.TotalRound = uint(math.Ceil(totalTime.Minutes() / c.RoundDuration.Minutes()))
 
 	return nil
 }
@@ -5,10 +5,15 @@
 package clock
 
 import (
+	"context"
+	"fmt"
 	"testing"
 	"time"
 
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
+
+	"github.com/vidar-team/Cardinal/internal/timeutil"
 )
 
 func Test_checkConfig(t *testing.T) {
@@ -154,3 +159,377 @@ func Test_combineDuration(t *testing.T) {
 func date(year, month, day, hour, min, sec int) time.Time {
 	return time.Date(year, time.Month(month), day, hour, min, sec, 0, time.Local)
 }
+
+func Test_stateAt(t *testing.T) {
+	c := &Clock{
+		StartAt:       date(2021, 10, 3, 12, 0, 0),
+		EndAt:         date(2021, 10, 3, 18, 0, 0),
+		RoundDuration: time.Hour,
+		RestTime: [][]time.Time{
+			{date(2021, 10, 3, 14, 0, 0), date(2021, 10, 3, 15, 0, 0)},
+		},
+	}
+	assert.Nil(t, c.calculate())
+	assert.Equal(t, uint(5), c.TotalRound)
+
+	for _, tc := range []struct {
+		name        string
+		time        time.Time
+		status      Status
+		round       uint
+		roundRemain time.Duration
+	}{
+		{"wait", date(2021, 10, 3, 11, 0, 0), StatusWait, 0, 0},
+		{"start", date(2021, 10, 3, 12, 0, 0), StatusRunning, 1, time.Hour},
+		{"first round", date(2021, 10, 3, 12, 20, 0), StatusRunning, 1, 40 * time.Minute},
+		{"second round", date(2021, 10, 3, 13, 30, 0), StatusRunning, 2, 30 * time.Minute},
+		{"rest", date(2021, 10, 3, 14, 30, 0), StatusPause, 2, 0},
+		{"third round", date(2021, 10, 3, 15, 10, 0), StatusRunning, 3, 50 * time.Minute},
+		{"resume", date(2021, 10, 3, 15, 0, 0), StatusRunning, 3, time.Hour},
+		{"end", date(2021, 10, 3, 18, 0, 0), StatusEnd, 5, 0},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			status, round, roundRemain := c.stateAt(tc.time)
+			assert.Equal(t, tc.status, status)
+			assert.Equal(t, tc.round, round)
+			assert.Equal(t, tc.roundRemain, roundRemain)
+		})
+	}
+}
+
+func TestClock_Pause(t *testing.T) {
+	now := date(2021, 10, 3, 12, 0, 0)
+	defer timeutil.SetClock(timeutil.NewFakeClock(now))()
+
+	c := newRunningClock(t, now)
+	snapshot, err := c.Pause()
+	assert.Nil(t, err)
+	assert.Equal(t, StatusPause, c.Status)
+	assert.Len(t, c.RestTime, 1)
+	assert.Equal(t, c.EndAt, c.RestTime[0][1])
+	assert.Equal(t, Snapshot{
+		EndAt:      c.EndAt,
+		RestTime:   [][]time.Time{{now, c.EndAt}},
+		TotalRound: c.TotalRound,
+		Status:     StatusPause,
+	}, snapshot)
+
+	// Pause the paused game.
+	_, err = c.Pause()
+	assert.Equal(t, ErrGamePaused, err)
+
+	// Pause the game before it starts.
+	c = newRunningClock(t, now.Add(time.Hour))
+	_, err = c.Pause()
+	assert.Equal(t, ErrGameNotRunning, err)
+	assert.Len(t, c.RestTime, 0)
+}
+
+func TestClock_Resume(t *testing.T) {
+	now := date(2021, 10, 3, 12, 0, 0)
+	defer timeutil.SetClock(timeutil.NewFakeClock(now))()
+
+	c := newRunningClock(t, now)
+	_, err := c.Resume(false)
+	assert.Equal(t, ErrGameNotPaused, err)
+
+	// Resume without extending the end time.
+	c.RestTime = [][]time.Time{{now.Add(-10 * time.Minute), c.EndAt}}
+	assert.Nil(t, c.calculate())
+	endAt := c.EndAt
+	_, err = c.Resume(false)
+	assert.Nil(t, err)
+	assert.Equal(t, StatusRunning, c.Status)
+	assert.Equal(t, endAt, c.EndAt)
+	assert.True(t, c.RestTime[0][1].Before(c.EndAt))
+
+	// Resume with extending the end time.
+	c = newRunningClock(t, now)
+	c.RestTime = [][]time.Time{{now.Add(-10 * time.Minute), c.EndAt}}
+	assert.Nil(t, c.calculate())
+	totalRound := newRunningClock(t, now).TotalRound
+	_, err = c.Resume(true)
+	assert.Nil(t, err)
+	assert.Equal(t, StatusRunning, c.Status)
+	assert.True(t, c.EndAt.After(endAt))
+	assert.Equal(t, totalRound, c.TotalRound)
+}
+
+func TestClock_ExtendEndAt(t *testing.T) {
+	now := date(2021, 10, 3, 12, 0, 0)
+	defer timeutil.SetClock(timeutil.NewFakeClock(now))()
+
+	c := newRunningClock(t, now)
+	totalRound := c.TotalRound
+	_, err := c.ExtendEndAt(c.EndAt.Add(-time.Hour))
+	assert.Equal(t, ErrEndTimeOrder, err)
+
+	// The pause lasts until the new end time.
+	_, err = c.Pause()
+	assert.Nil(t, err)
+	endAt := c.EndAt.Add(2 * time.Hour)
+	_, err = c.ExtendEndAt(endAt)
+	assert.Nil(t, err)
+	assert.Equal(t, endAt, c.EndAt)
+	assert.Equal(t, endAt, c.RestTime[0][1])
+
+	_, err = c.Resume(false)
+	assert.Nil(t, err)
+	snapshot, err := c.ExtendEndAt(c.EndAt.Add(2 * time.Hour))
+	assert.Nil(t, err)
+	assert.True(t, c.TotalRound > totalRound)
+	assert.Equal(t, c.TotalRound, snapshot.TotalRound)
+}
+
+func TestClock_AddRestTime(t *testing.T) {
+	now := date(2021, 10, 3, 12, 0, 0)
+	defer timeutil.SetClock(timeutil.NewFakeClock(now))()
+
+	c := newRunningClock(t, now)
+	_, err := c.AddRestTime(now.Add(-time.Hour), now.Add(time.Hour))
+	assert.Equal(t, ErrRestTimeInPast, err)
+
+	// The rest time overflows the end time, the configuration is rolled back.
+	_, err = c.AddRestTime(now.Add(time.Hour), c.EndAt.Add(time.Hour))
+	assert.Equal(t, ErrRestTimeOverflow, errors.Cause(err))
+	assert.Len(t, c.RestTime, 0)
+
+	totalRound := c.TotalRound
+	_, err = c.AddRestTime(now.Add(2*time.Hour), now.Add(3*time.Hour))
+	assert.Nil(t, err)
+	_, err = c.AddRestTime(now.Add(time.Hour), now.Add(90*time.Minute))
+	assert.Nil(t, err)
+	assert.Len(t, c.RestTime, 2)
+	assert.True(t, c.RestTime[0][0].Before(c.RestTime[1][0]))
+	assert.Equal(t, totalRound-3, c.TotalRound)
+}
+
+func TestClock_Rollback(t *testing.T) {
+	now := date(2021, 10, 3, 12, 0, 0)
+	defer timeutil.SetClock(timeutil.NewFakeClock(now))()
+
+	c := newRunningClock(t, now)
+	previous := c.Snapshot()
+
+	_, err := c.Pause()
+	assert.Nil(t, err)
+	_, err = c.ExtendEndAt(c.EndAt.Add(time.Hour))
+	assert.Nil(t, err)
+
+	assert.Nil(t, c.Rollback(previous))
+	assert.Equal(t, previous, c.Snapshot())
+	assert.Equal(t, StatusRunning, c.Status)
+}
+
+// newRunningClock returns a clock of a 10 hours game with 30 minutes rounds,
+// which has started half an hour before the given time.
+func newRunningClock(t *testing.T, now time.Time) *Clock {
+	c := &Clock{
+		StartAt:       now.Add(-30 * time.Minute),
+		EndAt:         now.Add(570 * time.Minute),
+		RoundDuration: 30 * time.Minute,
+	}
+	if err := c.calculate(); err != nil {
+		t.Fatal(err)
+	}
+	c.Status, c.CurrentRound, c.RoundRemainDuration = c.stateAt(now)
+	return c
+}
+
+func Test_roundsAt(t *testing.T) {
+	c := &Clock{
+		StartAt:       date(2021, 10, 3, 12, 0, 0),
+		EndAt:         date(2021, 10, 3, 15, 0, 0),
+		RoundDuration: time.Hour,
+		RestTime: [][]time.Time{
+			{date(2021, 10, 3, 12, 30, 0), date(2021, 10, 3, 13, 0, 0)},
+		},
+	}
+	assert.Nil(t, c.calculate())
+	assert.Equal(t, uint(3), c.TotalRound)
+
+	for _, tc := range []struct {
+		name     string
+		time     time.Time
+		started  uint
+		finished uint
+	}{
+		{"wait", date(2021, 10, 3, 11, 0, 0), 0, 0},
+		{"first round", date(2021, 10, 3, 12, 10, 0), 1, 0},
+		{"interrupted first round", date(2021, 10, 3, 12, 45, 0), 1, 0},
+		{"second round", date(2021, 10, 3, 13, 30, 0), 2, 1},
+		{"last round", date(2021, 10, 3, 14, 40, 0), 3, 2},
+		{"end", date(2021, 10, 3, 15, 0, 0), 3, 3},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			started, finished := c.roundsAt(tc.time)
+			assert.Equal(t, tc.started, started)
+			assert.Equal(t, tc.finished, finished)
+		})
+	}
+}
+
+func Test_roundPeriod(t *testing.T) {
+	c := &Clock{
+		StartAt:       date(2021, 10, 3, 12, 0, 0),
+		EndAt:         date(2021, 10, 3, 15, 0, 0),
+		RoundDuration: time.Hour,
+		RestTime: [][]time.Time{
+			{date(2021, 10, 3, 12, 30, 0), date(2021, 10, 3, 13, 0, 0)},
+		},
+	}
+	assert.Nil(t, c.calculate())
+
+	for _, tc := range []struct {
+		round   uint
+		startAt time.Time
+		endAt   time.Time
+	}{
+		{1, date(2021, 10, 3, 12, 0, 0), date(2021, 10, 3, 13, 30, 0)},
+		{2, date(2021, 10, 3, 13, 30, 0), date(2021, 10, 3, 14, 30, 0)},
+		// The last round is cut off by the end of the game.
+		{3, date(2021, 10, 3, 14, 30, 0), date(2021, 10, 3, 15, 0, 0)},
+	} {
+		startAt, endAt := c.roundPeriod(tc.round)
+		assert.Equal(t, tc.startAt, startAt)
+		assert.Equal(t, tc.endAt, endAt)
+	}
+
+	// The round finishes at the beginning of the rest time.
+	c.RestTime = [][]time.Time{{date(2021, 10, 3, 13, 0, 0), date(2021, 10, 3, 13, 30, 0)}}
+	assert.Nil(t, c.calculate())
+	startAt, endAt := c.roundPeriod(1)
+	assert.Equal(t, date(2021, 10, 3, 12, 0, 0), startAt)
+	assert.Equal(t, date(2021, 10, 3, 13, 0, 0), endAt)
+	startAt, _ = c.roundPeriod(2)
+	assert.Equal(t, date(2021, 10, 3, 13, 30, 0), startAt)
+}
+
+func TestClock_lifecycle(t *testing.T) {
+	fakeClock := timeutil.NewFakeClock(date(2021, 10, 3, 11, 0, 0))
+	defer timeutil.SetClock(fakeClock)()
+
+	c := &Clock{
+		StartAt:       date(2021, 10, 3, 12, 0, 0),
+		EndAt:         date(2021, 10, 3, 18, 0, 0),
+		RoundDuration: time.Hour,
+		RestTime: [][]time.Time{
+			{date(2021, 10, 3, 14, 0, 0), date(2021, 10, 3, 15, 0, 0)},
+		},
+	}
+	assert.Nil(t, c.calculate())
+
+	tick := func() []event {
+		return c.tick(fakeClock.Now())
+	}
+
+	// The game is not started.
+	assert.Nil(t, tick())
+	assert.Equal(t, StatusWait, c.Status)
+
+	// The first round begins.
+	fakeClock.Set(date(2021, 10, 3, 12, 0, 0))
+	assert.Equal(t, []event{{EventRoundStart, 1}}, tick())
+	assert.Equal(t, StatusRunning, c.Status)
+	assert.Equal(t, time.Hour, c.RoundRemainDuration)
+
+	fakeClock.Advance(30 * time.Minute)
+	assert.Nil(t, tick())
+	assert.Equal(t, 30*time.Minute, c.RoundRemainDuration)
+
+	fakeClock.Set(date(2021, 10, 3, 13, 0, 0))
+	assert.Equal(t, []event{{EventRoundEnd, 1}, {EventRoundStart, 2}}, tick())
+
+	// The configured rest time.
+	fakeClock.Set(date(2021, 10, 3, 14, 0, 0))
+	assert.Equal(t, []event{{EventRoundEnd, 2}, {EventPause, 2}}, tick())
+	assert.Equal(t, StatusPause, c.Status)
+
+	fakeClock.Set(date(2021, 10, 3, 15, 0, 0))
+	assert.Equal(t, []event{{EventResume, 3}, {EventRoundStart, 3}}, tick())
+
+	// Pause the game manually.
+	fakeClock.Set(date(2021, 10, 3, 15, 20, 0))
+	_, err := c.Pause()
+	assert.Nil(t, err)
+	assert.Equal(t, []event{{EventPause, 3}}, tick())
+	fakeClock.Set(date(2021, 10, 3, 15, 50, 0))
+	assert.Nil(t, tick())
+
+	// Resume the game and extend the end time, the round continues.
+	fakeClock.Set(date(2021, 10, 3, 16, 0, 0))
+	_, err = c.Resume(true)
+	assert.Nil(t, err)
+	assert.Equal(t, date(2021, 10, 3, 18, 40, 0), c.EndAt)
+	assert.Equal(t, uint(5), c.TotalRound)
+	assert.Equal(t, []event{{EventResume, 3}}, tick())
+	assert.Equal(t, 40*time.Minute, c.RoundRemainDuration)
+
+	fakeClock.Set(date(2021, 10, 3, 16, 40, 0))
+	assert.Equal(t, []event{{EventRoundEnd, 3}, {EventRoundStart, 4}}, tick())
+
+	// The game is over, the rounds missed are finished.
+	fakeClock.Set(date(2021, 10, 3, 18, 40, 0))
+	assert.Equal(t, []event{{EventRoundEnd, 4}, {EventRoundEnd, 5}, {EventGameEnd, 5}}, tick())
+	assert.Equal(t, StatusEnd, c.Status)
+	assert.Nil(t, tick())
+
+	status, round := c.State()
+	assert.Equal(t, StatusEnd, status)
+	assert.Equal(t, uint(5), round)
+}
+
+func Test_nextChangeAt(t *testing.T) {
+	c := &Clock{
+		StartAt:       date(2021, 10, 3, 12, 0, 0),
+		EndAt:         date(2021, 10, 3, 18, 0, 0),
+		RoundDuration: 40 * time.Minute,
+		RestTime: [][]time.Time{
+			{date(2021, 10, 3, 13, 0, 0), date(2021, 10, 3, 14, 0, 0)},
+		},
+	}
+	assert.Nil(t, c.calculate())
+
+	for _, tc := range []struct {
+		name string
+		time time.Time
+		next time.Time
+		ok   bool
+	}{
+		{"wait", date(2021, 10, 3, 11, 0, 0), date(2021, 10, 3, 12, 0, 0), true},
+		{"round end", date(2021, 10, 3, 12, 0, 0), date(2021, 10, 3, 12, 40, 0), true},
+		{"rest start", date(2021, 10, 3, 12, 40, 0), date(2021, 10, 3, 13, 0, 0), true},
+		{"rest end", date(2021, 10, 3, 13, 10, 0), date(2021, 10, 3, 14, 0, 0), true},
+		{"interrupted round end", date(2021, 10, 3, 14, 0, 0), date(2021, 10, 3, 14, 20, 0), true},
+		{"game end", date(2021, 10, 3, 17, 50, 0), date(2021, 10, 3, 18, 0, 0), true},
+		{"end", date(2021, 10, 3, 18, 0, 0), time.Time{}, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			next, ok := c.nextChangeAt(tc.time)
+			assert.Equal(t, tc.next, next)
+			assert.Equal(t, tc.ok, ok)
+		})
+	}
+}
+
+func TestClock_dispatch(t *testing.T) {
+	c := &Clock{}
+
+	var got []string
+	c.OnPause(func(ctx context.Context, round uint) error {
+		got = append(got, fmt.Sprintf("pause %d", round))
+		return errors.New("unexpected error")
+	})
+	c.OnPause(func(ctx context.Context, round uint) error {
+		got = append(got, fmt.Sprintf("another pause %d", round))
+		return nil
+	})
+	c.OnResume(func(ctx context.Context, round uint) error {
+		got = append(got, fmt.Sprintf("resume %d", round))
+		return nil
+	})
+
+	// The error of the handler does not stop the others.
+	c.dispatch(context.Background(), []event{{EventPause, 2}, {EventResume, 3}})
+	assert.Equal(t, []string{"pause 2", "another pause 2", "resume 3"}, got)
+}
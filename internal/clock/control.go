@@ -0,0 +1,244 @@
+// Copyright 2021 E99p1ant. All rights reserved.
+// Use of this source code is governed by an AGPL-style
+// license that can be found in the LICENSE file.
+
+package clock
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/pkg/errors"
+
+	"github.com/vidar-team/Cardinal/internal/db"
+	"github.com/vidar-team/Cardinal/internal/timeutil"
+)
+
+const (
+	settingKeyEndAt    = "clock.end_at"
+	settingKeyRestTime = "clock.rest_time"
+)
+
+// Pause pauses the game from now on until it is resumed.
+// The pause is an open rest time period which lasts until the end of the game.
+func (c *Clock) Pause() (Snapshot, error) {
+	now := timeutil.Now()
+	return c.update(now, func() error {
+		switch status, _, _ := c.stateAt(now); status {
+		case StatusWait, StatusEnd:
+			return ErrGameNotRunning
+		case StatusPause:
+			return ErrGamePaused
+		}
+
+		c.RestTime = append(c.RestTime, []time.Time{now, c.EndAt})
+		sortDuration(c.RestTime)
+		return nil
+	})
+}
+
+// Resume resumes the game now, it ends the current rest time period.
+// If extend is true, the end time of the game will be postponed for the duration of the rest time,
+// so that the game has the same total round count as before it was paused.
+func (c *Clock) Resume(extend bool) (Snapshot, error) {
+	now := timeutil.Now()
+	return c.update(now, func() error {
+		if status, _, _ := c.stateAt(now); status != StatusPause {
+			return ErrGameNotPaused
+		}
+
+		for _, duration := range c.RestTime {
+			if now.Before(duration[0]) || !now.Before(duration[1]) {
+				continue
+			}
+
+			restDuration := now.Sub(duration[0])
+			duration[1] = now
+			if extend {
+				c.EndAt = c.EndAt.Add(restDuration)
+			}
+			return nil
+		}
+		return ErrGameNotPaused
+	})
+}
+
+// ExtendEndAt postpones the end time of the game to the given time.
+// The rest time period which lasts until the end of the game is postponed too.
+func (c *Clock) ExtendEndAt(endAt time.Time) (Snapshot, error) {
+	now := timeutil.Now()
+	return c.update(now, func() error {
+		if status, _, _ := c.stateAt(now); status == StatusEnd {
+			return ErrGameEnded
+		}
+		if !endAt.After(c.EndAt) {
+			return ErrEndTimeOrder
+		}
+
+		for _, duration := range c.RestTime {
+			if duration[1].Equal(c.EndAt) {
+				duration[1] = endAt
+			}
+		}
+		c.EndAt = endAt
+		return nil
+	})
+}
+
+// AddRestTime inserts a rest time period into the game.
+// The rest time period can not start in the past.
+func (c *Clock) AddRestTime(startAt, endAt time.Time) (Snapshot, error) {
+	now := timeutil.Now()
+	return c.update(now, func() error {
+		if status, _, _ := c.stateAt(now); status == StatusEnd {
+			return ErrGameEnded
+		}
+		if startAt.Before(now) {
+			return ErrRestTimeInPast
+		}
+
+		c.RestTime = append(c.RestTime, []time.Time{startAt, endAt})
+		sortDuration(c.RestTime)
+		return nil
+	})
+}
+
+// sortDuration sorts the time durations by their start time.
+func sortDuration(d [][]time.Time) {
+	sort.SliceStable(d, func(i, j int) bool {
+		return d[i][0].Before(d[j][0])
+	})
+}
+
+// Snapshot is the time configuration of the clock at a moment,
+// it is returned by the control methods so it can be read without the lock.
+type Snapshot struct {
+	EndAt      time.Time
+	RestTime   [][]time.Time
+	TotalRound uint
+	Status     Status
+}
+
+// Snapshot returns the current time configuration of the clock.
+func (c *Clock) Snapshot() Snapshot {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.snapshot()
+}
+
+func (c *Clock) snapshot() Snapshot {
+	restTime := make([][]time.Time, 0, len(c.RestTime))
+	for _, duration := range c.RestTime {
+		restTime = append(restTime, []time.Time{duration[0], duration[1]})
+	}
+	return Snapshot{
+		EndAt:      c.EndAt,
+		RestTime:   restTime,
+		TotalRound: c.TotalRound,
+		Status:     c.Status,
+	}
+}
+
+// Rollback restores the end time and the rest time of the game to the snapshot,
+// it is used when the changed time configuration fails to be saved.
+func (c *Clock) Rollback(s Snapshot) error {
+	_, err := c.update(timeutil.Now(), func() error {
+		c.EndAt = s.EndAt
+		c.RestTime = make([][]time.Time, 0, len(s.RestTime))
+		for _, duration := range s.RestTime {
+			c.RestTime = append(c.RestTime, []time.Time{duration[0], duration[1]})
+		}
+		return nil
+	})
+	return err
+}
+
+// update changes the time configuration with the given function,
+// and recalculates the run time cycle and the total round count.
+// The time configuration will be rolled back if it is invalid after changed.
+// It returns the snapshot of the changed time configuration.
+func (c *Clock) update(now time.Time, change func() error) (Snapshot, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	endAt := c.EndAt
+	restTime := make([][]time.Time, 0, len(c.RestTime))
+	for _, duration := range c.RestTime {
+		restTime = append(restTime, []time.Time{duration[0], duration[1]})
+	}
+	runTime := c.RunTime
+	totalRound := c.TotalRound
+
+	rollback := func() {
+		c.EndAt = endAt
+		c.RestTime = restTime
+		c.RunTime = runTime
+		c.TotalRound = totalRound
+	}
+
+	if err := change(); err != nil {
+		rollback()
+		return Snapshot{}, err
+	}
+	if err := c.calculate(); err != nil {
+		rollback()
+		return Snapshot{}, err
+	}
+
+	// The current round is not changed, for the time configuration can only be changed from now on.
+	c.Status, _, c.RoundRemainDuration = c.stateAt(now)
+
+	// Wake up the clock processor to reschedule the next event.
+	select {
+	case c.resetChan <- struct{}{}:
+	default:
+	}
+	return c.snapshot(), nil
+}
+
+// Save persists the time configuration of the snapshot changed at runtime to the database.
+func (c *Clock) Save(ctx context.Context, s Snapshot) error {
+	restTime, err := jsoniter.Marshal(s.RestTime)
+	if err != nil {
+		return errors.Wrap(err, "marshal rest time")
+	}
+
+	if err := db.Settings.Set(ctx, settingKeyEndAt, s.EndAt.Format(time.RFC3339)); err != nil {
+		return errors.Wrap(err, "set end time")
+	}
+	if err := db.Settings.Set(ctx, settingKeyRestTime, string(restTime)); err != nil {
+		return errors.Wrap(err, "set rest time")
+	}
+	return nil
+}
+
+// load loads the time configuration changed at runtime from the database.
+// It does nothing when the database is not initialized.
+func (c *Clock) load(ctx context.Context) error {
+	if db.Settings == nil {
+		return nil
+	}
+
+	endAt, err := db.Settings.Get(ctx, settingKeyEndAt)
+	if err == nil {
+		c.EndAt, err = time.ParseInLocation(time.RFC3339, endAt, time.Local)
+		if err != nil {
+			return errors.Wrap(err, "parse end time")
+		}
+	} else if err != db.ErrSettingNotExists {
+		return errors.Wrap(err, "get end time")
+	}
+
+	restTime, err := db.Settings.Get(ctx, settingKeyRestTime)
+	if err == nil {
+		if err := jsoniter.Unmarshal([]byte(restTime), &c.RestTime); err != nil {
+			return errors.Wrap(err, "unmarshal rest time")
+		}
+	} else if err != db.ErrSettingNotExists {
+		return errors.Wrap(err, "get rest time")
+	}
+
+	return nil
+}
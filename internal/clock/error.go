@@ -15,4 +15,10 @@ var (
 	ErrRestTimeOrder     = errors.New("rest start time should before end time")
 	ErrRestTimeOverflow  = errors.New("rest time overflow")
 	ErrRestTimeListOrder = errors.New("rest time list should in order")
+	ErrRestTimeInPast    = errors.New("rest time should not start in the past")
+	ErrEndTimeOrder      = errors.New("new end time should after the current end time")
+	ErrGameNotRunning    = errors.New("game is not running")
+	ErrGamePaused        = errors.New("game is already paused")
+	ErrGameNotPaused     = errors.New("game is not paused")
+	ErrGameEnded         = errors.New("game is over")
 )
@@ -0,0 +1,119 @@
+// Copyright 2021 E99p1ant. All rights reserved.
+// Use of this source code is governed by an AGPL-style
+// license that can be found in the LICENSE file.
+
+package clock
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	log "unknwon.dev/clog/v2"
+
+	"github.com/vidar-team/Cardinal/internal/db"
+)
+
+// EventType is the type of the game clock event.
+type EventType string
+
+const (
+	EventRoundStart EventType = "round_start"
+	EventRoundEnd   EventType = "round_end"
+	EventPause      EventType = "pause"
+	EventResume     EventType = "resume"
+	EventGameEnd    EventType = "game_end"
+)
+
+// EventHandler handles the game clock event of the given round.
+type EventHandler func(ctx context.Context, round uint) error
+
+type event struct {
+	Type  EventType
+	Round uint
+}
+
+// OnRoundStart registers the handler which is called when a new round starts.
+func (c *Clock) OnRoundStart(handler EventHandler) {
+	c.on(EventRoundStart, handler)
+}
+
+// OnRoundEnd registers the handler which is called when a round finishes.
+// It is also called for the rounds which finished when Cardinal was not running.
+func (c *Clock) OnRoundEnd(handler EventHandler) {
+	c.on(EventRoundEnd, handler)
+}
+
+// OnPause registers the handler which is called when the game is paused,
+// the round is the interrupted round.
+func (c *Clock) OnPause(handler EventHandler) {
+	c.on(EventPause, handler)
+}
+
+// OnResume registers the handler which is called when the game is resumed,
+// the round is the current round after resumed.
+func (c *Clock) OnResume(handler EventHandler) {
+	c.on(EventResume, handler)
+}
+
+// OnGameEnd registers the handler which is called when the game is over,
+// the round is the total round count of the game.
+func (c *Clock) OnGameEnd(handler EventHandler) {
+	c.on(EventGameEnd, handler)
+}
+
+func (c *Clock) on(eventType EventType, handler EventHandler) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.handlers == nil {
+		c.handlers = make(map[EventType][]EventHandler)
+	}
+	c.handlers[eventType] = append(c.handlers[eventType], handler)
+}
+
+// dispatch persists the rounds of the events, and calls the handlers of the events in order.
+// The error of the handler is logged, and it does not stop the other handlers.
+func (c *Clock) dispatch(ctx context.Context, events []event) {
+	for _, e := range events {
+		if err := c.record(ctx, e); err != nil {
+			log.Error("Failed to record %s event of round %d: %v", e.Type, e.Round, err)
+		}
+
+		c.mu.RLock()
+		handlers := c.handlers[e.Type]
+		c.mu.RUnlock()
+
+		for _, handler := range handlers {
+			if err := handler(ctx, e.Round); err != nil {
+				log.Error("Failed to handle %s event of round %d: %v", e.Type, e.Round, err)
+			}
+		}
+	}
+}
+
+// record persists the round of the round start and the round end event.
+func (c *Clock) record(ctx context.Context, e event) error {
+	if e.Type != EventRoundStart && e.Type != EventRoundEnd {
+		return nil
+	}
+
+	c.mu.RLock()
+	startAt, endAt := c.roundPeriod(e.Round)
+	c.mu.RUnlock()
+
+	// The round may have not been recorded when it finished, if Cardinal was not running.
+	err := db.Rounds.Create(ctx, db.CreateRoundOptions{
+		Number:    e.Round,
+		StartedAt: startAt,
+	})
+	if err != nil && err != db.ErrRoundExists {
+		return errors.Wrap(err, "create round")
+	}
+
+	if e.Type == EventRoundEnd {
+		if err := db.Rounds.Finish(ctx, e.Round, endAt); err != nil {
+			return errors.Wrap(err, "finish round")
+		}
+	}
+	return nil
+}
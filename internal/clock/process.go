@@ -7,15 +7,11 @@ package clock
 import (
 	"context"
 	"math"
-	"sync"
 	"time"
 
 	log "unknwon.dev/clog/v2"
 
-	"github.com/vidar-team/Cardinal/internal/db"
-	"github.com/vidar-team/Cardinal/internal/misc/webhook"
-	"github.com/vidar-team/Cardinal/internal/rank"
-	//"github.com/vidar-team/Cardinal/internal/score"
+	"github.com/vidar-team/Cardinal/internal/timeutil"
 )
 
 // Start starts the game clock processor routine.
@@ -29,129 +25,136 @@ func Stop() {
 	T.stopChan <- struct{}{}
 }
 
+// start runs the clock processor. It sleeps until the next time when the game status or the round changes,
+// or the time configuration is changed at runtime, then fires the events since last processed.
 func (c *Clock) start() {
 	ctx, cancel := context.WithCancel(context.Background())
 
-	// Refresh the ranking list.
-	if err := rank.SetTitle(ctx); err != nil {
-		log.Error("Failed to set rank title: %v", err)
+	if err := c.restore(ctx, timeutil.Now()); err != nil {
+		log.Error("Failed to restore rounds: %v", err)
 	}
-	if err := rank.SetRankList(ctx); err != nil {
-		log.Error("Failed to set rank list: %v", err)
-	}
-
-	var latestCalculateRound uint
-	lastRound := sync.Once{}
 
 	for {
+		now := timeutil.Now()
+		c.dispatch(ctx, c.tick(now))
+
+		// The handlers may take a long time, so the waiting duration is calculated after they finished.
+		// It is negative if the next change has passed, and the processor will tick immediately.
+		var next <-chan time.Time
+		c.mu.RLock()
+		nextChangeAt, ok := c.nextChangeAt(now)
+		c.mu.RUnlock()
+		if ok {
+			next = timeutil.After(nextChangeAt.Sub(timeutil.Now()))
+		}
+
 		select {
 		case <-c.stopChan:
 			cancel()
 			close(c.stopChan)
 			return
+		case <-c.resetChan:
+		case <-next:
+		}
+	}
+}
+
+// tick refreshes the status of the clock at the given time,
+// and returns the events happened since last processed in order.
+func (c *Clock) tick(now time.Time) []event {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 
-		default:
-			currentTime := time.Now()
+	status, round, roundRemainDuration := c.stateAt(now)
+	started, finished := c.roundsAt(now)
+	c.Status = status
+	c.RoundRemainDuration = roundRemainDuration
 
-			if currentTime.Before(c.StartAt) {
-				// The game is not started.
-				c.Status = StatusWait
-				continue
+	var events []event
+	for ; c.finishedRound < finished; c.finishedRound++ {
+		events = append(events, event{Type: EventRoundEnd, Round: c.finishedRound + 1})
+	}
 
-			} else if currentTime.After(c.EndAt) {
-				// The game is over.
-				// Calculate the score of the last round when the game is over.
-				lastRound.Do(func() {
-					if err := db.Scores.Calculate(ctx, c.TotalRound); err != nil {
-						log.Error("Failed to calculate the last round score: %v", err)
-					}
+	if status == StatusPause && c.lastStatus != StatusPause {
+		events = append(events, event{Type: EventPause, Round: round})
+	} else if status == StatusRunning && c.lastStatus == StatusPause {
+		events = append(events, event{Type: EventResume, Round: round})
+	}
 
-					go webhook.Add(webhook.END_HOOK, nil)
-					// TODO logger.New(logger.IMPORTANT, "system", locales.T("timer.end"))
-				})
+	if c.CurrentRound < started {
+		// The round is not regarded as started if the game is over.
+		if status != StatusEnd {
+			events = append(events, event{Type: EventRoundStart, Round: started})
+		}
+		c.CurrentRound = started
+	}
 
-				c.Status = StatusEnd
-				continue
-			}
+	if status == StatusEnd && c.lastStatus != StatusEnd {
+		events = append(events, event{Type: EventGameEnd, Round: c.TotalRound})
+	}
 
-			// The game is running.
-			// Get which time cycle now.
-			currentRunTimeIndex := -1
-			for index, duration := range c.RunTime {
-				if currentTime.After(duration[0]) && currentTime.Before(duration[1]) {
-					currentRunTimeIndex = index
-					break
-				}
-			}
+	c.lastStatus = status
+	return events
+}
 
-			if currentRunTimeIndex == -1 {
-				// Suspended
-				if c.Status != StatusPause {
-					go webhook.Add(webhook.PAUSE_HOOK, nil)
-				}
-				c.Status = StatusPause
-
-			} else {
-				// In progress
-				c.Status = StatusRunning
-
-				// Cumulative time until now.
-				var runningDuration time.Duration
-				for index, duration := range c.RunTime {
-					if index < currentRunTimeIndex {
-						runningDuration += duration[1].Sub(duration[0])
-					} else {
-						// The last runtime cycle for now.
-						runningDuration += currentTime.Sub(duration[0])
-						break
-					}
-				}
-
-				// Get current round.
-				currentRound := uint(math.Ceil(runningDuration.Seconds() / c.RoundDuration.Seconds()))
-				// Calculate the time duration next round.
-				c.RoundRemainDuration = time.Duration(currentRound)*c.RoundDuration - runningDuration
-
-				// Check if it is a new round.
-				if c.CurrentRound < currentRound {
-					c.CurrentRound = currentRound
-					if c.CurrentRound == 1 {
-						go webhook.Add(webhook.BEGIN_HOOK, nil)
-					}
-
-					go webhook.Add(webhook.BEGIN_HOOK, c.CurrentRound)
-
-					// Clean the status of the game boxes.
-					if err := db.GameBoxes.CleanAllStatus(ctx); err != nil {
-						log.Error("Failed to clean game boxes' status: %v", err)
-					}
-
-					// Refresh the ranking list.
-					if err := rank.SetTitle(ctx); err != nil {
-						log.Error("Failed to set rank title: %v", err)
-					}
-					if err := rank.SetRankList(ctx); err != nil {
-						log.Error("Failed to set rank list: %v", err)
-					}
-
-					// If Cardinal has been restart by accident, get the latest round score and chick if it needs to calculate the scores of previous round.
-					// The default value of `latestCalculateRound` is 0, it means that Cardinal will calculate the score when started.
-					if latestCalculateRound < c.CurrentRound-1 {
-						if err := db.Scores.Calculate(ctx, c.CurrentRound-1); err != nil {
-							log.Error("Failed to calculate score: %v", err)
-						}
-						latestCalculateRound = c.CurrentRound - 1
-					}
-
-					// TODO Auto refresh flag
-					//RefreshFlag()
-
-					// TODO Asteroid Unity3D refresh.
-					//asteroid.NewRoundAction()
-				}
+// nextChangeAt returns the next time after the given time when the game status or the round changes.
+// It returns false if the game is over.
+func (c *Clock) nextChangeAt(t time.Time) (time.Time, bool) {
+	if t.Before(c.StartAt) {
+		return c.StartAt, true
+	} else if !t.Before(c.EndAt) {
+		return time.Time{}, false
+	}
+
+	next := c.EndAt
+	for _, duration := range c.RunTime {
+		for _, edge := range duration {
+			if edge.After(t) && edge.Before(next) {
+				next = edge
 			}
+		}
+	}
+
+	if status, _, roundRemainDuration := c.stateAt(t); status == StatusRunning {
+		if roundEndAt := t.Add(roundRemainDuration); roundEndAt.Before(next) {
+			next = roundEndAt
+		}
+	}
+	return next, true
+}
+
+// stateAt returns the game status, the round and the remain duration of the round at the given time.
+func (c *Clock) stateAt(t time.Time) (Status, uint, time.Duration) {
+	if t.Before(c.StartAt) {
+		return StatusWait, 0, 0
+	} else if !t.Before(c.EndAt) {
+		return StatusEnd, c.TotalRound, 0
+	}
+
+	runningDuration, running := c.runningDurationAt(t)
+	if !running {
+		// The round which is interrupted by the rest time is counted.
+		round := uint(math.Ceil(runningDuration.Seconds() / c.RoundDuration.Seconds()))
+		return StatusPause, round, 0
+	}
+
+	// The round begins at the beginning of its time duration.
+	round := uint(runningDuration/c.RoundDuration) + 1
+
+	// Calculate the time duration next round.
+	return StatusRunning, round, time.Duration(round)*c.RoundDuration - runningDuration
+}
 
-			time.Sleep(1 * time.Second)
+// runningDurationAt returns the cumulative running time until the given time,
+// and whether the given time is in a run time cycle.
+func (c *Clock) runningDurationAt(t time.Time) (time.Duration, bool) {
+	var runningDuration time.Duration
+	for _, duration := range c.RunTime {
+		if !t.Before(duration[0]) && t.Before(duration[1]) {
+			return runningDuration + t.Sub(duration[0]), true
+		} else if !t.Before(duration[1]) {
+			runningDuration += duration[1].Sub(duration[0])
 		}
 	}
+	return runningDuration, false
 }
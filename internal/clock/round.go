@@ -0,0 +1,87 @@
+// Copyright 2021 E99p1ant. All rights reserved.
+// Use of this source code is governed by an AGPL-style
+// license that can be found in the LICENSE file.
+
+package clock
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/vidar-team/Cardinal/internal/db"
+)
+
+// roundsAt returns the count of the rounds which have been started and finished at the given time.
+func (c *Clock) roundsAt(t time.Time) (started, finished uint) {
+	if t.Before(c.StartAt) {
+		return 0, 0
+	} else if !t.Before(c.EndAt) {
+		return c.TotalRound, c.TotalRound
+	}
+
+	runningDuration, running := c.runningDurationAt(t)
+	finished = uint(runningDuration / c.RoundDuration)
+	if running {
+		return finished + 1, finished
+	}
+	// The round which is interrupted by the rest time has been started but not finished.
+	return uint(math.Ceil(runningDuration.Seconds() / c.RoundDuration.Seconds())), finished
+}
+
+// roundPeriod returns the start time and the end time of the given round.
+func (c *Clock) roundPeriod(round uint) (startAt, endAt time.Time) {
+	return c.timeAtRunning(time.Duration(round-1)*c.RoundDuration, false),
+		c.timeAtRunning(time.Duration(round)*c.RoundDuration, true)
+}
+
+// timeAtRunning returns the time when the cumulative running time reaches the given duration.
+// When the duration is reached at the end of a run time cycle, the end of the cycle is returned
+// if end is true, otherwise the beginning of the next cycle is returned.
+func (c *Clock) timeAtRunning(d time.Duration, end bool) time.Time {
+	var runningDuration time.Duration
+	for _, duration := range c.RunTime {
+		length := duration[1].Sub(duration[0])
+		if d < runningDuration+length || (end && d == runningDuration+length) {
+			return duration[0].Add(d - runningDuration)
+		}
+		runningDuration += length
+	}
+	return c.EndAt
+}
+
+// restore restores the processed rounds from the database when the clock processor starts at the given time.
+// The rounds which finished when Cardinal was not running are regarded as unprocessed, so their round end events
+// will be fired. The current round starts again only if it has not been recorded.
+func (c *Clock) restore(ctx context.Context, t time.Time) error {
+	rounds, err := db.Rounds.Get(ctx)
+	if err != nil {
+		return errors.Wrap(err, "get rounds")
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// The rounds are processed until the first round whose score has not been calculated.
+	var finished uint
+	for _, round := range rounds {
+		if round.Number != finished+1 || round.Status != db.RoundStatusFinished || !round.ScoreCalculated {
+			break
+		}
+		finished = round.Number
+	}
+
+	var current uint
+	if len(rounds) != 0 {
+		current = rounds[len(rounds)-1].Number
+	}
+	if started, _ := c.roundsAt(t); started > 0 && current < started-1 {
+		current = started - 1
+	}
+
+	c.CurrentRound = current
+	c.finishedRound = finished
+	return nil
+}
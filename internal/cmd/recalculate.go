@@ -0,0 +1,70 @@
+// Copyright 2021 E99p1ant. All rights reserved.
+// Use of this source code is governed by an AGPL-style
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/urfave/cli/v2"
+	log "unknwon.dev/clog/v2"
+
+	"github.com/vidar-team/Cardinal/internal/clock"
+	"github.com/vidar-team/Cardinal/internal/conf"
+	"github.com/vidar-team/Cardinal/internal/db"
+)
+
+var Recalculate = &cli.Command{
+	Name:  "recalculate",
+	Usage: "Recalculate the scores of all the finished rounds",
+	Description: `Recalculate the action scores of all the finished rounds in a transaction,
+and print the team scores before and after the recalculation.
+The ranking list of the running web server is refreshed when the next round starts.`,
+	Action: runRecalculate,
+	Flags: []cli.Flag{
+		stringFlag("config, c", "", "Custom configuration file path"),
+		boolFlag("dry-run", "Print the score differences without changing the database"),
+	},
+}
+
+func runRecalculate(c *cli.Context) error {
+	err := conf.Init(c.String("config"))
+	if err != nil {
+		log.Fatal("Failed to load config: %v", err)
+	}
+
+	if err = db.Init(); err != nil {
+		log.Fatal("Failed to init database: %v", err)
+	}
+
+	if err := clock.Init(); err != nil {
+		log.Fatal("Failed to init clock: %v", err)
+	}
+
+	round := clock.T.FinishedRound()
+	dryRun := c.Bool("dry-run")
+	teams, err := db.Scores.Recalculate(context.Background(), db.RecalculateScoreOptions{
+		Round:  round,
+		DryRun: dryRun,
+	})
+	if err != nil {
+		log.Fatal("Failed to recalculate scores: %v", err)
+	}
+
+	if dryRun {
+		fmt.Printf("Dry run, the scores of round 1 - %d are not changed.\n", round)
+	} else {
+		fmt.Printf("The scores of round 1 - %d have been recalculated.\n", round)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "ID\tTeam\tBefore\tAfter\tDiff")
+	for _, team := range teams {
+		_, _ = fmt.Fprintf(w, "%d\t%s\t%.2f\t%.2f\t%+.2f\n", team.TeamID, team.Name, team.Before, team.After, team.Diff)
+	}
+	return w.Flush()
+}
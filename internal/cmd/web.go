@@ -5,13 +5,27 @@
 package cmd
 
 import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/pkg/errors"
 	"github.com/urfave/cli/v2"
 	log "unknwon.dev/clog/v2"
 
+	"github.com/vidar-team/Cardinal/internal/checker"
 	"github.com/vidar-team/Cardinal/internal/clock"
 	"github.com/vidar-team/Cardinal/internal/conf"
 	"github.com/vidar-team/Cardinal/internal/db"
+	"github.com/vidar-team/Cardinal/internal/flagserver"
+	"github.com/vidar-team/Cardinal/internal/flagutil"
+	"github.com/vidar-team/Cardinal/internal/livelog"
 	"github.com/vidar-team/Cardinal/internal/locales"
+	"github.com/vidar-team/Cardinal/internal/misc/webhook"
+	"github.com/vidar-team/Cardinal/internal/rank"
+	"github.com/vidar-team/Cardinal/internal/ratelimit"
 	"github.com/vidar-team/Cardinal/internal/route"
 	"github.com/vidar-team/Cardinal/internal/store"
 )
@@ -39,18 +53,134 @@ func runWeb(c *cli.Context) error {
 		log.Fatal("Failed to init database: %v", err)
 	}
 
+	if err := flagutil.CheckFormat(); err != nil {
+		log.Fatal("Failed to check flag format: %v", err)
+	}
+	if _, err := flagutil.GetPusher(conf.Game.FlagPusher); err != nil {
+		log.Fatal("Failed to get flag pusher %q: %v", conf.Game.FlagPusher, err)
+	}
+
 	// TODO Install
 
 	store.Init()
+	livelog.Init()
+	ratelimit.Init()
+
+	if err := rank.Init(context.Background()); err != nil {
+		log.Fatal("Failed to init rank: %v", err)
+	}
+	// Refresh the ranking list.
+	if err := refreshRank(context.Background(), 0); err != nil {
+		log.Error("Failed to refresh rank: %v", err)
+	}
 
 	if err := clock.Init(); err != nil {
 		log.Fatal("Failed to init clock: %v", err)
 	}
+	registerClockEvents()
 	clock.Start()
 
 	f := route.NewRouter()
-	log.Info("Listen on http://0.0.0.0:%d", c.Int("port"))
 
+	if addr := conf.Game.FlagServerAddr; addr != "" {
+		flagServer := flagserver.NewServer(flagserver.Options{
+			RateLimit: conf.Game.FlagServerRateLimit,
+		})
+		go func() {
+			log.Info("Flag submission server listen on %s", addr)
+			if err := flagServer.ListenAndServe(addr); err != nil && err != flagserver.ErrServerClosed {
+				log.Fatal("Failed to start flag submission server: %v", err)
+			}
+		}()
+		defer func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			if err := flagServer.Shutdown(ctx); err != nil {
+				log.Error("Failed to shutdown flag submission server: %v", err)
+			}
+		}()
+	}
+
+	// Stop the servers gracefully when receiving the interrupt signal.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		f.Stop()
+	}()
+
+	log.Info("Listen on http://0.0.0.0:%d", c.Int("port"))
 	f.Run("0.0.0.0", c.Int("port"))
 	return nil
 }
+
+// registerClockEvents registers the handlers of the game clock events.
+func registerClockEvents() {
+	clock.T.OnRoundStart(func(ctx context.Context, round uint) error {
+		if round == 1 {
+			go webhook.Add(webhook.BEGIN_HOOK, nil)
+		}
+		go webhook.Add(webhook.NEW_ROUND_HOOK, round)
+		_ = livelog.Stream.Write(livelog.GlobalStream, livelog.NewLine("new_round", round))
+		return nil
+	})
+	clock.T.OnRoundStart(func(ctx context.Context, round uint) error {
+		// Clean the status of the game boxes.
+		if err := db.GameBoxes.CleanAllStatus(ctx); err != nil {
+			return errors.Wrap(err, "clean game boxes' status")
+		}
+		return nil
+	})
+	clock.T.OnRoundStart(rotateAndCheck)
+	clock.T.OnRoundStart(refreshRank)
+
+	clock.T.OnRoundEnd(checker.MarkUp)
+	clock.T.OnRoundEnd(db.Scores.Calculate)
+	clock.T.OnRoundEnd(refreshRank)
+
+	clock.T.OnPause(func(ctx context.Context, round uint) error {
+		go webhook.Add(webhook.PAUSE_HOOK, nil)
+		_ = livelog.Stream.Write(livelog.GlobalStream, livelog.NewLine("game_pause", round))
+		return nil
+	})
+
+	clock.T.OnResume(func(ctx context.Context, round uint) error {
+		_ = livelog.Stream.Write(livelog.GlobalStream, livelog.NewLine("game_resume", round))
+		return nil
+	})
+
+	clock.T.OnGameEnd(func(ctx context.Context, round uint) error {
+		go webhook.Add(webhook.END_HOOK, nil)
+		_ = livelog.Stream.Write(livelog.GlobalStream, livelog.NewLine("game_end", round))
+		return nil
+	})
+}
+
+// rotateAndCheck pushes the flags of the round and then checks the game boxes in the background,
+// so the slow game boxes don't block the other clock events. The checker runs after the flags are pushed,
+// and both are cancelled if they do not finish within the round duration.
+func rotateAndCheck(ctx context.Context, round uint) error {
+	go func() {
+		ctx, cancel := context.WithTimeout(ctx, clock.T.RoundDuration)
+		defer cancel()
+
+		if err := flagutil.Rotate(ctx, round); err != nil {
+			log.Error("Failed to rotate flags of round %d: %v", round, err)
+		}
+		if err := checker.Run(ctx, round); err != nil {
+			log.Error("Failed to run checker of round %d: %v", round, err)
+		}
+	}()
+	return nil
+}
+
+// refreshRank refreshes the title and the ranking list in cache.
+func refreshRank(ctx context.Context, _ uint) error {
+	if err := rank.SetTitle(ctx); err != nil {
+		return errors.Wrap(err, "set rank title")
+	}
+	if err := rank.SetRankList(ctx); err != nil {
+		return errors.Wrap(err, "set rank list")
+	}
+	return nil
+}
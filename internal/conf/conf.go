@@ -6,6 +6,8 @@ package conf
 
 import (
 	"os"
+	"regexp"
+	"sync"
 
 	"github.com/pelletier/go-toml"
 	"github.com/pkg/errors"
@@ -67,3 +69,28 @@ func Save(customConf string) error {
 	}
 	return nil
 }
+
+var (
+	flagRegexpMu   sync.Mutex
+	flagRegexpExpr string
+	flagRegexp     *regexp.Regexp
+)
+
+// FlagRegexp returns the compiled Game.FlagRegexp, it is compiled again only if the expression is changed.
+// It returns nil if the regular expression is not set.
+func FlagRegexp() (*regexp.Regexp, error) {
+	flagRegexpMu.Lock()
+	defer flagRegexpMu.Unlock()
+
+	if Game.FlagRegexp == "" {
+		return nil, nil
+	}
+	if flagRegexp == nil || flagRegexpExpr != Game.FlagRegexp {
+		re, err := regexp.Compile(Game.FlagRegexp)
+		if err != nil {
+			return nil, errors.Wrap(err, "compile flag regular expression")
+		}
+		flagRegexpExpr, flagRegexp = Game.FlagRegexp, re
+	}
+	return flagRegexp, nil
+}
@@ -13,3 +13,27 @@ import (
 func TestNewInit(t *testing.T) {
 	assert.Nil(t, Init("./testdata/custom.toml"))
 }
+
+func TestFlagRegexp(t *testing.T) {
+	t.Cleanup(func() {
+		Game.FlagRegexp = ""
+	})
+
+	re, err := FlagRegexp()
+	assert.Nil(t, err)
+	assert.Nil(t, re)
+
+	Game.FlagRegexp = `^flag\{[0-9a-f]+\}$`
+	re, err = FlagRegexp()
+	assert.Nil(t, err)
+	assert.True(t, re.MatchString("flag{e99}"))
+
+	// The compiled regular expression is reused.
+	got, err := FlagRegexp()
+	assert.Nil(t, err)
+	assert.Equal(t, re, got)
+
+	Game.FlagRegexp = `(`
+	_, err = FlagRegexp()
+	assert.NotNil(t, err)
+}
@@ -31,6 +31,9 @@ var (
 		SeparateFrontend bool
 		EnableSentry     bool
 		SecuritySalt     string
+		// TrustedProxies is the IP addresses or the CIDRs of the reverse proxies in front of Cardinal.
+		// The client IP is read from the X-Forwarded-For header only if the request comes from them.
+		TrustedProxies []string
 	}
 
 	// Database is the database settings.
@@ -55,8 +58,63 @@ var (
 
 		FlagPrefix string
 		FlagSuffix string
+		// FlagTemplate is the Go template of the flag, e.g. `flag{{{.Random 32}}}`, `FLAG_{{.Round}}_{{.HMAC}}`.
+		// It must contain .HMAC or .Random, so the flags can't be guessed by the teams.
+		// The flag is FlagPrefix + HMAC + FlagSuffix if it is not set.
+		FlagTemplate string
+		// FlagHMAC is the hash algorithm of the HMAC in the flag, "sha1" or "sha256". It is "sha1" by default.
+		FlagHMAC string
+		// FlagRegexp is the regular expression which the submitted flag should match,
+		// the malformed flag is rejected before looking up in the database.
+		FlagRegexp string
+		// FlagValidRounds is the number of the rounds in which the flag can be submitted, including the round
+		// it belongs to. Only the flag of the current round can be submitted if it is not set.
+		FlagValidRounds uint
+		// FlagPusher is the name of the pusher writing the flags into the game boxes, it is "ssh" by default.
+		FlagPusher string
+		// FlagServerAddr is the listen address of the TCP flag submission server, e.g. ":19998".
+		// The server is not started if it is not set.
+		FlagServerAddr string
+		// FlagServerRateLimit is the max number of the flags submitted per second in each TCP connection.
+		// The number of the flags is unlimited if it is not set.
+		FlagServerRateLimit float64
+		// FlagSubmitRate is the number of the flags allowed to be submitted per second for each team.
+		// The submissions are not limited if it is not set.
+		FlagSubmitRate float64
+		// FlagSubmitBurst is the max number of the flags submitted at the same time for each team,
+		// a batch submission with more flags is always rejected.
+		FlagSubmitBurst int
+		// FlagLockoutThreshold is the number of the invalid flags submitted in FlagLockoutWindow seconds
+		// to lock out the team for FlagLockoutDuration seconds. The team is never locked out if it is not set.
+		FlagLockoutThreshold int
+		FlagLockoutWindow    uint
+		FlagLockoutDuration  uint
+		// FlagPushConcurrency is the max number of the game boxes which the flags are pushed to at the same time.
+		FlagPushConcurrency int
+		// SSHPrivateKeyFile is the path of the private key used to connect to the game boxes through SSH.
+		// The password of the game box is used if it is not set.
+		SSHPrivateKeyFile string
+		// CheckerTimeout is the max seconds to check the service of each game box.
+		CheckerTimeout uint
+		// CheckerConcurrency is the max number of the game boxes which are checked at the same time.
+		CheckerConcurrency int
 
 		AttackScore    int
 		CheckDownScore int
+		// ScoringStrategy is the name of the scoring strategy, it is "zero-sum" by default.
+		ScoringStrategy string
+		// FirstBloodBonus is the bonus score for the first team capturing the flag of a challenge,
+		// it is awarded in all the scoring strategies.
+		FirstBloodBonus int
+
+		// FreezeAt is the time when the ranking list for teams is frozen until it is revealed by the manager.
+		// The ranking list is never frozen if it is not set.
+		FreezeAt *toml.LocalDateTime
+		// PublicScoreboard enables the scoreboard which can be accessed without authentication.
+		PublicScoreboard bool
+		// TeamAttackMatrix enables the attack matrix and the first bloods for teams.
+		// They only contain the ended rounds before the last AttackMatrixDelay rounds.
+		TeamAttackMatrix  bool
+		AttackMatrixDelay uint
 	}
 )
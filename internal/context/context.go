@@ -5,13 +5,17 @@
 package context
 
 import (
+	"net"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/flamego/flamego"
 	jsoniter "github.com/json-iterator/go"
 	"github.com/unknwon/com"
 	log "unknwon.dev/clog/v2"
+
+	"github.com/vidar-team/Cardinal/internal/conf"
 )
 
 // Context represents context of a request.
@@ -42,6 +46,55 @@ func (c *Context) Success(data ...interface{}) error {
 	return nil
 }
 
+// ClientIP returns the IP address of the client. The X-Forwarded-For header is only honoured when
+// the request comes from the trusted proxies in the configuration, otherwise it is forged easily.
+func (c *Context) ClientIP() string {
+	return clientIP(c.Request().RemoteAddr, c.Request().Header.Get("X-Forwarded-For"))
+}
+
+func clientIP(remoteAddr, forwardedFor string) string {
+	ip, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		ip = remoteAddr
+	}
+	if !isTrustedProxy(ip) {
+		return ip
+	}
+
+	// Each proxy appends the address it receives the request from,
+	// so the client is the last address which is not a trusted proxy.
+	forwarded := strings.Split(forwardedFor, ",")
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		addr := strings.TrimSpace(forwarded[i])
+		if addr == "" {
+			continue
+		}
+		ip = addr
+		if !isTrustedProxy(addr) {
+			break
+		}
+	}
+	return ip
+}
+
+// isTrustedProxy returns whether the IP address matches the IP address or the CIDR of a trusted proxy.
+func isTrustedProxy(ip string) bool {
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return false
+	}
+	for _, proxy := range conf.App.TrustedProxies {
+		if _, network, err := net.ParseCIDR(proxy); err == nil {
+			if network.Contains(addr) {
+				return true
+			}
+		} else if proxyIP := net.ParseIP(proxy); proxyIP != nil && proxyIP.Equal(addr) {
+			return true
+		}
+	}
+	return false
+}
+
 func (c *Context) ServerError() error {
 	return c.Error(http.StatusInternalServerError*100, "Internal server error")
 }
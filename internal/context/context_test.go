@@ -0,0 +1,38 @@
+// Copyright 2021 E99p1ant. All rights reserved.
+// Use of this source code is governed by an AGPL-style
+// license that can be found in the LICENSE file.
+
+package context
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/vidar-team/Cardinal/internal/conf"
+)
+
+func TestClientIP(t *testing.T) {
+	conf.App.TrustedProxies = []string{"10.0.0.1", "172.16.0.0/12"}
+	t.Cleanup(func() {
+		conf.App.TrustedProxies = nil
+	})
+
+	for _, tc := range []struct {
+		name         string
+		remoteAddr   string
+		forwardedFor string
+		want         string
+	}{
+		{name: "direct", remoteAddr: "192.168.1.1:23333", want: "192.168.1.1"},
+		{name: "forged header", remoteAddr: "192.168.1.1:23333", forwardedFor: "1.1.1.1", want: "192.168.1.1"},
+		{name: "trusted proxy", remoteAddr: "10.0.0.1:23333", forwardedFor: "192.168.1.1", want: "192.168.1.1"},
+		{name: "forged header through proxies", remoteAddr: "10.0.0.1:23333", forwardedFor: "1.1.1.1, 192.168.1.1, 172.16.0.2", want: "192.168.1.1"},
+		{name: "proxy without header", remoteAddr: "10.0.0.1:23333", want: "10.0.0.1"},
+		{name: "IPv6", remoteAddr: "[::1]:23333", forwardedFor: "1.1.1.1", want: "::1"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, clientIP(tc.remoteAddr, tc.forwardedFor))
+		})
+	}
+}
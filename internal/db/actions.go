@@ -6,11 +6,13 @@ package db
 
 import (
 	"context"
+	"sync"
 
 	"github.com/go-sql-driver/mysql"
 	"github.com/jackc/pgconn"
 	"github.com/pkg/errors"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 var _ ActionsStore = (*actions)(nil)
@@ -22,8 +24,14 @@ var Actions ActionsStore
 type ActionsStore interface {
 	// Create creates a new action and persists to database, it returns the Action if succeeded.
 	Create(ctx context.Context, opts CreateActionOptions) (*Action, error)
+	// BatchCreate creates the actions in a transaction, the returned actions are in the same order as the options.
+	// The action is nil if it already exists, and ErrGameBoxNotExists is returned if any game box does not exist.
+	BatchCreate(ctx context.Context, opts []CreateActionOptions) ([]*Action, error)
 	// Get returns the actions according to the given options.
 	Get(ctx context.Context, opts GetActionOptions) ([]*Action, error)
+	// GetByType returns the actions with the given type in the given round.
+	// Unlike Get, it can be used for the ActionTypeBeenAttack whose value is zero.
+	GetByType(ctx context.Context, actionType ActionType, round uint) ([]*Action, error)
 	// SetScore updates the action's score.
 	SetScore(ctx context.Context, opts SetActionScoreOptions) error
 	// CountScore counts score with the given options.
@@ -44,10 +52,16 @@ func NewActionsStore(db *gorm.DB) ActionsStore {
 type ActionType uint
 
 const (
+	// ActionTypeBeenAttack is created on the victim's game box for each accepted flag,
+	// it records the attacker team with AttackerTeamID.
 	ActionTypeBeenAttack ActionType = iota
 	ActionTypeCheckDown
+	// ActionTypeAttack is created by the scoring strategy on the attacker's game box of the same challenge,
+	// it holds the attack score gained in the round. It is not created by the flag submission.
 	ActionTypeAttack
 	ActionTypeServiceOnline
+	// ActionTypeFirstBlood is the bonus for the attacker's game box of the first been attacked action of a challenge.
+	ActionTypeFirstBlood
 )
 
 // Action represents the action such as check down or being attacked.
@@ -62,10 +76,18 @@ type Action struct {
 	Round          uint       `gorm:"uniqueIndex:action_unique_idx"`
 
 	Score float64
+
+	// FirstBlood is whether the been attacked action is recorded as the first blood of the challenge
+	// when it is created. It is only set by Create and BatchCreate.
+	FirstBlood bool `gorm:"-" json:"-"`
 }
 
 type actions struct {
 	*gorm.DB
+
+	// firstBloodChallenges is the set of the challenges whose first blood has been recorded,
+	// the first blood of them is not recorded again.
+	firstBloodChallenges sync.Map
 }
 
 type CreateActionOptions struct {
@@ -78,7 +100,7 @@ type CreateActionOptions struct {
 var ErrDuplicateAction = errors.New("duplicate action")
 
 func (db *actions) Create(ctx context.Context, opts CreateActionOptions) (*Action, error) {
-	if opts.Type == ActionTypeCheckDown || opts.Type == ActionTypeAttack {
+	if opts.Type == ActionTypeCheckDown || opts.Type == ActionTypeAttack || opts.Type == ActionTypeFirstBlood {
 		opts.AttackerTeamID = 0
 	}
 
@@ -88,52 +110,180 @@ func (db *actions) Create(ctx context.Context, opts CreateActionOptions) (*Actio
 		return nil, err
 	}
 
-	tx := db.WithContext(ctx).Begin()
+	// The transaction is nested as a save point when the store is used in a transaction.
 	var action Action
-	err = tx.Model(&Action{}).Where(&Action{
-		Type:           opts.Type,
-		TeamID:         gameBox.TeamID,
-		ChallengeID:    gameBox.ChallengeID,
-		GameBoxID:      gameBox.ID,
-		AttackerTeamID: opts.AttackerTeamID,
-		Round:          opts.Round,
-	}).First(&action).Error
-	if err == nil {
-		tx.Rollback()
-		return nil, ErrDuplicateAction
-	} else if err != gorm.ErrRecordNotFound {
-		tx.Rollback()
-		return nil, errors.Wrap(err, "get action")
+	err = db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&Action{}).Where(&Action{
+			Type:           opts.Type,
+			TeamID:         gameBox.TeamID,
+			ChallengeID:    gameBox.ChallengeID,
+			GameBoxID:      gameBox.ID,
+			AttackerTeamID: opts.AttackerTeamID,
+			Round:          opts.Round,
+		}).First(&action).Error
+		if err == nil {
+			return ErrDuplicateAction
+		} else if err != gorm.ErrRecordNotFound {
+			return errors.Wrap(err, "get action")
+		}
+
+		action = Action{
+			Type:           opts.Type,
+			TeamID:         gameBox.TeamID,
+			ChallengeID:    gameBox.ChallengeID,
+			GameBoxID:      gameBox.ID,
+			AttackerTeamID: opts.AttackerTeamID,
+			Round:          opts.Round,
+		}
+		if err := tx.Create(&action).Error; err != nil {
+			return err
+		}
+		return db.recordFirstBloods(tx, []*Action{&action})
+	})
+	if err != nil {
+		if err == ErrDuplicateAction || isDuplicateKeyError(err) {
+			return nil, ErrDuplicateAction
+		}
+		return nil, err
 	}
+	db.cacheFirstBloods([]*Action{&action})
 
-	action = Action{
-		Type:           opts.Type,
-		TeamID:         gameBox.TeamID,
-		ChallengeID:    gameBox.ChallengeID,
-		GameBoxID:      gameBox.ID,
-		AttackerTeamID: opts.AttackerTeamID,
-		Round:          opts.Round,
+	return &action, nil
+}
+
+// isDuplicateKeyError returns whether the error is caused by the unique index.
+func isDuplicateKeyError(err error) bool {
+	// NOTE: How to check if error type is DUPLICATE KEY in GORM.
+	// https://github.com/go-gorm/gorm/issues/4037
+
+	// Postgres
+	if pgError, ok := err.(*pgconn.PgError); ok && errors.Is(err, pgError) && pgError.Code == "23505" {
+		return true
 	}
-	err = tx.Create(&action).Error
-	if err != nil {
-		tx.Rollback()
+	// MySQL
+	var mysqlErr mysql.MySQLError
+	return errors.As(err, &mysqlErr) && mysqlErr.Number == 1062
+}
 
-		// NOTE: How to check if error type is DUPLICATE KEY in GORM.
-		// https://github.com/go-gorm/gorm/issues/4037
+// actionKey is the unique key of the action.
+type actionKey struct {
+	Type           ActionType
+	GameBoxID      uint
+	AttackerTeamID uint
+	Round          uint
+}
 
-		// Postgres
-		if pgError, ok := err.(*pgconn.PgError); ok && errors.Is(err, pgError) && pgError.Code == "23505" {
-			return nil, ErrDuplicateAction
+func (db *actions) BatchCreate(ctx context.Context, opts []CreateActionOptions) ([]*Action, error) {
+	if len(opts) == 0 {
+		return []*Action{}, nil
+	}
+
+	gameBoxIDs := make([]uint, 0, len(opts))
+	rounds := make([]uint, 0, len(opts))
+	for i := range opts {
+		if opts[i].Type == ActionTypeCheckDown || opts[i].Type == ActionTypeAttack || opts[i].Type == ActionTypeFirstBlood {
+			opts[i].AttackerTeamID = 0
+		}
+		gameBoxIDs = append(gameBoxIDs, opts[i].GameBoxID)
+		rounds = append(rounds, opts[i].Round)
+	}
+
+	var gameBoxes []*GameBox
+	if err := db.WithContext(ctx).Model(&GameBox{}).Where("id IN ?", gameBoxIDs).Find(&gameBoxes).Error; err != nil {
+		return nil, errors.Wrap(err, "get game boxes")
+	}
+	gameBoxSets := make(map[uint]*GameBox, len(gameBoxes))
+	for _, gameBox := range gameBoxes {
+		gameBoxSets[gameBox.ID] = gameBox
+	}
+
+	actions := make([]*Action, len(opts))
+	err := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var existActions []*Action
+		if err := tx.Model(&Action{}).Where("game_box_id IN ? AND round IN ?", gameBoxIDs, rounds).Find(&existActions).Error; err != nil {
+			return errors.Wrap(err, "get actions")
+		}
+		existKeys := make(map[actionKey]struct{}, len(existActions))
+		for _, action := range existActions {
+			existKeys[actionKey{action.Type, action.GameBoxID, action.AttackerTeamID, action.Round}] = struct{}{}
+		}
+
+		newActions := make([]*Action, 0, len(opts))
+		for i, opt := range opts {
+			gameBox, ok := gameBoxSets[opt.GameBoxID]
+			if !ok {
+				return ErrGameBoxNotExists
+			}
+
+			// The duplicate actions in the options are also skipped.
+			key := actionKey{opt.Type, opt.GameBoxID, opt.AttackerTeamID, opt.Round}
+			if _, ok := existKeys[key]; ok {
+				continue
+			}
+			existKeys[key] = struct{}{}
+
+			actions[i] = &Action{
+				Type:           opt.Type,
+				TeamID:         gameBox.TeamID,
+				ChallengeID:    gameBox.ChallengeID,
+				GameBoxID:      gameBox.ID,
+				AttackerTeamID: opt.AttackerTeamID,
+				Round:          opt.Round,
+			}
+			newActions = append(newActions, actions[i])
+		}
+		if len(newActions) == 0 {
+			return nil
+		}
+		if err := tx.CreateInBatches(newActions, len(newActions)).Error; err != nil {
+			return err
+		}
+		return db.recordFirstBloods(tx, newActions)
+	})
+	if err != nil {
+		if err == ErrGameBoxNotExists {
+			return nil, ErrGameBoxNotExists
 		}
-		// MySQL
-		var mysqlErr mysql.MySQLError
-		if errors.As(err, &mysqlErr) && mysqlErr.Number == 1062 {
+		if isDuplicateKeyError(err) {
 			return nil, ErrDuplicateAction
 		}
 		return nil, err
 	}
+	db.cacheFirstBloods(actions)
+	return actions, nil
+}
+
+// recordFirstBloods records the been attacked actions as the first bloods of their challenges in the transaction.
+// Only the first action of each challenge is recorded because of the unique index,
+// so there is only one first blood even if the flags are submitted at the same time.
+func (db *actions) recordFirstBloods(tx *gorm.DB, actions []*Action) error {
+	for _, action := range actions {
+		if action == nil || action.Type != ActionTypeBeenAttack {
+			continue
+		}
+		if _, ok := db.firstBloodChallenges.Load(action.ChallengeID); ok {
+			continue
+		}
 
-	return &action, tx.Commit().Error
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&FirstBlood{
+			ChallengeID: action.ChallengeID,
+			ActionID:    action.ID,
+		})
+		if result.Error != nil {
+			return errors.Wrap(result.Error, "create first blood")
+		}
+		action.FirstBlood = result.RowsAffected == 1
+	}
+	return nil
+}
+
+// cacheFirstBloods marks the challenges of the been attacked actions as recorded after the transaction is committed.
+func (db *actions) cacheFirstBloods(actions []*Action) {
+	for _, action := range actions {
+		if action != nil && action.Type == ActionTypeBeenAttack {
+			db.firstBloodChallenges.Store(action.ChallengeID, struct{}{})
+		}
+	}
 }
 
 type GetActionOptions struct {
@@ -159,6 +309,11 @@ func (db *actions) Get(ctx context.Context, opts GetActionOptions) ([]*Action, e
 	}).Find(&actions).Error
 }
 
+func (db *actions) GetByType(ctx context.Context, actionType ActionType, round uint) ([]*Action, error) {
+	var actions []*Action
+	return actions, db.WithContext(ctx).Model(&Action{}).Where("type = ? AND round = ?", actionType, round).Order("id ASC").Find(&actions).Error
+}
+
 type SetActionScoreOptions struct {
 	ActionID  uint
 	Round     uint
@@ -187,12 +342,12 @@ func (db *actions) SetScore(ctx context.Context, opts SetActionScoreOptions) err
 	action := actions[0]
 
 	// Check the action score sign, the BeenAttack and CheckDown score must be negative,
-	// the Attack and ServiceOnline score must be positive.
+	// the Attack, ServiceOnline and FirstBlood score must be positive.
 	if action.Type == ActionTypeBeenAttack || action.Type == ActionTypeCheckDown {
 		if opts.Score > 0 {
 			return ErrActionScoreInvalid
 		}
-	} else if action.Type == ActionTypeAttack || action.Type == ActionTypeServiceOnline {
+	} else if action.Type == ActionTypeAttack || action.Type == ActionTypeServiceOnline || action.Type == ActionTypeFirstBlood {
 		if opts.Score < 0 {
 			return ErrActionScoreInvalid
 		}
@@ -245,19 +400,69 @@ type DeleteActionOptions struct {
 }
 
 func (db *actions) Delete(ctx context.Context, opts DeleteActionOptions) error {
-	return db.WithContext(ctx).Where(&Action{
-		Model: gorm.Model{
-			ID: opts.ActionID,
-		},
-		Type:           opts.Type,
-		TeamID:         opts.TeamID,
-		ChallengeID:    opts.ChallengeID,
-		GameBoxID:      opts.GameBoxID,
-		AttackerTeamID: opts.AttackerTeamID,
-		Round:          opts.Round,
-	}).Delete(&Action{}).Error
+	var firstBloods []*FirstBlood
+	err := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var actionIDs []uint
+		q := tx.Unscoped().Model(&Action{}).Where(&Action{
+			Model: gorm.Model{
+				ID: opts.ActionID,
+			},
+			Type:           opts.Type,
+			TeamID:         opts.TeamID,
+			ChallengeID:    opts.ChallengeID,
+			GameBoxID:      opts.GameBoxID,
+			AttackerTeamID: opts.AttackerTeamID,
+			Round:          opts.Round,
+		})
+		if err := q.Pluck("id", &actionIDs).Error; err != nil {
+			return errors.Wrap(err, "get action IDs")
+		}
+		if len(actionIDs) == 0 {
+			return nil
+		}
+
+		// The actions are hard deleted, so they can be created again without conflicting with the unique index.
+		if err := tx.Unscoped().Where("id IN ?", actionIDs).Delete(&Action{}).Error; err != nil {
+			return errors.Wrap(err, "delete actions")
+		}
+
+		// The first bloods of the deleted actions are deleted too, so the next been attacked action gets it.
+		if err := tx.Where("action_id IN ?", actionIDs).Find(&firstBloods).Error; err != nil {
+			return errors.Wrap(err, "get first bloods")
+		}
+		if len(firstBloods) == 0 {
+			return nil
+		}
+		if err := tx.Unscoped().Delete(&firstBloods).Error; err != nil {
+			return errors.Wrap(err, "delete first bloods")
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, firstBlood := range firstBloods {
+		db.firstBloodChallenges.Delete(firstBlood.ChallengeID)
+	}
+	return nil
 }
 
 func (db *actions) DeleteAll(ctx context.Context) error {
-	return db.WithContext(ctx).Session(&gorm.Session{AllowGlobalUpdate: true}).Delete(&Action{}).Error
+	// The recorded first bloods are deleted with the actions.
+	err := db.WithContext(ctx).Session(&gorm.Session{AllowGlobalUpdate: true}).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&Action{}).Error; err != nil {
+			return errors.Wrap(err, "delete actions")
+		}
+		return tx.Unscoped().Delete(&FirstBlood{}).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	db.firstBloodChallenges.Range(func(key, _ interface{}) bool {
+		db.firstBloodChallenges.Delete(key)
+		return true
+	})
+	return nil
 }
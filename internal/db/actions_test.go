@@ -82,14 +82,14 @@ func TestActions(t *testing.T) {
 	})
 	assert.Nil(t, err)
 
-	actionsStore := NewActionsStore(db)
-
 	for _, tc := range []struct {
 		name string
 		test func(t *testing.T, ctx context.Context, db *actions)
 	}{
 		{"Create", testActionsCreate},
+		{"BatchCreate", testActionsBatchCreate},
 		{"Get", testActionsGet},
+		{"GetByType", testActionsGetByType},
 		{"SetScore", testActionsSetScore},
 		{"CountScore", testActionsCountScore},
 		{"GetEmptyScore", testActionsGetEmptyScore},
@@ -98,12 +98,13 @@ func TestActions(t *testing.T) {
 	} {
 		t.Run(tc.name, func(t *testing.T) {
 			t.Cleanup(func() {
-				err := cleanup("actions")
+				err := cleanup("actions", "first_bloods")
 				if err != nil {
 					t.Fatal(err)
 				}
 			})
-			tc.test(t, context.Background(), actionsStore.(*actions))
+			// The recorded first bloods are cached in the store, so each test uses a new one.
+			tc.test(t, context.Background(), NewActionsStore(db).(*actions))
 		})
 	}
 }
@@ -131,9 +132,20 @@ func testActionsCreate(t *testing.T, ctx context.Context, db *actions) {
 		AttackerTeamID: 2,
 		Round:          1,
 		Score:          0,
+		FirstBlood:     true,
 	}
 	assert.Equal(t, want, got)
 
+	// Only the first been attacked action of the challenge is the first blood.
+	got, err = db.Create(ctx, CreateActionOptions{
+		Type:           ActionTypeBeenAttack,
+		GameBoxID:      1,
+		AttackerTeamID: 2,
+		Round:          2,
+	})
+	assert.Nil(t, err)
+	assert.False(t, got.FirstBlood)
+
 	_, err = db.Create(ctx, CreateActionOptions{
 		Type:           ActionTypeBeenAttack,
 		GameBoxID:      1,
@@ -152,6 +164,52 @@ func testActionsCreate(t *testing.T, ctx context.Context, db *actions) {
 	assert.Equal(t, ErrGameBoxNotExists, err)
 }
 
+func testActionsBatchCreate(t *testing.T, ctx context.Context, db *actions) {
+	_, err := db.Create(ctx, CreateActionOptions{
+		Type:           ActionTypeBeenAttack,
+		GameBoxID:      1,
+		AttackerTeamID: 2,
+		Round:          1,
+	})
+	assert.Nil(t, err)
+
+	got, err := db.BatchCreate(ctx, []CreateActionOptions{
+		{Type: ActionTypeBeenAttack, GameBoxID: 1, AttackerTeamID: 2, Round: 1}, // Exists.
+		{Type: ActionTypeBeenAttack, GameBoxID: 1, AttackerTeamID: 2, Round: 2},
+		{Type: ActionTypeBeenAttack, GameBoxID: 1, AttackerTeamID: 2, Round: 2}, // Duplicate in the options.
+		{Type: ActionTypeCheckDown, GameBoxID: 2, AttackerTeamID: 1, Round: 2},
+	})
+	assert.Nil(t, err)
+	assert.Len(t, got, 4)
+	assert.Nil(t, got[0])
+	assert.NotNil(t, got[1])
+	assert.Nil(t, got[2])
+	assert.NotNil(t, got[3])
+
+	assert.Equal(t, uint(1), got[1].TeamID)
+	assert.Equal(t, uint(1), got[1].ChallengeID)
+	assert.Equal(t, uint(2), got[1].AttackerTeamID)
+	assert.False(t, got[1].FirstBlood)
+	// The attacker team of the check down action is ignored.
+	assert.Equal(t, uint(2), got[3].TeamID)
+	assert.Equal(t, uint(0), got[3].AttackerTeamID)
+
+	actions, err := db.Get(ctx, GetActionOptions{})
+	assert.Nil(t, err)
+	assert.Len(t, actions, 3)
+
+	_, err = db.BatchCreate(ctx, []CreateActionOptions{
+		{Type: ActionTypeBeenAttack, GameBoxID: 1, AttackerTeamID: 2, Round: 3},
+		{Type: ActionTypeBeenAttack, GameBoxID: 3, AttackerTeamID: 2, Round: 3},
+	})
+	assert.Equal(t, ErrGameBoxNotExists, err)
+
+	// Nothing is created if any game box does not exist.
+	actions, err = db.Get(ctx, GetActionOptions{Round: 3})
+	assert.Nil(t, err)
+	assert.Len(t, actions, 0)
+}
+
 func testActionsGet(t *testing.T, ctx context.Context, db *actions) {
 	_, err := db.Create(ctx, CreateActionOptions{
 		Type:           ActionTypeBeenAttack,
@@ -241,6 +299,43 @@ func testActionsGet(t *testing.T, ctx context.Context, db *actions) {
 	assert.Equal(t, want, got)
 }
 
+func testActionsGetByType(t *testing.T, ctx context.Context, db *actions) {
+	_, err := db.Create(ctx, CreateActionOptions{Type: ActionTypeBeenAttack, GameBoxID: 1, AttackerTeamID: 2, Round: 1})
+	assert.Nil(t, err)
+	_, err = db.Create(ctx, CreateActionOptions{Type: ActionTypeCheckDown, GameBoxID: 2, Round: 1})
+	assert.Nil(t, err)
+	_, err = db.Create(ctx, CreateActionOptions{Type: ActionTypeBeenAttack, GameBoxID: 2, AttackerTeamID: 1, Round: 2})
+	assert.Nil(t, err)
+
+	// The been attacked actions in the first round only.
+	got, err := db.GetByType(ctx, ActionTypeBeenAttack, 1)
+	assert.Nil(t, err)
+
+	for _, action := range got {
+		action.CreatedAt = time.Time{}
+		action.UpdatedAt = time.Time{}
+	}
+
+	want := []*Action{
+		{
+			Model: gorm.Model{
+				ID: 1,
+			},
+			Type:           ActionTypeBeenAttack,
+			TeamID:         1,
+			ChallengeID:    1,
+			GameBoxID:      1,
+			AttackerTeamID: 2,
+			Round:          1,
+		},
+	}
+	assert.Equal(t, want, got)
+
+	got, err = db.GetByType(ctx, ActionTypeCheckDown, 2)
+	assert.Nil(t, err)
+	assert.Len(t, got, 0)
+}
+
 func testActionsSetScore(t *testing.T, ctx context.Context, db *actions) {
 	_, err := db.Create(ctx, CreateActionOptions{
 		Type:           ActionTypeAttack,
@@ -488,6 +583,21 @@ func testActionsDelete(t *testing.T, ctx context.Context, db *actions) {
 	assert.Nil(t, err)
 	_, err = db.Get(ctx, GetActionOptions{})
 	assert.Nil(t, err)
+
+	// The first blood is deleted with its action, so the next been attacked action gets it.
+	var count int64
+	err = db.Model(&FirstBlood{}).Count(&count).Error
+	assert.Nil(t, err)
+	assert.Equal(t, int64(0), count)
+
+	action, err := db.Create(ctx, CreateActionOptions{
+		Type:           ActionTypeBeenAttack,
+		GameBoxID:      2,
+		AttackerTeamID: 1,
+		Round:          1,
+	})
+	assert.Nil(t, err)
+	assert.True(t, action.FirstBlood)
 }
 
 func testActionsDeleteAll(t *testing.T, ctx context.Context, db *actions) {
@@ -0,0 +1,139 @@
+// Copyright 2021 E99p1ant. All rights reserved.
+// Use of this source code is governed by an AGPL-style
+// license that can be found in the LICENSE file.
+
+package db
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+var _ AttacksStore = (*attacks)(nil)
+
+// Attacks is the default instance of the AttacksStore.
+var Attacks AttacksStore
+
+// AttacksStore is the statistics interface for the attacks, which are aggregated from the been attacked actions.
+type AttacksStore interface {
+	// Matrix returns the count of the flags captured by each attacker team from each victim team,
+	// order by the attacker team and the victim team.
+	Matrix(ctx context.Context, opts GetAttackStatsOptions) ([]*AttackMatrixItem, error)
+	// FirstBloods returns the been attacked actions recorded as the first blood of each challenge, order by the challenge.
+	FirstBloods(ctx context.Context, opts GetAttackStatsOptions) ([]*Action, error)
+	// MostAttacked returns the victim teams order by the count of the captured flags.
+	MostAttacked(ctx context.Context, opts GetAttackStatsOptions) ([]*AttackStat, error)
+	// MostEffectiveAttackers returns the attacker teams order by the count of the captured flags.
+	MostEffectiveAttackers(ctx context.Context, opts GetAttackStatsOptions) ([]*AttackStat, error)
+}
+
+// NewAttacksStore returns a AttacksStore instance with the given database connection.
+func NewAttacksStore(db *gorm.DB) AttacksStore {
+	return &attacks{DB: db}
+}
+
+type attacks struct {
+	*gorm.DB
+}
+
+// FirstBlood records the first been attacked action of a challenge. It is created in the same transaction
+// as the action, and the unique index makes sure there is only one for each challenge.
+type FirstBlood struct {
+	gorm.Model
+
+	ChallengeID uint `gorm:"uniqueIndex"`
+	ActionID    uint
+}
+
+// AttackMatrixItem is the cell of the attacker x victim matrix.
+type AttackMatrixItem struct {
+	AttackerTeamID uint
+	TeamID         uint
+	Count          int64
+}
+
+// AttackStat is the attack statistics of a team.
+type AttackStat struct {
+	TeamID uint
+	// Count is the count of the captured flags.
+	Count int64
+	// Teams is the count of the distinct opponent teams, they are the attackers for the victim team,
+	// and the victims for the attacker team.
+	Teams int64
+}
+
+type GetAttackStatsOptions struct {
+	// Round is the round of the attacks, all the rounds are counted cumulatively if it is zero.
+	Round uint
+	// MaxRound is the last round counted, it is used to delay the statistics.
+	MaxRound    uint
+	ChallengeID uint
+	// Limit is the max number of the teams returned by MostAttacked and MostEffectiveAttackers.
+	Limit int
+}
+
+func (db *attacks) query(ctx context.Context, opts GetAttackStatsOptions) *gorm.DB {
+	// The ActionTypeBeenAttack is zero, so it can't be used as the struct condition.
+	q := db.WithContext(ctx).Model(&Action{}).Where("type = ?", ActionTypeBeenAttack).Where(&Action{
+		ChallengeID: opts.ChallengeID,
+		Round:       opts.Round,
+	})
+	if opts.MaxRound != 0 {
+		q = q.Where("round <= ?", opts.MaxRound)
+	}
+	return q
+}
+
+func (db *attacks) Matrix(ctx context.Context, opts GetAttackStatsOptions) ([]*AttackMatrixItem, error) {
+	var items []*AttackMatrixItem
+	if err := db.query(ctx, opts).
+		Select("attacker_team_id, team_id, COUNT(*) AS count").
+		Group("attacker_team_id, team_id").Order("attacker_team_id, team_id").
+		Find(&items).Error; err != nil {
+		return nil, errors.Wrap(err, "count")
+	}
+	return items, nil
+}
+
+func (db *attacks) FirstBloods(ctx context.Context, opts GetAttackStatsOptions) ([]*Action, error) {
+	firstBloods := db.WithContext(ctx).Model(&FirstBlood{}).Select("action_id")
+
+	q := db.WithContext(ctx).Model(&Action{}).Where("id IN (?)", firstBloods).Where(&Action{
+		ChallengeID: opts.ChallengeID,
+	})
+	if opts.MaxRound != 0 {
+		q = q.Where("round <= ?", opts.MaxRound)
+	}
+
+	var actions []*Action
+	if err := q.Order("challenge_id").Find(&actions).Error; err != nil {
+		return nil, errors.Wrap(err, "get first blood actions")
+	}
+	return actions, nil
+}
+
+func (db *attacks) MostAttacked(ctx context.Context, opts GetAttackStatsOptions) ([]*AttackStat, error) {
+	return db.stats(ctx, opts, "team_id", "attacker_team_id")
+}
+
+func (db *attacks) MostEffectiveAttackers(ctx context.Context, opts GetAttackStatsOptions) ([]*AttackStat, error) {
+	return db.stats(ctx, opts, "attacker_team_id", "team_id")
+}
+
+// stats counts the captured flags grouped by the team column, and the distinct opponent teams.
+func (db *attacks) stats(ctx context.Context, opts GetAttackStatsOptions, teamColumn, opponentColumn string) ([]*AttackStat, error) {
+	q := db.query(ctx, opts).
+		Select(teamColumn + " AS team_id, COUNT(*) AS count, COUNT(DISTINCT " + opponentColumn + ") AS teams").
+		Group(teamColumn).Order("count DESC, team_id ASC")
+	if opts.Limit > 0 {
+		q = q.Limit(opts.Limit)
+	}
+
+	var stats []*AttackStat
+	if err := q.Find(&stats).Error; err != nil {
+		return nil, errors.Wrap(err, "count")
+	}
+	return stats, nil
+}
@@ -0,0 +1,169 @@
+// Copyright 2021 E99p1ant. All rights reserved.
+// Use of this source code is governed by an AGPL-style
+// license that can be found in the LICENSE file.
+
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAttacks(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+
+	t.Parallel()
+
+	db, cleanup := newTestDB(t)
+
+	// The game box ID is `(TeamID - 1) * 2 + ChallengeID`.
+	err := db.Create([]*Action{
+		{Type: ActionTypeBeenAttack, TeamID: 2, ChallengeID: 1, GameBoxID: 3, AttackerTeamID: 1, Round: 1},
+		{Type: ActionTypeBeenAttack, TeamID: 3, ChallengeID: 1, GameBoxID: 5, AttackerTeamID: 1, Round: 1},
+		{Type: ActionTypeBeenAttack, TeamID: 1, ChallengeID: 1, GameBoxID: 1, AttackerTeamID: 2, Round: 2},
+		{Type: ActionTypeBeenAttack, TeamID: 1, ChallengeID: 2, GameBoxID: 2, AttackerTeamID: 3, Round: 2},
+		{Type: ActionTypeBeenAttack, TeamID: 3, ChallengeID: 1, GameBoxID: 5, AttackerTeamID: 2, Round: 2},
+		{Type: ActionTypeBeenAttack, TeamID: 2, ChallengeID: 1, GameBoxID: 3, AttackerTeamID: 1, Round: 2},
+		{Type: ActionTypeBeenAttack, TeamID: 1, ChallengeID: 2, GameBoxID: 2, AttackerTeamID: 2, Round: 2},
+		// The other actions are not counted.
+		{Type: ActionTypeCheckDown, TeamID: 2, ChallengeID: 2, GameBoxID: 4, Round: 1},
+		{Type: ActionTypeAttack, TeamID: 1, ChallengeID: 1, GameBoxID: 1, Round: 1},
+	}).Error
+	assert.Nil(t, err)
+	// The first bloods are recorded when the actions are created.
+	err = db.Create([]*FirstBlood{
+		{ChallengeID: 1, ActionID: 1},
+		{ChallengeID: 2, ActionID: 4},
+	}).Error
+	assert.Nil(t, err)
+	t.Cleanup(func() {
+		err := cleanup("actions", "first_bloods")
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	attacksStore := NewAttacksStore(db)
+
+	for _, tc := range []struct {
+		name string
+		test func(t *testing.T, ctx context.Context, db *attacks)
+	}{
+		{"Matrix", testAttacksMatrix},
+		{"FirstBloods", testAttacksFirstBloods},
+		{"MostAttacked", testAttacksMostAttacked},
+		{"MostEffectiveAttackers", testAttacksMostEffectiveAttackers},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.test(t, context.Background(), attacksStore.(*attacks))
+		})
+	}
+}
+
+func testAttacksMatrix(t *testing.T, ctx context.Context, db *attacks) {
+	for _, tc := range []struct {
+		name string
+		opts GetAttackStatsOptions
+		want []*AttackMatrixItem
+	}{
+		{
+			name: "cumulative",
+			opts: GetAttackStatsOptions{},
+			want: []*AttackMatrixItem{
+				{AttackerTeamID: 1, TeamID: 2, Count: 2},
+				{AttackerTeamID: 1, TeamID: 3, Count: 1},
+				{AttackerTeamID: 2, TeamID: 1, Count: 2},
+				{AttackerTeamID: 2, TeamID: 3, Count: 1},
+				{AttackerTeamID: 3, TeamID: 1, Count: 1},
+			},
+		},
+		{
+			name: "round",
+			opts: GetAttackStatsOptions{Round: 2},
+			want: []*AttackMatrixItem{
+				{AttackerTeamID: 1, TeamID: 2, Count: 1},
+				{AttackerTeamID: 2, TeamID: 1, Count: 2},
+				{AttackerTeamID: 2, TeamID: 3, Count: 1},
+				{AttackerTeamID: 3, TeamID: 1, Count: 1},
+			},
+		},
+		{
+			name: "max round",
+			opts: GetAttackStatsOptions{MaxRound: 1},
+			want: []*AttackMatrixItem{
+				{AttackerTeamID: 1, TeamID: 2, Count: 1},
+				{AttackerTeamID: 1, TeamID: 3, Count: 1},
+			},
+		},
+		{
+			name: "challenge",
+			opts: GetAttackStatsOptions{ChallengeID: 2},
+			want: []*AttackMatrixItem{
+				{AttackerTeamID: 2, TeamID: 1, Count: 1},
+				{AttackerTeamID: 3, TeamID: 1, Count: 1},
+			},
+		},
+		{
+			name: "no attacks",
+			opts: GetAttackStatsOptions{Round: 3},
+			want: []*AttackMatrixItem{},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := db.Matrix(ctx, tc.opts)
+			assert.Nil(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func testAttacksFirstBloods(t *testing.T, ctx context.Context, db *attacks) {
+	got, err := db.FirstBloods(ctx, GetAttackStatsOptions{})
+	assert.Nil(t, err)
+	assert.Len(t, got, 2)
+	assert.Equal(t, uint(1), got[0].ID)
+	assert.Equal(t, uint(1), got[0].ChallengeID)
+	assert.Equal(t, uint(1), got[0].AttackerTeamID)
+	assert.Equal(t, uint(4), got[1].ID)
+	assert.Equal(t, uint(2), got[1].ChallengeID)
+	assert.Equal(t, uint(3), got[1].AttackerTeamID)
+
+	// The first blood of the challenge 2 is not happened yet in the round 1.
+	got, err = db.FirstBloods(ctx, GetAttackStatsOptions{MaxRound: 1})
+	assert.Nil(t, err)
+	assert.Len(t, got, 1)
+	assert.Equal(t, uint(1), got[0].ID)
+}
+
+func testAttacksMostAttacked(t *testing.T, ctx context.Context, db *attacks) {
+	got, err := db.MostAttacked(ctx, GetAttackStatsOptions{})
+	assert.Nil(t, err)
+	want := []*AttackStat{
+		{TeamID: 1, Count: 3, Teams: 2},
+		{TeamID: 2, Count: 2, Teams: 1},
+		{TeamID: 3, Count: 2, Teams: 2},
+	}
+	assert.Equal(t, want, got)
+}
+
+func testAttacksMostEffectiveAttackers(t *testing.T, ctx context.Context, db *attacks) {
+	got, err := db.MostEffectiveAttackers(ctx, GetAttackStatsOptions{})
+	assert.Nil(t, err)
+	want := []*AttackStat{
+		{TeamID: 1, Count: 3, Teams: 2},
+		{TeamID: 2, Count: 3, Teams: 2},
+		{TeamID: 3, Count: 1, Teams: 1},
+	}
+	assert.Equal(t, want, got)
+
+	got, err = db.MostEffectiveAttackers(ctx, GetAttackStatsOptions{Round: 1, Limit: 1})
+	assert.Nil(t, err)
+	want = []*AttackStat{
+		{TeamID: 1, Count: 2, Teams: 2},
+	}
+	assert.Equal(t, want, got)
+}
@@ -9,6 +9,8 @@ import (
 
 	"github.com/pkg/errors"
 	"gorm.io/gorm"
+
+	"github.com/vidar-team/Cardinal/internal/conf"
 )
 
 var _ ChallengesStore = (*challenges)(nil)
@@ -49,10 +51,36 @@ func NewChallengesStore(db *gorm.DB) ChallengesStore {
 type Challenge struct {
 	gorm.Model
 
-	Title            string
-	BaseScore        float64
+	Title     string
+	BaseScore float64
+	// AttackScore and CheckDownScore override the global scores in the game config,
+	// the global scores are used when they are zero.
+	AttackScore      float64
+	CheckDownScore   float64
 	AutoRenewFlag    bool
 	RenewFlagCommand string
+	// Checker is the name of the checker which checks the service of the game boxes every round,
+	// the game boxes are not checked if it is empty. CheckerConfig is the checker specific configuration.
+	Checker       string
+	CheckerConfig string
+}
+
+// GetAttackScore returns the attack score of the challenge,
+// it falls back to the global attack score if it is not set.
+func (c *Challenge) GetAttackScore() float64 {
+	if c.AttackScore != 0 {
+		return c.AttackScore
+	}
+	return float64(conf.Game.AttackScore)
+}
+
+// GetCheckDownScore returns the check down score of the challenge,
+// it falls back to the global check down score if it is not set.
+func (c *Challenge) GetCheckDownScore() float64 {
+	if c.CheckDownScore != 0 {
+		return c.CheckDownScore
+	}
+	return float64(conf.Game.CheckDownScore)
 }
 
 type challenges struct {
@@ -62,8 +90,12 @@ type challenges struct {
 type CreateChallengeOptions struct {
 	Title            string
 	BaseScore        float64
+	AttackScore      float64
+	CheckDownScore   float64
 	AutoRenewFlag    bool
 	RenewFlagCommand string
+	Checker          string
+	CheckerConfig    string
 }
 
 var ErrChallengeAlreadyExists = errors.New("challenge already exits")
@@ -79,8 +111,12 @@ func (db *challenges) Create(ctx context.Context, opts CreateChallengeOptions) (
 	c := &Challenge{
 		Title:            opts.Title,
 		BaseScore:        opts.BaseScore,
+		AttackScore:      opts.AttackScore,
+		CheckDownScore:   opts.CheckDownScore,
 		AutoRenewFlag:    opts.AutoRenewFlag,
 		RenewFlagCommand: opts.RenewFlagCommand,
+		Checker:          opts.Checker,
+		CheckerConfig:    opts.CheckerConfig,
 	}
 	if err := db.WithContext(ctx).Create(c).Error; err != nil {
 		return 0, err
@@ -106,8 +142,12 @@ func (db *challenges) BatchCreate(ctx context.Context, opts []CreateChallengeOpt
 		c := &Challenge{
 			Title:            option.Title,
 			BaseScore:        option.BaseScore,
+			AttackScore:      option.AttackScore,
+			CheckDownScore:   option.CheckDownScore,
 			AutoRenewFlag:    option.AutoRenewFlag,
 			RenewFlagCommand: option.RenewFlagCommand,
+			Checker:          option.Checker,
+			CheckerConfig:    option.CheckerConfig,
 		}
 		if err := tx.WithContext(ctx).Create(c).Error; err != nil {
 			tx.Rollback()
@@ -158,18 +198,26 @@ func (db *challenges) GetByIDs(ctx context.Context, ids ...uint) ([]*Challenge,
 type UpdateChallengeOptions struct {
 	Title            string
 	BaseScore        float64
+	AttackScore      float64
+	CheckDownScore   float64
 	AutoRenewFlag    bool
 	RenewFlagCommand string
+	Checker          string
+	CheckerConfig    string
 }
 
 func (db *challenges) Update(ctx context.Context, id uint, opts UpdateChallengeOptions) error {
 	return db.WithContext(ctx).Model(&Challenge{}).Where("id = ?", id).
-		Select("Title", "BaseScore", "AutoRenewFlag", "RenewFlagCommand").
+		Select("Title", "BaseScore", "AttackScore", "CheckDownScore", "AutoRenewFlag", "RenewFlagCommand", "Checker", "CheckerConfig").
 		Updates(&Challenge{
 			Title:            opts.Title,
 			BaseScore:        opts.BaseScore,
+			AttackScore:      opts.AttackScore,
+			CheckDownScore:   opts.CheckDownScore,
 			AutoRenewFlag:    opts.AutoRenewFlag,
 			RenewFlagCommand: opts.RenewFlagCommand,
+			Checker:          opts.Checker,
+			CheckerConfig:    opts.CheckerConfig,
 		}).Error
 }
 
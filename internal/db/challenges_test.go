@@ -346,8 +346,12 @@ func testChallengesUpdate(t *testing.T, ctx context.Context, db *challenges) {
 	err = db.Update(ctx, 1, UpdateChallengeOptions{
 		Title:            "Web2",
 		BaseScore:        500,
+		AttackScore:      100,
+		CheckDownScore:   20,
 		AutoRenewFlag:    false,
 		RenewFlagCommand: "echo 'flag'",
+		Checker:          "tcp",
+		CheckerConfig:    "-v",
 	})
 	assert.Nil(t, err)
 
@@ -363,10 +367,16 @@ func testChallengesUpdate(t *testing.T, ctx context.Context, db *challenges) {
 		},
 		Title:            "Web2",
 		BaseScore:        500,
+		AttackScore:      100,
+		CheckDownScore:   20,
 		AutoRenewFlag:    false,
 		RenewFlagCommand: "echo 'flag'",
+		Checker:          "tcp",
+		CheckerConfig:    "-v",
 	}
 	assert.Equal(t, want, got)
+	assert.Equal(t, float64(100), got.GetAttackScore())
+	assert.Equal(t, float64(20), got.GetCheckDownScore())
 }
 
 func testChallengesDeleteByID(t *testing.T, ctx context.Context, db *challenges) {
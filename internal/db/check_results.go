@@ -0,0 +1,247 @@
+// Copyright 2021 E99p1ant. All rights reserved.
+// Use of this source code is governed by an AGPL-style
+// license that can be found in the LICENSE file.
+
+package db
+
+import (
+	"context"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var _ CheckResultsStore = (*checkResults)(nil)
+
+// CheckResults is the default instance of the CheckResultsStore.
+var CheckResults CheckResultsStore
+
+// CheckResultsStore is the persistent interface for the check results of the game boxes.
+type CheckResultsStore interface {
+	// BatchCreate creates the check results and persists to database.
+	// The result of the game box in the same round is overwritten.
+	BatchCreate(ctx context.Context, opts []CreateCheckResultOptions) error
+	// MarkUp records the game boxes which have no check result in the round as up,
+	// the existing results are kept.
+	MarkUp(ctx context.Context, round uint, gameBoxes []*GameBox) error
+	// Get returns the check results with the given options, order by the round and the game box.
+	Get(ctx context.Context, opts GetCheckResultsOptions) ([]*CheckResult, int64, error)
+	// SLA returns the percentage of the rounds which the services are up, grouped by the game box,
+	// the team or the challenge. The rounds with the internal error of the checker are not counted.
+	SLA(ctx context.Context, opts GetCheckSLAOptions) ([]*CheckSLA, error)
+	// DeleteAll deletes all the check results.
+	DeleteAll(ctx context.Context) error
+}
+
+// NewCheckResultsStore returns a CheckResultsStore instance with the given database connection.
+func NewCheckResultsStore(db *gorm.DB) CheckResultsStore {
+	return &checkResults{DB: db}
+}
+
+type CheckStatus string
+
+const (
+	// CheckStatusOK means the service works normally.
+	CheckStatusOK CheckStatus = "OK"
+	// CheckStatusMumble means the service responds unexpectedly.
+	CheckStatusMumble CheckStatus = "MUMBLE"
+	// CheckStatusCorrupt means the flag put into the service can not be retrieved.
+	CheckStatusCorrupt CheckStatus = "CORRUPT"
+	// CheckStatusDown means the service is unreachable.
+	CheckStatusDown CheckStatus = "DOWN"
+	// CheckStatusError means the checker itself failed, the game box is not checked down.
+	CheckStatusError CheckStatus = "ERROR"
+)
+
+// IsDown returns whether the game box should be checked down with the status.
+func (s CheckStatus) IsDown() bool {
+	return s == CheckStatusMumble || s == CheckStatusCorrupt || s == CheckStatusDown
+}
+
+// CheckResult represents the check result of the game box in a round.
+type CheckResult struct {
+	gorm.Model
+
+	TeamID      uint
+	ChallengeID uint
+	GameBoxID   uint `gorm:"uniqueIndex:check_result_unique_idx"`
+	Round       uint `gorm:"uniqueIndex:check_result_unique_idx"`
+
+	Status CheckStatus
+	// PublicMessage is the failure reason shown to the team owning the game box,
+	// PrivateMessage is the debug output of the checker which is only shown to the managers.
+	PublicMessage  string
+	PrivateMessage string
+	// Latency is the time in milliseconds spent on checking the game box.
+	Latency int64
+}
+
+type checkResults struct {
+	*gorm.DB
+}
+
+type CreateCheckResultOptions struct {
+	TeamID         uint
+	ChallengeID    uint
+	GameBoxID      uint
+	Round          uint
+	Status         CheckStatus
+	PublicMessage  string
+	PrivateMessage string
+	Latency        int64
+}
+
+func (db *checkResults) BatchCreate(ctx context.Context, opts []CreateCheckResultOptions) error {
+	if len(opts) == 0 {
+		return nil
+	}
+
+	results := make([]*CheckResult, 0, len(opts))
+	for _, opt := range opts {
+		results = append(results, &CheckResult{
+			TeamID:         opt.TeamID,
+			ChallengeID:    opt.ChallengeID,
+			GameBoxID:      opt.GameBoxID,
+			Round:          opt.Round,
+			Status:         opt.Status,
+			PublicMessage:  opt.PublicMessage,
+			PrivateMessage: opt.PrivateMessage,
+			Latency:        opt.Latency,
+		})
+	}
+
+	// The game boxes may be checked again in the same round after Cardinal restarted.
+	if err := db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "game_box_id"}, {Name: "round"}},
+		DoUpdates: clause.AssignmentColumns([]string{"status", "public_message", "private_message", "latency", "updated_at"}),
+	}).Create(&results).Error; err != nil {
+		return errors.Wrap(err, "batch create check results")
+	}
+	return nil
+}
+
+func (db *checkResults) MarkUp(ctx context.Context, round uint, gameBoxes []*GameBox) error {
+	if len(gameBoxes) == 0 {
+		return nil
+	}
+
+	results := make([]*CheckResult, 0, len(gameBoxes))
+	for _, gameBox := range gameBoxes {
+		results = append(results, &CheckResult{
+			TeamID:      gameBox.TeamID,
+			ChallengeID: gameBox.ChallengeID,
+			GameBoxID:   gameBox.ID,
+			Round:       round,
+			Status:      CheckStatusOK,
+		})
+	}
+
+	if err := db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "game_box_id"}, {Name: "round"}},
+		DoNothing: true,
+	}).Create(&results).Error; err != nil {
+		return errors.Wrap(err, "create check results")
+	}
+	return nil
+}
+
+type GetCheckResultsOptions struct {
+	Page        int
+	PageSize    int
+	TeamID      uint
+	ChallengeID uint
+	GameBoxID   uint
+	Round       uint
+	Status      CheckStatus
+}
+
+func (db *checkResults) Get(ctx context.Context, opts GetCheckResultsOptions) ([]*CheckResult, int64, error) {
+	var results []*CheckResult
+	var count int64
+
+	q := db.WithContext(ctx).Model(&CheckResult{}).Where(&CheckResult{
+		TeamID:      opts.TeamID,
+		ChallengeID: opts.ChallengeID,
+		GameBoxID:   opts.GameBoxID,
+		Round:       opts.Round,
+		Status:      opts.Status,
+	})
+	if err := q.Count(&count).Error; err != nil {
+		return nil, 0, errors.Wrap(err, "count")
+	}
+
+	if opts.Page <= 0 {
+		opts.Page = 1
+	}
+	if opts.PageSize != 0 {
+		q = q.Offset((opts.Page - 1) * opts.PageSize).Limit(opts.PageSize)
+	}
+
+	return results, count, q.Order("round ASC, game_box_id ASC").Find(&results).Error
+}
+
+type CheckSLAGroup string
+
+const (
+	CheckSLAGroupGameBox   CheckSLAGroup = "gameBox"
+	CheckSLAGroupTeam      CheckSLAGroup = "team"
+	CheckSLAGroupChallenge CheckSLAGroup = "challenge"
+)
+
+type GetCheckSLAOptions struct {
+	// GroupBy is the group of the SLA, it is grouped by the game box by default.
+	GroupBy     CheckSLAGroup
+	TeamID      uint
+	ChallengeID uint
+}
+
+// CheckSLA is the SLA of the game box, the team or the challenge,
+// the IDs which are not in the group are zero.
+type CheckSLA struct {
+	GameBoxID   uint    `json:"GameBoxID"`
+	TeamID      uint    `json:"TeamID"`
+	ChallengeID uint    `json:"ChallengeID"`
+	Total       int64   `json:"Total"`
+	Up          int64   `json:"Up"`
+	SLA         float64 `json:"SLA" gorm:"-"` // In percentage.
+}
+
+func (db *checkResults) SLA(ctx context.Context, opts GetCheckSLAOptions) ([]*CheckSLA, error) {
+	var columns []string
+	switch opts.GroupBy {
+	case CheckSLAGroupTeam:
+		columns = []string{"team_id"}
+	case CheckSLAGroupChallenge:
+		columns = []string{"challenge_id"}
+	default:
+		columns = []string{"game_box_id", "team_id", "challenge_id"}
+	}
+	group := strings.Join(columns, ", ")
+
+	var slas []*CheckSLA
+	if err := db.WithContext(ctx).Model(&CheckResult{}).
+		Select(group+", COUNT(*) AS total, COUNT(CASE WHEN status = ? THEN 1 END) AS up", CheckStatusOK).
+		Where(&CheckResult{
+			TeamID:      opts.TeamID,
+			ChallengeID: opts.ChallengeID,
+		}).
+		Where("status <> ?", CheckStatusError).
+		Group(group).Order(group).
+		Find(&slas).Error; err != nil {
+		return nil, errors.Wrap(err, "count")
+	}
+
+	for _, sla := range slas {
+		if sla.Total != 0 {
+			sla.SLA = float64(sla.Up) / float64(sla.Total) * 100
+		}
+	}
+	return slas, nil
+}
+
+func (db *checkResults) DeleteAll(ctx context.Context) error {
+	// The results are hard deleted, so the round can be checked again without conflict.
+	return db.WithContext(ctx).Session(&gorm.Session{AllowGlobalUpdate: true}).Unscoped().Delete(&CheckResult{}).Error
+}
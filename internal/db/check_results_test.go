@@ -0,0 +1,203 @@
+// Copyright 2021 E99p1ant. All rights reserved.
+// Use of this source code is governed by an AGPL-style
+// license that can be found in the LICENSE file.
+
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestCheckResults(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+
+	t.Parallel()
+
+	db, cleanup := newTestDB(t)
+	store := NewCheckResultsStore(db)
+
+	for _, tc := range []struct {
+		name string
+		test func(t *testing.T, ctx context.Context, db *checkResults)
+	}{
+		{"BatchCreate", testCheckResultsBatchCreate},
+		{"MarkUp", testCheckResultsMarkUp},
+		{"Get", testCheckResultsGet},
+		{"SLA", testCheckResultsSLA},
+		{"DeleteAll", testCheckResultsDeleteAll},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Cleanup(func() {
+				err := cleanup("check_results")
+				if err != nil {
+					t.Fatal(err)
+				}
+			})
+			tc.test(t, context.Background(), store.(*checkResults))
+		})
+	}
+}
+
+func createTestCheckResults(t *testing.T, ctx context.Context, db *checkResults) {
+	err := db.BatchCreate(ctx, []CreateCheckResultOptions{
+		{TeamID: 1, ChallengeID: 1, GameBoxID: 1, Round: 1, Status: CheckStatusOK, Latency: 20},
+		{TeamID: 2, ChallengeID: 1, GameBoxID: 2, Round: 1, Status: CheckStatusMumble, PublicMessage: "login failed", PrivateMessage: "POST /login: 500", Latency: 30},
+		{TeamID: 1, ChallengeID: 1, GameBoxID: 1, Round: 2, Status: CheckStatusDown, PublicMessage: "timeout", PrivateMessage: "dial tcp: i/o timeout", Latency: 10},
+		{TeamID: 2, ChallengeID: 1, GameBoxID: 2, Round: 2, Status: CheckStatusOK, Latency: 25},
+	})
+	assert.Nil(t, err)
+}
+
+func testCheckResultsBatchCreate(t *testing.T, ctx context.Context, db *checkResults) {
+	createTestCheckResults(t, ctx, db)
+
+	// The result of the game box in the same round is overwritten.
+	err := db.BatchCreate(ctx, []CreateCheckResultOptions{
+		{TeamID: 1, ChallengeID: 1, GameBoxID: 1, Round: 2, Status: CheckStatusOK, Latency: 15},
+	})
+	assert.Nil(t, err)
+
+	got, count, err := db.Get(ctx, GetCheckResultsOptions{GameBoxID: 1, Round: 2})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), count)
+	assert.Equal(t, CheckStatusOK, got[0].Status)
+	assert.Equal(t, "", got[0].PublicMessage)
+	assert.Equal(t, "", got[0].PrivateMessage)
+	assert.Equal(t, int64(15), got[0].Latency)
+
+	// Nothing happens with no result.
+	err = db.BatchCreate(ctx, nil)
+	assert.Nil(t, err)
+}
+
+func testCheckResultsMarkUp(t *testing.T, ctx context.Context, db *checkResults) {
+	// The challenge is only checked by the reporter, which reports the game box 1 down in round 1.
+	err := db.BatchCreate(ctx, []CreateCheckResultOptions{
+		{TeamID: 1, ChallengeID: 1, GameBoxID: 1, Round: 1, Status: CheckStatusDown, PublicMessage: "exploited"},
+	})
+	assert.Nil(t, err)
+
+	gameBoxes := []*GameBox{
+		{Model: gorm.Model{ID: 1}, TeamID: 1, ChallengeID: 1},
+		{Model: gorm.Model{ID: 2}, TeamID: 2, ChallengeID: 1},
+	}
+	for round := uint(1); round <= 4; round++ {
+		err = db.MarkUp(ctx, round, gameBoxes)
+		assert.Nil(t, err)
+	}
+
+	// The reported result is kept.
+	got, _, err := db.Get(ctx, GetCheckResultsOptions{GameBoxID: 1, Round: 1})
+	assert.Nil(t, err)
+	assert.Equal(t, CheckStatusDown, got[0].Status)
+	assert.Equal(t, "exploited", got[0].PublicMessage)
+
+	// All the rounds are counted in the SLA.
+	slas, err := db.SLA(ctx, GetCheckSLAOptions{})
+	assert.Nil(t, err)
+	want := []*CheckSLA{
+		{GameBoxID: 1, TeamID: 1, ChallengeID: 1, Total: 4, Up: 3, SLA: 75},
+		{GameBoxID: 2, TeamID: 2, ChallengeID: 1, Total: 4, Up: 4, SLA: 100},
+	}
+	assert.Equal(t, want, slas)
+
+	// Nothing happens with no game box.
+	err = db.MarkUp(ctx, 5, nil)
+	assert.Nil(t, err)
+}
+
+func testCheckResultsGet(t *testing.T, ctx context.Context, db *checkResults) {
+	createTestCheckResults(t, ctx, db)
+
+	for _, tc := range []struct {
+		name      string
+		opts      GetCheckResultsOptions
+		wantIDs   []uint
+		wantCount int64
+	}{
+		{name: "all", opts: GetCheckResultsOptions{}, wantIDs: []uint{1, 2, 3, 4}, wantCount: 4},
+		{name: "team", opts: GetCheckResultsOptions{TeamID: 2}, wantIDs: []uint{2, 4}, wantCount: 2},
+		{name: "round", opts: GetCheckResultsOptions{Round: 2}, wantIDs: []uint{3, 4}, wantCount: 2},
+		{name: "status", opts: GetCheckResultsOptions{Status: CheckStatusOK}, wantIDs: []uint{1, 4}, wantCount: 2},
+		{name: "page", opts: GetCheckResultsOptions{Page: 2, PageSize: 3}, wantIDs: []uint{4}, wantCount: 4},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, count, err := db.Get(ctx, tc.opts)
+			assert.Nil(t, err)
+			assert.Equal(t, tc.wantCount, count)
+
+			ids := make([]uint, 0, len(got))
+			for _, result := range got {
+				ids = append(ids, result.ID)
+			}
+			assert.Equal(t, tc.wantIDs, ids)
+		})
+	}
+}
+
+func testCheckResultsSLA(t *testing.T, ctx context.Context, db *checkResults) {
+	createTestCheckResults(t, ctx, db)
+	err := db.BatchCreate(ctx, []CreateCheckResultOptions{
+		{TeamID: 1, ChallengeID: 2, GameBoxID: 3, Round: 1, Status: CheckStatusOK},
+		{TeamID: 1, ChallengeID: 2, GameBoxID: 3, Round: 2, Status: CheckStatusOK},
+		// The internal error of the checker is not counted.
+		{TeamID: 1, ChallengeID: 2, GameBoxID: 3, Round: 3, Status: CheckStatusError},
+		{TeamID: 1, ChallengeID: 1, GameBoxID: 1, Round: 3, Status: CheckStatusCorrupt},
+	})
+	assert.Nil(t, err)
+
+	got, err := db.SLA(ctx, GetCheckSLAOptions{})
+	assert.Nil(t, err)
+	want := []*CheckSLA{
+		{GameBoxID: 1, TeamID: 1, ChallengeID: 1, Total: 3, Up: 1, SLA: float64(1) / 3 * 100},
+		{GameBoxID: 2, TeamID: 2, ChallengeID: 1, Total: 2, Up: 1, SLA: 50},
+		{GameBoxID: 3, TeamID: 1, ChallengeID: 2, Total: 2, Up: 2, SLA: 100},
+	}
+	assert.Equal(t, want, got)
+
+	got, err = db.SLA(ctx, GetCheckSLAOptions{GroupBy: CheckSLAGroupTeam})
+	assert.Nil(t, err)
+	want = []*CheckSLA{
+		{TeamID: 1, Total: 5, Up: 3, SLA: 60},
+		{TeamID: 2, Total: 2, Up: 1, SLA: 50},
+	}
+	assert.Equal(t, want, got)
+
+	got, err = db.SLA(ctx, GetCheckSLAOptions{GroupBy: CheckSLAGroupChallenge, TeamID: 1})
+	assert.Nil(t, err)
+	want = []*CheckSLA{
+		{ChallengeID: 1, Total: 3, Up: 1, SLA: float64(1) / 3 * 100},
+		{ChallengeID: 2, Total: 2, Up: 2, SLA: 100},
+	}
+	assert.Equal(t, want, got)
+}
+
+func testCheckResultsDeleteAll(t *testing.T, ctx context.Context, db *checkResults) {
+	createTestCheckResults(t, ctx, db)
+
+	err := db.DeleteAll(ctx)
+	assert.Nil(t, err)
+
+	got, count, err := db.Get(ctx, GetCheckResultsOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(0), count)
+	assert.Len(t, got, 0)
+}
+
+func TestCheckStatusIsDown(t *testing.T) {
+	for status, want := range map[CheckStatus]bool{
+		CheckStatusOK:      false,
+		CheckStatusMumble:  true,
+		CheckStatusCorrupt: true,
+		CheckStatusDown:    true,
+		CheckStatusError:   false,
+	} {
+		assert.Equal(t, want, status.IsDown(), status)
+	}
+}
@@ -5,6 +5,7 @@
 package db
 
 import (
+	"context"
 	"fmt"
 	"time"
 
@@ -22,11 +23,17 @@ var AllTables = []interface{}{
 	&Action{},
 	&Bulletin{},
 	&Challenge{},
+	&CheckResult{},
+	&FirstBlood{},
 	&Flag{},
 	&GameBox{},
 	&Log{},
 	&Manager{},
+	&Round{},
+	&Setting{},
+	&Submission{},
 	&Team{},
+	&TeamRoundScore{},
 }
 
 type DatabaseType string
@@ -66,6 +73,10 @@ func Init() error {
 		log.Fatal("Unexpected database type: %q", conf.Database.Type)
 	}
 
+	if _, err := GetScoringStrategy(conf.Game.ScoringStrategy); err != nil {
+		return errors.Wrapf(err, "scoring strategy %q", conf.Game.ScoringStrategy)
+	}
+
 	db, err := gorm.Open(dialector, &gorm.Config{
 		NowFunc: func() time.Time {
 			return dbutil.Now()
@@ -79,6 +90,9 @@ func Init() error {
 	if db.AutoMigrate(AllTables...) != nil {
 		return errors.Wrap(err, "auto migrate")
 	}
+	if err := backfillManagerTokens(context.Background(), db); err != nil {
+		return errors.Wrap(err, "backfill manager tokens")
+	}
 
 	SetDatabaseStore(db)
 
@@ -88,13 +102,19 @@ func Init() error {
 // SetDatabaseStore sets the database table store.
 func SetDatabaseStore(db *gorm.DB) {
 	Actions = NewActionsStore(db)
+	Attacks = NewAttacksStore(db)
 	Bulletins = NewBulletinsStore(db)
 	Challenges = NewChallengesStore(db)
+	CheckResults = NewCheckResultsStore(db)
 	Flags = NewFlagsStore(db)
 	GameBoxes = NewGameBoxesStore(db)
 	Ranks = NewRanksStore(db)
 	Scores = NewScoresStore(db)
 	Logs = NewLogsStore(db)
 	Managers = NewManagersStore(db)
+	Rounds = NewRoundsStore(db)
+	Settings = NewSettingsStore(db)
+	Submissions = NewSubmissionsStore(db)
 	Teams = NewTeamsStore(db)
+	TeamRoundScores = NewTeamRoundScoresStore(db)
 }
@@ -10,6 +10,8 @@ import (
 	"github.com/pkg/errors"
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
+
+	"github.com/vidar-team/Cardinal/internal/conf"
 )
 
 var _ FlagsStore = (*flags)(nil)
@@ -26,8 +28,14 @@ type FlagsStore interface {
 	// Count counts the number of the flags with the given options.
 	Count(ctx context.Context, opts CountFlagOptions) (int64, error)
 	// Check checks the given flag.
-	// It returns ErrFlagNotExists when not found.
+	// It returns ErrFlagInvalidFormat when the flag does not match the regular expression in the configuration,
+	// and ErrFlagNotExists when not found.
 	Check(ctx context.Context, flag string) (*Flag, error)
+	// BatchCheck checks the given flags in one query, it returns the existing flags with the flag value as the key.
+	// The flags which do not match the regular expression in the configuration are not looked up.
+	BatchCheck(ctx context.Context, flags []string) (map[string]*Flag, error)
+	// SetStatus sets the delivery status of the flag with the given id.
+	SetStatus(ctx context.Context, id uint, opts SetFlagStatusOptions) error
 	// DeleteAll deletes all the flags.
 	DeleteAll(ctx context.Context) error
 }
@@ -47,8 +55,25 @@ type Flag struct {
 	Round       uint `gorm:"uniqueIndex:flag_unique_idx"`
 
 	Value string
+
+	// Status is the delivery status of the flag to its game box.
+	Status FlagStatus `gorm:"default:pending"`
+	// Message is the error message when the flag failed to be delivered.
+	Message string
+	// ExitCode is the exit code of the command delivering the flag, it is -1 if the command did not exit normally.
+	ExitCode int
+	// Latency is the time in milliseconds spent on delivering the flag.
+	Latency int64
 }
 
+type FlagStatus string
+
+const (
+	FlagStatusPending   FlagStatus = "pending"
+	FlagStatusDelivered FlagStatus = "delivered"
+	FlagStatusFailed    FlagStatus = "failed"
+)
+
 type flags struct {
 	*gorm.DB
 }
@@ -95,6 +120,7 @@ func (db *flags) BatchCreate(ctx context.Context, opts CreateFlagOptions) error
 			GameBoxID:   gameBox.ID,
 			Round:       flag.Round,
 			Value:       flag.Value,
+			Status:      FlagStatusPending,
 		})
 	}
 
@@ -120,6 +146,7 @@ type GetFlagOptions struct {
 	ChallengeID uint
 	GameBoxID   uint
 	Round       uint
+	Status      FlagStatus
 }
 
 func (db *flags) Get(ctx context.Context, opts GetFlagOptions) ([]*Flag, int64, error) {
@@ -131,6 +158,7 @@ func (db *flags) Get(ctx context.Context, opts GetFlagOptions) ([]*Flag, int64,
 		GameBoxID:   opts.GameBoxID,
 		ChallengeID: opts.ChallengeID,
 		Round:       opts.Round,
+		Status:      opts.Status,
 	})
 	q.Count(&count)
 
@@ -164,11 +192,33 @@ func (db *flags) Count(ctx context.Context, opts CountFlagOptions) (int64, error
 	return count, q.Count(&count).Error
 }
 
-var ErrFlagNotExists = errors.New("flag does not find")
+var (
+	ErrFlagNotExists     = errors.New("flag does not find")
+	ErrFlagInvalidFormat = errors.New("flag format is invalid")
+)
+
+// matchFlagFormat checks the flag with the regular expression in the configuration.
+// All the flags are matched if the regular expression is not set.
+func matchFlagFormat(flag string) (bool, error) {
+	re, err := conf.FlagRegexp()
+	if err != nil {
+		return false, err
+	}
+	return re == nil || re.MatchString(flag), nil
+}
 
 func (db *flags) Check(ctx context.Context, flagValue string) (*Flag, error) {
+	// Reject the malformed flag before looking up in the database.
+	matched, err := matchFlagFormat(flagValue)
+	if err != nil {
+		return nil, errors.Wrap(err, "match flag format")
+	}
+	if !matched {
+		return nil, ErrFlagInvalidFormat
+	}
+
 	var flag Flag
-	err := db.WithContext(ctx).Model(&Flag{}).Where("value = ?", flagValue).First(&flag).Error
+	err = db.WithContext(ctx).Model(&Flag{}).Where("value = ?", flagValue).First(&flag).Error
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return nil, ErrFlagNotExists
@@ -178,6 +228,51 @@ func (db *flags) Check(ctx context.Context, flagValue string) (*Flag, error) {
 	return &flag, nil
 }
 
+func (db *flags) BatchCheck(ctx context.Context, flagValues []string) (map[string]*Flag, error) {
+	values := make([]string, 0, len(flagValues))
+	for _, value := range flagValues {
+		matched, err := matchFlagFormat(value)
+		if err != nil {
+			return nil, errors.Wrap(err, "match flag format")
+		}
+		if matched {
+			values = append(values, value)
+		}
+	}
+
+	flagSets := make(map[string]*Flag, len(values))
+	if len(values) == 0 {
+		return flagSets, nil
+	}
+
+	var flags []*Flag
+	if err := db.WithContext(ctx).Model(&Flag{}).Where("value IN ?", values).Find(&flags).Error; err != nil {
+		return nil, errors.Wrap(err, "get")
+	}
+	for _, flag := range flags {
+		flagSets[flag.Value] = flag
+	}
+	return flagSets, nil
+}
+
+type SetFlagStatusOptions struct {
+	Status   FlagStatus
+	Message  string
+	ExitCode int
+	Latency  int64
+}
+
+func (db *flags) SetStatus(ctx context.Context, id uint, opts SetFlagStatusOptions) error {
+	return db.WithContext(ctx).Model(&Flag{}).Where("id = ?", id).
+		Select("Status", "Message", "ExitCode", "Latency").
+		Updates(&Flag{
+			Status:   opts.Status,
+			Message:  opts.Message,
+			ExitCode: opts.ExitCode,
+			Latency:  opts.Latency,
+		}).Error
+}
+
 func (db *flags) DeleteAll(ctx context.Context) error {
 	return db.WithContext(ctx).Session(&gorm.Session{AllowGlobalUpdate: true}).Delete(&Flag{}).Error
 }
@@ -11,6 +11,8 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"gorm.io/gorm"
+
+	"github.com/vidar-team/Cardinal/internal/conf"
 )
 
 func TestFlags(t *testing.T) {
@@ -116,6 +118,8 @@ func TestFlags(t *testing.T) {
 		{"Get", testFlagsGet},
 		{"Count", testFlagsCount},
 		{"Check", testFlagsCheck},
+		{"BatchCheck", testFlagsBatchCheck},
+		{"SetStatus", testFlagsSetStatus},
 		{"DeleteAll", testFlagsDeleteAll},
 	} {
 		t.Run(tc.name, func(t *testing.T) {
@@ -269,6 +273,7 @@ func testFlagsGet(t *testing.T, ctx context.Context, db *flags) {
 			GameBoxID:   1,
 			Round:       1,
 			Value:       "d3ctf{c4ca4238a0b923820dcc509a6f75849b}",
+			Status:      FlagStatusPending,
 		},
 		{
 			Model: gorm.Model{
@@ -279,6 +284,7 @@ func testFlagsGet(t *testing.T, ctx context.Context, db *flags) {
 			GameBoxID:   2,
 			Round:       1,
 			Value:       "d3ctf{c81e728d9d4c2f636f067f89cc14862c}",
+			Status:      FlagStatusPending,
 		},
 		{
 			Model: gorm.Model{
@@ -289,6 +295,7 @@ func testFlagsGet(t *testing.T, ctx context.Context, db *flags) {
 			GameBoxID:   3,
 			Round:       1,
 			Value:       "d3ctf{eccbc87e4b5ce2fe28308fd9f2a7baf3}",
+			Status:      FlagStatusPending,
 		},
 		{
 			Model: gorm.Model{
@@ -299,6 +306,7 @@ func testFlagsGet(t *testing.T, ctx context.Context, db *flags) {
 			GameBoxID:   4,
 			Round:       1,
 			Value:       "d3ctf{a87ff679a2f3e71d9181a67b7542122c}",
+			Status:      FlagStatusPending,
 		},
 	}
 	assert.Equal(t, want, got)
@@ -326,6 +334,7 @@ func testFlagsGet(t *testing.T, ctx context.Context, db *flags) {
 			GameBoxID:   1,
 			Round:       1,
 			Value:       "d3ctf{c4ca4238a0b923820dcc509a6f75849b}",
+			Status:      FlagStatusPending,
 		},
 		{
 			Model: gorm.Model{
@@ -336,6 +345,7 @@ func testFlagsGet(t *testing.T, ctx context.Context, db *flags) {
 			GameBoxID:   2,
 			Round:       1,
 			Value:       "d3ctf{c81e728d9d4c2f636f067f89cc14862c}",
+			Status:      FlagStatusPending,
 		},
 		{
 			Model: gorm.Model{
@@ -346,6 +356,7 @@ func testFlagsGet(t *testing.T, ctx context.Context, db *flags) {
 			GameBoxID:   3,
 			Round:       1,
 			Value:       "d3ctf{eccbc87e4b5ce2fe28308fd9f2a7baf3}",
+			Status:      FlagStatusPending,
 		},
 	}
 	assert.Equal(t, want, got)
@@ -431,6 +442,7 @@ func testFlagsCheck(t *testing.T, ctx context.Context, db *flags) {
 		GameBoxID:   2,
 		Round:       1,
 		Value:       "d3ctf{c81e728d9d4c2f636f067f89cc14862c}",
+		Status:      FlagStatusPending,
 	}
 	assert.Equal(t, want, got)
 
@@ -438,6 +450,85 @@ func testFlagsCheck(t *testing.T, ctx context.Context, db *flags) {
 	assert.Equal(t, ErrFlagNotExists, err)
 }
 
+func testFlagsBatchCheck(t *testing.T, ctx context.Context, db *flags) {
+	conf.Game.FlagRegexp = `^d3ctf\{[0-9a-f]+\}$`
+	t.Cleanup(func() {
+		conf.Game.FlagRegexp = ""
+	})
+
+	err := db.BatchCreate(ctx, CreateFlagOptions{
+		Flags: []FlagMetadata{
+			{
+				GameBoxID: 1,
+				Round:     1,
+				Value:     "d3ctf{c4ca4238a0b923820dcc509a6f75849b}",
+			},
+			{
+				GameBoxID: 2,
+				Round:     1,
+				Value:     "d3ctf{c81e728d9d4c2f636f067f89cc14862c}",
+			},
+		},
+	})
+	assert.Nil(t, err)
+
+	got, err := db.BatchCheck(ctx, []string{
+		"d3ctf{c4ca4238a0b923820dcc509a6f75849b}",
+		"d3ctf{c81e728d9d4c2f636f067f89cc14862c}",
+		"d3ctf{eccbc87e4b5ce2fe28308fd9f2a7baf3}",
+		"d3ctf{malformed}",
+	})
+	assert.Nil(t, err)
+	assert.Len(t, got, 2)
+	assert.Equal(t, uint(1), got["d3ctf{c4ca4238a0b923820dcc509a6f75849b}"].GameBoxID)
+	assert.Equal(t, uint(2), got["d3ctf{c81e728d9d4c2f636f067f89cc14862c}"].GameBoxID)
+
+	got, err = db.BatchCheck(ctx, []string{"d3ctf{malformed}"})
+	assert.Nil(t, err)
+	assert.Len(t, got, 0)
+}
+
+func testFlagsSetStatus(t *testing.T, ctx context.Context, db *flags) {
+	err := db.BatchCreate(ctx, CreateFlagOptions{
+		Flags: []FlagMetadata{
+			{
+				GameBoxID: 1,
+				Round:     1,
+				Value:     "d3ctf{c4ca4238a0b923820dcc509a6f75849b}",
+			},
+			{
+				GameBoxID: 2,
+				Round:     1,
+				Value:     "d3ctf{c81e728d9d4c2f636f067f89cc14862c}",
+			},
+		},
+	})
+	assert.Nil(t, err)
+
+	err = db.SetStatus(ctx, 1, SetFlagStatusOptions{Status: FlagStatusDelivered})
+	assert.Nil(t, err)
+	err = db.SetStatus(ctx, 2, SetFlagStatusOptions{Status: FlagStatusFailed, Message: "exit with code 1", ExitCode: 1, Latency: 120})
+	assert.Nil(t, err)
+
+	got, gotCount, err := db.Get(ctx, GetFlagOptions{Status: FlagStatusFailed})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), gotCount)
+	assert.Equal(t, uint(2), got[0].ID)
+	assert.Equal(t, "exit with code 1", got[0].Message)
+	assert.Equal(t, 1, got[0].ExitCode)
+	assert.Equal(t, int64(120), got[0].Latency)
+
+	// The flag is delivered after retrying, the message is cleared.
+	err = db.SetStatus(ctx, 2, SetFlagStatusOptions{Status: FlagStatusDelivered})
+	assert.Nil(t, err)
+
+	got, gotCount, err = db.Get(ctx, GetFlagOptions{Status: FlagStatusDelivered})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), gotCount)
+	assert.Equal(t, "", got[1].Message)
+	assert.Equal(t, 0, got[1].ExitCode)
+}
+
 func testFlagsDeleteAll(t *testing.T, ctx context.Context, db *flags) {
 	err := db.BatchCreate(ctx, CreateFlagOptions{
 		Flags: []FlagMetadata{
@@ -474,3 +565,19 @@ func testFlagsDeleteAll(t *testing.T, ctx context.Context, db *flags) {
 	assert.Equal(t, want, got)
 	assert.Equal(t, int64(0), gotCount)
 }
+
+func TestFlagsCheckFormat(t *testing.T) {
+	conf.Game.FlagRegexp = `^d3ctf\{[0-9a-f]{32}\}$`
+	t.Cleanup(func() {
+		conf.Game.FlagRegexp = ""
+	})
+
+	// The malformed flag is rejected without the database.
+	flagsStore := NewFlagsStore(nil)
+	_, err := flagsStore.Check(context.Background(), "d3ctf{malformed}")
+	assert.Equal(t, ErrFlagInvalidFormat, err)
+
+	conf.Game.FlagRegexp = `(`
+	_, err = flagsStore.Check(context.Background(), "d3ctf{malformed}")
+	assert.NotNil(t, err)
+}
@@ -74,6 +74,9 @@ type GameBox struct {
 	InternalSSHPort     uint
 	InternalSSHUser     string
 	InternalSSHPassword string
+	// InternalSSHHostKey pins the SSH host key of the game box, it can be the SHA256
+	// fingerprint or the public key in the authorized_keys format.
+	InternalSSHHostKey string
 
 	Visible    bool
 	Score      float64 // The score can be negative.
@@ -89,6 +92,7 @@ type SSHConfig struct {
 	Port     uint
 	User     string
 	Password string
+	HostKey  string
 }
 
 type CreateGameBoxOptions struct {
@@ -137,6 +141,7 @@ func (db *gameboxes) Create(ctx context.Context, opts CreateGameBoxOptions) (uin
 		InternalSSHPort:     opts.InternalSSH.Port,
 		InternalSSHUser:     opts.InternalSSH.User,
 		InternalSSHPassword: opts.InternalSSH.Password,
+		InternalSSHHostKey:  opts.InternalSSH.HostKey,
 		Score:               challenge.BaseScore,
 	}
 
@@ -190,6 +195,7 @@ func (db *gameboxes) BatchCreate(ctx context.Context, opts []CreateGameBoxOption
 			InternalSSHPort:     option.InternalSSH.Port,
 			InternalSSHUser:     option.InternalSSH.User,
 			InternalSSHPassword: option.InternalSSH.Password,
+			InternalSSHHostKey:  option.InternalSSH.HostKey,
 			Score:               challengeSets[option.ChallengeID].BaseScore,
 		}
 		if err := tx.WithContext(ctx).Create(g).Error; err != nil {
@@ -319,6 +325,7 @@ func (db *gameboxes) Update(ctx context.Context, id uint, opts UpdateGameBoxOpti
 			InternalSSHPort:     opts.InternalSSH.Port,
 			InternalSSHUser:     opts.InternalSSH.User,
 			InternalSSHPassword: opts.InternalSSH.Password,
+			InternalSSHHostKey:  opts.InternalSSH.HostKey,
 		}).Error
 }
 
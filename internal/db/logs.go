@@ -46,6 +46,7 @@ const (
 	LogTypeManagerOperate LogType = "manager_operate"
 	LogTypeSSH            LogType = "ssh"
 	LogTypeSystem         LogType = "system"
+	LogTypeFlagSubmission LogType = "flag_submission"
 )
 
 // Log represents the log.
@@ -78,7 +79,7 @@ func (db *logs) Create(ctx context.Context, opts CreateLogOptions) error {
 	}
 
 	switch opts.Type {
-	case LogTypeHealthCheck, LogTypeManagerOperate, LogTypeSSH, LogTypeSystem:
+	case LogTypeHealthCheck, LogTypeManagerOperate, LogTypeSSH, LogTypeSystem, LogTypeFlagSubmission:
 	default:
 		return ErrBadLogType
 	}
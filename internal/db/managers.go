@@ -35,6 +35,12 @@ type ManagersStore interface {
 	// GetByID returns the manager with given id.
 	// It returns ErrManagerNotExists when not found.
 	GetByID(ctx context.Context, id uint) (*Manager, error)
+	// GetByToken returns the manager with given token.
+	// It returns ErrManagerNotExists when not found.
+	GetByToken(ctx context.Context, token string) (*Manager, error)
+	// ResetToken generates a new token for the manager with given id, and returns the new token.
+	// It returns ErrManagerNotExists when not found.
+	ResetToken(ctx context.Context, id uint) (string, error)
 	// ChangePassword changes the manager's password with given id.
 	ChangePassword(ctx context.Context, id uint, newPassword string) error
 	// Update updates the manager with given id.
@@ -58,6 +64,8 @@ type Manager struct {
 	Password       string
 	Salt           string
 	IsCheckAccount bool
+	// Token is used by the check account to report the check down game boxes.
+	Token string
 }
 
 // EncodePassword encodes password to safe format.
@@ -116,6 +124,7 @@ func (db *managers) Create(ctx context.Context, opts CreateManagerOptions) (*Man
 		Password:       opts.Password,
 		Salt:           getManagerSalt(),
 		IsCheckAccount: opts.IsCheckAccount,
+		Token:          randstr.Hex(16), // Random token.
 	}
 	m.EncodePassword()
 
@@ -141,6 +150,51 @@ func (db *managers) GetByID(ctx context.Context, id uint) (*Manager, error) {
 	return &manager, nil
 }
 
+func (db *managers) GetByToken(ctx context.Context, token string) (*Manager, error) {
+	// The empty token is never matched, even if the manager's token is not backfilled yet.
+	if token == "" {
+		return nil, ErrManagerNotExists
+	}
+
+	var manager Manager
+	if err := db.WithContext(ctx).Model(&Manager{}).Where("token = ?", token).First(&manager).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrManagerNotExists
+		}
+		return nil, err
+	}
+
+	return &manager, nil
+}
+
+func (db *managers) ResetToken(ctx context.Context, id uint) (string, error) {
+	token := randstr.Hex(16)
+	result := db.WithContext(ctx).Model(&Manager{}).Where("id = ?", id).Update("token", token)
+	if result.Error != nil {
+		return "", errors.Wrap(result.Error, "update token")
+	}
+	if result.RowsAffected == 0 {
+		return "", ErrManagerNotExists
+	}
+	return token, nil
+}
+
+// backfillManagerTokens generates the tokens for the managers created before the token was introduced.
+func backfillManagerTokens(ctx context.Context, db *gorm.DB) error {
+	var managers []*Manager
+	if err := db.WithContext(ctx).Model(&Manager{}).Where("token = ? OR token IS NULL", "").Find(&managers).Error; err != nil {
+		return errors.Wrap(err, "get managers without token")
+	}
+
+	managersStore := NewManagersStore(db)
+	for _, manager := range managers {
+		if _, err := managersStore.ResetToken(ctx, manager.ID); err != nil {
+			return errors.Wrapf(err, "reset token of manager %d", manager.ID)
+		}
+	}
+	return nil
+}
+
 func (db *managers) ChangePassword(ctx context.Context, id uint, newPassword string) error {
 	var newManager Manager
 	newManager.Password = newPassword
@@ -31,6 +31,9 @@ func TestManagers(t *testing.T) {
 		{"Create", testManagersCreate},
 		{"Get", testManagersGet},
 		{"GetByID", testManagersGetByID},
+		{"GetByToken", testManagersGetByToken},
+		{"ResetToken", testManagersResetToken},
+		{"BackfillTokens", testManagersBackfillTokens},
 		{"ChangePassword", testManagersChangePassword},
 		{"Update", testManagersUpdate},
 		{"DeleteByID", testManagersDeleteByID},
@@ -101,6 +104,7 @@ func testManagersCreate(t *testing.T, ctx context.Context, db *managers) {
 		Name:           "Vidar",
 		Password:       "123456",
 		Salt:           got.Salt,
+		Token:          got.Token,
 		IsCheckAccount: false,
 	}
 	want.EncodePassword()
@@ -154,6 +158,7 @@ func testManagersGet(t *testing.T, ctx context.Context, db *managers) {
 			Name:           "Vidar",
 			Password:       "123456",
 			Salt:           manager1.Salt,
+			Token:          manager1.Token,
 			IsCheckAccount: false,
 		},
 		{
@@ -163,6 +168,7 @@ func testManagersGet(t *testing.T, ctx context.Context, db *managers) {
 			Name:           "Checker",
 			Password:       "abcdef",
 			Salt:           manager2.Salt,
+			Token:          manager2.Token,
 			IsCheckAccount: true,
 		},
 		{
@@ -172,6 +178,7 @@ func testManagersGet(t *testing.T, ctx context.Context, db *managers) {
 			Name:           "Cosmos",
 			Password:       "zxcvbn",
 			Salt:           manager3.Salt,
+			Token:          manager3.Token,
 			IsCheckAccount: false,
 		},
 	}
@@ -202,6 +209,7 @@ func testManagersGetByID(t *testing.T, ctx context.Context, db *managers) {
 		Name:           "Vidar",
 		Password:       "123456",
 		Salt:           got.Salt,
+		Token:          got.Token,
 		IsCheckAccount: false,
 	}
 	want.EncodePassword()
@@ -214,6 +222,71 @@ func testManagersGetByID(t *testing.T, ctx context.Context, db *managers) {
 	assert.Equal(t, want, got)
 }
 
+func testManagersGetByToken(t *testing.T, ctx context.Context, db *managers) {
+	want, err := db.Create(ctx, CreateManagerOptions{
+		Name:           "Checker",
+		Password:       "123456",
+		IsCheckAccount: true,
+	})
+	assert.Nil(t, err)
+	assert.NotZero(t, want.Token)
+
+	got, err := db.GetByToken(ctx, want.Token)
+	assert.Nil(t, err)
+	assert.Equal(t, want.ID, got.ID)
+	assert.True(t, got.IsCheckAccount)
+
+	// Get not exist manager.
+	_, err = db.GetByToken(ctx, "not_exist")
+	assert.Equal(t, ErrManagerNotExists, err)
+	_, err = db.GetByToken(ctx, "")
+	assert.Equal(t, ErrManagerNotExists, err)
+}
+
+func testManagersResetToken(t *testing.T, ctx context.Context, db *managers) {
+	manager, err := db.Create(ctx, CreateManagerOptions{
+		Name:           "Checker",
+		Password:       "123456",
+		IsCheckAccount: true,
+	})
+	assert.Nil(t, err)
+
+	token, err := db.ResetToken(ctx, manager.ID)
+	assert.Nil(t, err)
+	assert.NotZero(t, token)
+	assert.NotEqual(t, manager.Token, token)
+
+	// The old token can't be used.
+	_, err = db.GetByToken(ctx, manager.Token)
+	assert.Equal(t, ErrManagerNotExists, err)
+	got, err := db.GetByToken(ctx, token)
+	assert.Nil(t, err)
+	assert.Equal(t, manager.ID, got.ID)
+
+	_, err = db.ResetToken(ctx, 2)
+	assert.Equal(t, ErrManagerNotExists, err)
+}
+
+func testManagersBackfillTokens(t *testing.T, ctx context.Context, db *managers) {
+	manager, err := db.Create(ctx, CreateManagerOptions{
+		Name:           "Checker",
+		Password:       "123456",
+		IsCheckAccount: true,
+	})
+	assert.Nil(t, err)
+
+	// The manager created before the token was introduced.
+	err = db.Model(&Manager{}).Where("id = ?", manager.ID).Update("token", "").Error
+	assert.Nil(t, err)
+
+	err = backfillManagerTokens(ctx, db.DB)
+	assert.Nil(t, err)
+
+	got, err := db.GetByID(ctx, manager.ID)
+	assert.Nil(t, err)
+	assert.NotZero(t, got.Token)
+}
+
 func testManagersChangePassword(t *testing.T, ctx context.Context, db *managers) {
 	manager, err := db.Create(ctx, CreateManagerOptions{
 		Name:           "Vidar",
@@ -262,6 +335,7 @@ func testManagersUpdate(t *testing.T, ctx context.Context, db *managers) {
 		Name:           "Checker",
 		Password:       "abcdef",
 		Salt:           manager.Salt,
+		Token:          manager.Token,
 		IsCheckAccount: false,
 	}
 	want.EncodePassword()
@@ -51,7 +51,9 @@ type GameBoxInfo struct {
 	ChallengeID uint
 	IsCaptured  bool
 	IsDown      bool
-	Score       float64 `json:",omitempty"` // Manager only
+	// FirstBlood is whether the team got the first blood of the challenge.
+	FirstBlood bool
+	Score      float64 `json:",omitempty"` // Manager only
 }
 
 func (g GameBoxInfoList) Len() int           { return len(g) }
@@ -72,6 +74,16 @@ func (db *ranks) List(ctx context.Context) ([]*RankItem, error) {
 		return nil, errors.Wrap(err, "get teams")
 	}
 
+	var firstBloodGameBoxIDs []uint
+	if err := db.WithContext(ctx).Model(&Action{}).Where("type = ?", ActionTypeFirstBlood).
+		Pluck("game_box_id", &firstBloodGameBoxIDs).Error; err != nil {
+		return nil, errors.Wrap(err, "get first blood game boxes")
+	}
+	firstBloods := make(map[uint]struct{}, len(firstBloodGameBoxIDs))
+	for _, gameBoxID := range firstBloodGameBoxIDs {
+		firstBloods[gameBoxID] = struct{}{}
+	}
+
 	rankItems := make([]*RankItem, 0, len(teams))
 
 	gameBoxesStore := NewGameBoxesStore(db.DB)
@@ -86,10 +98,12 @@ func (db *ranks) List(ctx context.Context) ([]*RankItem, error) {
 
 		gameBoxInfo := make(GameBoxInfoList, 0, len(gameBoxes))
 		for _, gameBox := range gameBoxes {
+			_, firstBlood := firstBloods[gameBox.ID]
 			gameBoxInfo = append(gameBoxInfo, &GameBoxInfo{
 				ChallengeID: gameBox.ChallengeID,
 				IsCaptured:  gameBox.IsCaptured,
 				IsDown:      gameBox.IsDown,
+				FirstBlood:  firstBlood,
 				Score:       gameBox.Score,
 			})
 		}
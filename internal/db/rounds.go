@@ -0,0 +1,132 @@
+// Copyright 2021 E99p1ant. All rights reserved.
+// Use of this source code is governed by an AGPL-style
+// license that can be found in the LICENSE file.
+
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+var _ RoundsStore = (*rounds)(nil)
+
+// Rounds is the default instance of the RoundsStore.
+var Rounds RoundsStore
+
+// RoundsStore is the persistent interface for rounds.
+type RoundsStore interface {
+	// Create creates a new started round and persists to database.
+	// It returns ErrRoundExists when the round has been created.
+	Create(ctx context.Context, opts CreateRoundOptions) error
+	// Get returns all the rounds order by the round number.
+	Get(ctx context.Context) ([]*Round, error)
+	// GetByNumber returns the round with the given round number.
+	// It returns ErrRoundNotExists when not found.
+	GetByNumber(ctx context.Context, number uint) (*Round, error)
+	// GetScoreUncalculated returns the finished rounds whose score has not been calculated,
+	// order by the round number.
+	GetScoreUncalculated(ctx context.Context) ([]*Round, error)
+	// Finish marks the round with the given round number as finished at the given time.
+	Finish(ctx context.Context, number uint, endedAt time.Time) error
+	// SetScoreCalculated marks the score of the round with the given round number as calculated.
+	SetScoreCalculated(ctx context.Context, number uint) error
+	// DeleteAll deletes all the rounds.
+	DeleteAll(ctx context.Context) error
+}
+
+// NewRoundsStore returns a RoundsStore instance with the given database connection.
+func NewRoundsStore(db *gorm.DB) RoundsStore {
+	return &rounds{DB: db}
+}
+
+type RoundStatus string
+
+const (
+	RoundStatusRunning  RoundStatus = "running"
+	RoundStatusFinished RoundStatus = "finished"
+)
+
+// Round represents a round of the game which has been started.
+type Round struct {
+	gorm.Model
+
+	Number          uint `gorm:"uniqueIndex"`
+	StartedAt       time.Time
+	EndedAt         *time.Time
+	Status          RoundStatus
+	ScoreCalculated bool
+}
+
+type rounds struct {
+	*gorm.DB
+}
+
+type CreateRoundOptions struct {
+	Number    uint
+	StartedAt time.Time
+}
+
+var ErrRoundExists = errors.New("round already exists")
+
+func (db *rounds) Create(ctx context.Context, opts CreateRoundOptions) error {
+	_, err := db.GetByNumber(ctx, opts.Number)
+	if err == nil {
+		return ErrRoundExists
+	} else if err != ErrRoundNotExists {
+		return errors.Wrap(err, "get round")
+	}
+
+	return db.WithContext(ctx).Create(&Round{
+		Number:    opts.Number,
+		StartedAt: opts.StartedAt,
+		Status:    RoundStatusRunning,
+	}).Error
+}
+
+func (db *rounds) Get(ctx context.Context) ([]*Round, error) {
+	var rounds []*Round
+	return rounds, db.WithContext(ctx).Model(&Round{}).Order("number ASC").Find(&rounds).Error
+}
+
+var ErrRoundNotExists = errors.New("round does not exist")
+
+func (db *rounds) GetByNumber(ctx context.Context, number uint) (*Round, error) {
+	var round Round
+	if err := db.WithContext(ctx).Model(&Round{}).Where("number = ?", number).First(&round).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrRoundNotExists
+		}
+		return nil, errors.Wrap(err, "get")
+	}
+	return &round, nil
+}
+
+func (db *rounds) GetScoreUncalculated(ctx context.Context) ([]*Round, error) {
+	var rounds []*Round
+	return rounds, db.WithContext(ctx).Model(&Round{}).
+		Where("status = ? AND score_calculated = ?", RoundStatusFinished, false).
+		Order("number ASC").Find(&rounds).Error
+}
+
+func (db *rounds) Finish(ctx context.Context, number uint, endedAt time.Time) error {
+	return db.WithContext(ctx).Model(&Round{}).Where("number = ?", number).
+		Select("EndedAt", "Status").
+		Updates(&Round{
+			EndedAt: &endedAt,
+			Status:  RoundStatusFinished,
+		}).Error
+}
+
+func (db *rounds) SetScoreCalculated(ctx context.Context, number uint) error {
+	return db.WithContext(ctx).Model(&Round{}).Where("number = ?", number).
+		Update("score_calculated", true).Error
+}
+
+func (db *rounds) DeleteAll(ctx context.Context) error {
+	// The rounds are hard deleted, so the round number can be used again without conflict.
+	return db.WithContext(ctx).Session(&gorm.Session{AllowGlobalUpdate: true}).Unscoped().Delete(&Round{}).Error
+}
@@ -0,0 +1,167 @@
+// Copyright 2021 E99p1ant. All rights reserved.
+// Use of this source code is governed by an AGPL-style
+// license that can be found in the LICENSE file.
+
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRounds(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+
+	t.Parallel()
+
+	db, cleanup := newTestDB(t)
+	store := NewRoundsStore(db)
+
+	for _, tc := range []struct {
+		name string
+		test func(t *testing.T, ctx context.Context, db *rounds)
+	}{
+		{"Create", testRoundsCreate},
+		{"Get", testRoundsGet},
+		{"GetByNumber", testRoundsGetByNumber},
+		{"GetScoreUncalculated", testRoundsGetScoreUncalculated},
+		{"Finish", testRoundsFinish},
+		{"SetScoreCalculated", testRoundsSetScoreCalculated},
+		{"DeleteAll", testRoundsDeleteAll},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Cleanup(func() {
+				err := cleanup("rounds")
+				if err != nil {
+					t.Fatal(err)
+				}
+			})
+			tc.test(t, context.Background(), store.(*rounds))
+		})
+	}
+}
+
+var roundStartAt = time.Date(2021, 10, 3, 12, 0, 0, 0, time.Local)
+
+func testRoundsCreate(t *testing.T, ctx context.Context, db *rounds) {
+	err := db.Create(ctx, CreateRoundOptions{
+		Number:    1,
+		StartedAt: roundStartAt,
+	})
+	assert.Nil(t, err)
+
+	// Create a repeated round.
+	err = db.Create(ctx, CreateRoundOptions{
+		Number:    1,
+		StartedAt: roundStartAt,
+	})
+	assert.Equal(t, ErrRoundExists, err)
+}
+
+func testRoundsGet(t *testing.T, ctx context.Context, db *rounds) {
+	// Get empty rounds list.
+	got, err := db.Get(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, []*Round{}, got)
+
+	err = db.Create(ctx, CreateRoundOptions{Number: 2, StartedAt: roundStartAt.Add(time.Hour)})
+	assert.Nil(t, err)
+	err = db.Create(ctx, CreateRoundOptions{Number: 1, StartedAt: roundStartAt})
+	assert.Nil(t, err)
+
+	got, err = db.Get(ctx)
+	assert.Nil(t, err)
+	assert.Len(t, got, 2)
+	assert.Equal(t, uint(1), got[0].Number)
+	assert.True(t, roundStartAt.Equal(got[0].StartedAt))
+	assert.Equal(t, RoundStatusRunning, got[0].Status)
+	assert.Nil(t, got[0].EndedAt)
+	assert.Equal(t, uint(2), got[1].Number)
+}
+
+func testRoundsGetByNumber(t *testing.T, ctx context.Context, db *rounds) {
+	err := db.Create(ctx, CreateRoundOptions{Number: 1, StartedAt: roundStartAt})
+	assert.Nil(t, err)
+
+	got, err := db.GetByNumber(ctx, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, uint(1), got.Number)
+	assert.Equal(t, RoundStatusRunning, got.Status)
+	assert.False(t, got.ScoreCalculated)
+
+	// Get not exist round.
+	got, err = db.GetByNumber(ctx, 2)
+	assert.Equal(t, ErrRoundNotExists, err)
+	assert.Equal(t, (*Round)(nil), got)
+}
+
+func testRoundsGetScoreUncalculated(t *testing.T, ctx context.Context, db *rounds) {
+	for number := uint(1); number <= 3; number++ {
+		err := db.Create(ctx, CreateRoundOptions{Number: number, StartedAt: roundStartAt})
+		assert.Nil(t, err)
+	}
+
+	// The running rounds are not included.
+	got, err := db.GetScoreUncalculated(ctx)
+	assert.Nil(t, err)
+	assert.Len(t, got, 0)
+
+	err = db.Finish(ctx, 1, roundStartAt.Add(time.Hour))
+	assert.Nil(t, err)
+	err = db.Finish(ctx, 2, roundStartAt.Add(2*time.Hour))
+	assert.Nil(t, err)
+	err = db.SetScoreCalculated(ctx, 1)
+	assert.Nil(t, err)
+
+	got, err = db.GetScoreUncalculated(ctx)
+	assert.Nil(t, err)
+	assert.Len(t, got, 1)
+	assert.Equal(t, uint(2), got[0].Number)
+}
+
+func testRoundsFinish(t *testing.T, ctx context.Context, db *rounds) {
+	err := db.Create(ctx, CreateRoundOptions{Number: 1, StartedAt: roundStartAt})
+	assert.Nil(t, err)
+
+	err = db.Finish(ctx, 1, roundStartAt.Add(time.Hour))
+	assert.Nil(t, err)
+
+	got, err := db.GetByNumber(ctx, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, RoundStatusFinished, got.Status)
+	assert.NotNil(t, got.EndedAt)
+	assert.True(t, roundStartAt.Add(time.Hour).Equal(*got.EndedAt))
+}
+
+func testRoundsSetScoreCalculated(t *testing.T, ctx context.Context, db *rounds) {
+	err := db.Create(ctx, CreateRoundOptions{Number: 1, StartedAt: roundStartAt})
+	assert.Nil(t, err)
+
+	err = db.SetScoreCalculated(ctx, 1)
+	assert.Nil(t, err)
+
+	got, err := db.GetByNumber(ctx, 1)
+	assert.Nil(t, err)
+	assert.True(t, got.ScoreCalculated)
+}
+
+func testRoundsDeleteAll(t *testing.T, ctx context.Context, db *rounds) {
+	err := db.Create(ctx, CreateRoundOptions{Number: 1, StartedAt: roundStartAt})
+	assert.Nil(t, err)
+
+	err = db.DeleteAll(ctx)
+	assert.Nil(t, err)
+
+	got, err := db.Get(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, []*Round{}, got)
+
+	// The round can be created again after being deleted.
+	err = db.Create(ctx, CreateRoundOptions{Number: 1, StartedAt: roundStartAt})
+	assert.Nil(t, err)
+}
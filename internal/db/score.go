@@ -25,6 +25,10 @@ type ScoresStore interface {
 	RefreshCheckScore(ctx context.Context, round uint, replaces ...bool) error
 	RefreshGameBoxScore(ctx context.Context) error
 	RefreshTeamScore(ctx context.Context) error
+	// Recalculate recalculates the scores of all the rounds until the given round in a transaction,
+	// and rebuilds the team score snapshots of the rounds.
+	// It returns the team scores before and after the recalculation.
+	Recalculate(ctx context.Context, opts RecalculateScoreOptions) ([]*TeamScoreDiff, error)
 }
 
 // NewScoresStore returns a ScoresStore instance with the given database connection.
@@ -36,8 +40,22 @@ type scores struct {
 	*gorm.DB
 }
 
-// Calculate calculates the score until the given round.
+// Calculate calculates the score until the given round, and marks the score of the round as calculated.
 func (db *scores) Calculate(ctx context.Context, round uint) error {
+	// The flags of the previous rounds may be submitted in this round,
+	// so the attack scores of these rounds are calculated again.
+	if validRounds := conf.Game.FlagValidRounds; validRounds > 1 {
+		startRound := uint(1)
+		if round > validRounds-1 {
+			startRound = round - (validRounds - 1)
+		}
+		for previousRound := startRound; previousRound < round; previousRound++ {
+			if err := db.RefreshAttackScore(ctx, previousRound, true); err != nil {
+				return errors.Wrapf(err, "refresh attack score of round %d", previousRound)
+			}
+		}
+	}
+
 	if err := db.RefreshAttackScore(ctx, round); err != nil {
 		return errors.Wrap(err, "refresh attack score")
 	}
@@ -54,164 +72,93 @@ func (db *scores) Calculate(ctx context.Context, round uint) error {
 		return errors.Wrap(err, "refresh team score")
 	}
 
+	teamRoundScoresStore := NewTeamRoundScoresStore(db.DB)
+	if err := teamRoundScoresStore.Snapshot(ctx, round); err != nil {
+		return errors.Wrap(err, "snapshot team score")
+	}
+
 	// TODO: Logger
 
 	// TODO: health check
 
+	roundsStore := NewRoundsStore(db.DB)
+	if err := roundsStore.SetScoreCalculated(ctx, round); err != nil {
+		return errors.Wrap(err, "set round score calculated")
+	}
+
 	return nil
 }
 
+// scoringStrategy returns the scoring strategy selected in the configuration.
+func (db *scores) scoringStrategy() (ScoringStrategy, error) {
+	strategy, err := GetScoringStrategy(conf.Game.ScoringStrategy)
+	if err != nil {
+		return nil, errors.Wrapf(err, "get scoring strategy %q", conf.Game.ScoringStrategy)
+	}
+	return strategy, nil
+}
+
 func (db *scores) RefreshAttackScore(ctx context.Context, round uint, replaces ...bool) error {
 	replace := len(replaces) != 0 && replaces[0]
 
-	gameBoxesStore := NewGameBoxesStore(db.DB)
-	gameBoxes, err := gameBoxesStore.Get(ctx, GetGameBoxesOption{})
+	strategy, err := db.scoringStrategy()
 	if err != nil {
-		return errors.Wrap(err, "get game boxes")
+		return err
 	}
-
-	actionsStore := NewActionsStore(db.DB)
-
-	for _, gameBox := range gameBoxes {
-		// [-] Been attacked score
-		beenAttackedActions, err := actionsStore.Get(ctx, GetActionOptions{
-			Type:      ActionTypeBeenAttack,
-			GameBoxID: gameBox.ID,
-			Round:     round,
-		})
-		if err != nil {
-			return errors.Wrap(err, "get been attacked actions")
-		}
-
-		if len(beenAttackedActions) == 0 {
-			continue
-		}
-		beenAttackAction := beenAttackedActions[0]
-		if err := actionsStore.SetScore(ctx, SetActionScoreOptions{
-			ActionID: beenAttackAction.ID,
-			Score:    -float64(conf.Game.AttackScore),
-			Replace:  replace,
-		}); err != nil {
-			return errors.Wrap(err, "set been attacked score")
-		}
-
-		// [+] Attacked score
-		attackActions, err := actionsStore.Get(ctx, GetActionOptions{
-			Type:      ActionTypeAttack,
-			GameBoxID: gameBox.ID,
-			Round:     round,
-		})
-		if err != nil {
-			return errors.Wrap(err, "get attack actions")
-		}
-		if len(attackActions) == 0 {
-			return errors.Errorf("unexpected count of attack actions: %d", len(attackActions))
-		}
-
-		score := float64(conf.Game.AttackScore) / float64(len(attackActions))
-		for _, action := range attackActions {
-			if err := actionsStore.SetScore(ctx, SetActionScoreOptions{
-				ActionID: action.ID,
-				Score:    score,
-				Replace:  replace,
-			}); err != nil {
-				return errors.Wrap(err, "set attack score")
-			}
-		}
+	if err := strategy.RefreshAttackScore(ctx, db.DB, round, replace); err != nil {
+		return err
 	}
 
+	// The first blood bonus is awarded in all the scoring strategies.
+	if err := db.refreshFirstBloodScore(ctx, round, replace); err != nil {
+		return errors.Wrap(err, "refresh first blood score")
+	}
 	return nil
 }
 
-func (db *scores) RefreshCheckScore(ctx context.Context, round uint, replaces ...bool) error {
-	replace := len(replaces) != 0 && replaces[0]
-
-	challengesStore := NewChallengesStore(db.DB)
+// refreshFirstBloodScore awards the FirstBloodBonus to the attacker's game box of the challenge
+// whose first been attacked action is in the given round.
+func (db *scores) refreshFirstBloodScore(ctx context.Context, round uint, replace bool) error {
+	attacksStore := NewAttacksStore(db.DB)
 	gameBoxesStore := NewGameBoxesStore(db.DB)
-	actionsStore := NewActionsStore(db.DB)
 
-	challenges, err := challengesStore.Get(ctx)
+	firstBloods, err := attacksStore.FirstBloods(ctx, GetAttackStatsOptions{})
 	if err != nil {
-		return errors.Wrap(err, "get challenges")
+		return errors.Wrap(err, "get first bloods")
 	}
 
-	for _, challenge := range challenges {
-		// Get the game boxes of the challenge.
-		gameBoxes, err := gameBoxesStore.Get(ctx, GetGameBoxesOption{
-			ChallengeID: challenge.ID,
-		})
-		if err != nil {
-			return errors.Wrap(err, "get game boxes")
-		}
-
-		// Skip the invisible challenge.
-		if len(gameBoxes) == 0 || !gameBoxes[0].Visible {
+	for _, firstBlood := range firstBloods {
+		if firstBlood.Round != round {
 			continue
 		}
 
-		// Get the game box check down actions of the challenge.
-		checkDownActions, err := actionsStore.Get(ctx, GetActionOptions{
-			Type:        ActionTypeCheckDown,
-			ChallengeID: challenge.ID,
-			Round:       round,
+		// The attacker team may not have the game box of the challenge.
+		gameBoxes, err := gameBoxesStore.Get(ctx, GetGameBoxesOption{
+			TeamID:      firstBlood.AttackerTeamID,
+			ChallengeID: firstBlood.ChallengeID,
 		})
 		if err != nil {
-			return errors.Wrap(err, "get challenge check down boxes")
+			return errors.Wrap(err, "get attacker game box")
 		}
-
-		// We need save the check down game box IDs of this challenge into a map,
-		// for we can get the service online game boxes when traversal all the game boxes.
-		checkDownGameBoxIDs := make(map[uint]struct{}, len(checkDownActions))
-		// [-] Been checked down
-		for _, action := range checkDownActions {
-			checkDownGameBoxIDs[action.GameBoxID] = struct{}{}
-
-			if err := actionsStore.SetScore(ctx, SetActionScoreOptions{
-				ActionID: action.ID,
-				Score:    -float64(conf.Game.CheckDownScore),
-				Replace:  replace,
-			}); err != nil {
-				return errors.Wrap(err, "set check down score")
-			}
+		if len(gameBoxes) == 0 {
+			continue
 		}
 
-		// [+] Service online
-		// Remove service online actions in given round first.
-		if err := actionsStore.Delete(ctx, DeleteActionOptions{
-			Type:  ActionTypeServiceOnline,
-			Round: round,
-		}); err != nil {
-			return errors.Wrap(err, "delete previous service online actions")
+		if err := setActionScore(ctx, db.DB, ActionTypeFirstBlood, gameBoxes[0].ID, round, float64(conf.Game.FirstBloodBonus), replace); err != nil {
+			return errors.Wrap(err, "set first blood score")
 		}
+	}
+	return nil
+}
 
-		serviceOnlineGameBoxCount := len(gameBoxes) - len(checkDownActions)
-		score := float64(conf.Game.CheckDownScore*len(checkDownActions)) / float64(serviceOnlineGameBoxCount)
-
-		for _, gameBox := range gameBoxes {
-			if _, ok := checkDownGameBoxIDs[gameBox.ID]; ok {
-				continue
-			}
-
-			action, err := actionsStore.Create(ctx, CreateActionOptions{
-				Type:      ActionTypeServiceOnline,
-				GameBoxID: gameBox.ID,
-				Round:     round,
-			})
-			if err != nil {
-				return errors.Wrap(err, "create service online action")
-			}
+func (db *scores) RefreshCheckScore(ctx context.Context, round uint, replaces ...bool) error {
+	replace := len(replaces) != 0 && replaces[0]
 
-			if err := actionsStore.SetScore(ctx, SetActionScoreOptions{
-				ActionID: action.ID,
-				Score:    score,
-				Replace:  true,
-			}); err != nil {
-				return errors.Wrap(err, "set service online action score")
-			}
-		}
+	strategy, err := db.scoringStrategy()
+	if err != nil {
+		return err
 	}
-
-	return nil
+	return strategy.RefreshCheckScore(ctx, db.DB, round, replace)
 }
 
 func (db *scores) RefreshGameBoxScore(ctx context.Context) error {
@@ -265,3 +212,98 @@ func (db *scores) RefreshTeamScore(ctx context.Context) error {
 
 	return nil
 }
+
+type RecalculateScoreOptions struct {
+	// Round is the last round to be recalculated.
+	Round uint
+	// DryRun rollbacks the transaction after the recalculation, the database will not be changed.
+	DryRun bool
+}
+
+// TeamScoreDiff represents the team score before and after the recalculation.
+type TeamScoreDiff struct {
+	TeamID uint
+	Name   string
+	Before float64
+	After  float64
+	Diff   float64
+}
+
+func (db *scores) Recalculate(ctx context.Context, opts RecalculateScoreOptions) ([]*TeamScoreDiff, error) {
+	tx := db.WithContext(ctx).Begin()
+	if tx.Error != nil {
+		return nil, errors.Wrap(tx.Error, "begin transaction")
+	}
+
+	diffs, err := (&scores{DB: tx}).recalculate(ctx, opts.Round)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if opts.DryRun {
+		return diffs, tx.Rollback().Error
+	}
+	return diffs, tx.Commit().Error
+}
+
+func (db *scores) recalculate(ctx context.Context, round uint) ([]*TeamScoreDiff, error) {
+	teamsStore := NewTeamsStore(db.DB)
+	roundsStore := NewRoundsStore(db.DB)
+	teamRoundScoresStore := NewTeamRoundScoresStore(db.DB)
+
+	beforeTeams, err := teamsStore.Get(ctx, GetTeamsOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "get teams before recalculation")
+	}
+
+	// Reset the scores of the actions, the attack actions which are not
+	// generated by the scoring strategy anymore will be left with zero score.
+	if err := db.WithContext(ctx).Model(&Action{}).Where("round <= ?", round).Update("score", 0).Error; err != nil {
+		return nil, errors.Wrap(err, "reset action scores")
+	}
+
+	for r := uint(1); r <= round; r++ {
+		if err := db.RefreshAttackScore(ctx, r, true); err != nil {
+			return nil, errors.Wrapf(err, "refresh attack score of round %d", r)
+		}
+		if err := db.RefreshCheckScore(ctx, r, true); err != nil {
+			return nil, errors.Wrapf(err, "refresh check score of round %d", r)
+		}
+		if err := teamRoundScoresStore.Snapshot(ctx, r); err != nil {
+			return nil, errors.Wrapf(err, "snapshot team score of round %d", r)
+		}
+		if err := roundsStore.SetScoreCalculated(ctx, r); err != nil {
+			return nil, errors.Wrapf(err, "set round %d score calculated", r)
+		}
+	}
+
+	if err := db.RefreshGameBoxScore(ctx); err != nil {
+		return nil, errors.Wrap(err, "refresh game box score")
+	}
+	if err := db.RefreshTeamScore(ctx); err != nil {
+		return nil, errors.Wrap(err, "refresh team score")
+	}
+
+	afterTeams, err := teamsStore.Get(ctx, GetTeamsOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "get teams after recalculation")
+	}
+	afterScores := make(map[uint]float64, len(afterTeams))
+	for _, team := range afterTeams {
+		afterScores[team.ID] = team.Score
+	}
+
+	diffs := make([]*TeamScoreDiff, 0, len(beforeTeams))
+	for _, team := range beforeTeams {
+		after := afterScores[team.ID]
+		diffs = append(diffs, &TeamScoreDiff{
+			TeamID: team.ID,
+			Name:   team.Name,
+			Before: team.Score,
+			After:  after,
+			Diff:   after - team.Score,
+		})
+	}
+	return diffs, nil
+}
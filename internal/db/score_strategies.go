@@ -0,0 +1,89 @@
+// Copyright 2021 E99p1ant. All rights reserved.
+// Use of this source code is governed by an AGPL-style
+// license that can be found in the LICENSE file.
+
+package db
+
+import (
+	"context"
+	"math"
+
+	"gorm.io/gorm"
+)
+
+var _ ScoringStrategy = (*zeroSumStrategy)(nil)
+
+// zeroSumStrategy is the classic AWD scoring strategy, the total score of all the teams never changes.
+//
+// The attacked game box loses AttackScore in each round, and the score is shared by its attackers.
+// The checked down game box loses CheckDownScore, and the score is shared by the service online
+// game boxes of the same challenge.
+type zeroSumStrategy struct{}
+
+func (zeroSumStrategy) RefreshAttackScore(ctx context.Context, db *gorm.DB, round uint, replace bool) error {
+	return refreshAttackScore(ctx, db, round, replace, func(challenge *Challenge, _ *Action, captures int) (float64, float64) {
+		score := challenge.GetAttackScore() / float64(captures)
+		return -score, score
+	})
+}
+
+func (zeroSumStrategy) RefreshCheckScore(ctx context.Context, db *gorm.DB, round uint, replace bool) error {
+	return refreshCheckScore(ctx, db, round, replace, func(challenge *Challenge, total, down int) (float64, float64) {
+		checkDownScore := challenge.GetCheckDownScore()
+		online := total - down
+		if online == 0 {
+			return -checkDownScore, 0
+		}
+		return -checkDownScore, checkDownScore * float64(down) / float64(online)
+	})
+}
+
+var _ ScoringStrategy = (*faustStrategy)(nil)
+
+// faustStrategy is the FAUST CTF style scoring strategy, which consists of offense, defense and SLA.
+//
+// n is the count of the teams which captured the flag of the attacked game box in the round.
+// Offense: each of the attackers gains AttackScore * (1 + 1/n) for the captured flag.
+// Defense: the attacked game box loses AttackScore * n^0.75 in total, each of its n been attacked actions
+// holds AttackScore * n^0.75 / n of the loss.
+// SLA: the service online game box gains CheckDownScore * sqrt(the count of the game boxes of the challenge),
+// and the checked down game box gains nothing.
+type faustStrategy struct{}
+
+func (faustStrategy) RefreshAttackScore(ctx context.Context, db *gorm.DB, round uint, replace bool) error {
+	return refreshAttackScore(ctx, db, round, replace, func(challenge *Challenge, _ *Action, captures int) (float64, float64) {
+		attackScore := challenge.GetAttackScore()
+		n := float64(captures)
+		return -attackScore * math.Pow(n, 0.75) / n, attackScore * (1 + 1/n)
+	})
+}
+
+func (faustStrategy) RefreshCheckScore(ctx context.Context, db *gorm.DB, round uint, replace bool) error {
+	return refreshCheckScore(ctx, db, round, replace, func(challenge *Challenge, total, _ int) (float64, float64) {
+		return 0, challenge.GetCheckDownScore() * math.Sqrt(float64(total))
+	})
+}
+
+var _ ScoringStrategy = (*ecscStrategy)(nil)
+
+// ecscStrategy is the ECSC style scoring strategy.
+//
+// Offense: the attacker gains AttackScore for each captured flag.
+// Defense: the attacked game box loses AttackScore * sqrt(n), n is the count of its captures.
+// SLA: the service online game box gains CheckDownScore, and the checked down game box loses CheckDownScore.
+type ecscStrategy struct{}
+
+func (ecscStrategy) RefreshAttackScore(ctx context.Context, db *gorm.DB, round uint, replace bool) error {
+	return refreshAttackScore(ctx, db, round, replace, func(challenge *Challenge, _ *Action, captures int) (float64, float64) {
+		attackScore := challenge.GetAttackScore()
+		n := float64(captures)
+		return -attackScore * math.Sqrt(n) / n, attackScore
+	})
+}
+
+func (ecscStrategy) RefreshCheckScore(ctx context.Context, db *gorm.DB, round uint, replace bool) error {
+	return refreshCheckScore(ctx, db, round, replace, func(challenge *Challenge, _, _ int) (float64, float64) {
+		checkDownScore := challenge.GetCheckDownScore()
+		return -checkDownScore, checkDownScore
+	})
+}
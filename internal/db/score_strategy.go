@@ -0,0 +1,276 @@
+// Copyright 2021 E99p1ant. All rights reserved.
+// Use of this source code is governed by an AGPL-style
+// license that can be found in the LICENSE file.
+
+package db
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// ScoringStrategy sets the scores of the actions in a round, the scores of the actions
+// are aggregated into the game box and team scores by the ScoresStore.
+type ScoringStrategy interface {
+	// RefreshAttackScore sets the scores of the been attacked actions in the given round,
+	// and the scores of the attack actions on the attackers' game boxes.
+	RefreshAttackScore(ctx context.Context, db *gorm.DB, round uint, replace bool) error
+	// RefreshCheckScore sets the scores of the check down actions in the given round,
+	// and the scores of the service online actions on the other game boxes.
+	RefreshCheckScore(ctx context.Context, db *gorm.DB, round uint, replace bool) error
+}
+
+const (
+	ScoringStrategyZeroSum = "zero-sum"
+	ScoringStrategyFAUST   = "faust"
+	ScoringStrategyECSC    = "ecsc"
+)
+
+var (
+	scoringStrategiesMu sync.RWMutex
+	scoringStrategies   = map[string]ScoringStrategy{
+		ScoringStrategyZeroSum: zeroSumStrategy{},
+		ScoringStrategyFAUST:   faustStrategy{},
+		ScoringStrategyECSC:    ecscStrategy{},
+	}
+)
+
+// RegisterScoringStrategy registers the scoring strategy with the given name,
+// the existing strategy with the same name will be replaced.
+func RegisterScoringStrategy(name string, strategy ScoringStrategy) {
+	scoringStrategiesMu.Lock()
+	defer scoringStrategiesMu.Unlock()
+	scoringStrategies[name] = strategy
+}
+
+var ErrScoringStrategyNotExists = errors.New("scoring strategy does not exist")
+
+// GetScoringStrategy returns the scoring strategy with the given name.
+// It returns the zero-sum strategy if the name is empty.
+func GetScoringStrategy(name string) (ScoringStrategy, error) {
+	if name == "" {
+		name = ScoringStrategyZeroSum
+	}
+
+	scoringStrategiesMu.RLock()
+	defer scoringStrategiesMu.RUnlock()
+
+	strategy, ok := scoringStrategies[name]
+	if !ok {
+		return nil, ErrScoringStrategyNotExists
+	}
+	return strategy, nil
+}
+
+// attackScorer returns the score of the been attacked action, and the score gained by its attacker.
+// The challenge is the challenge of the attacked game box, and the captures is the count of the teams
+// which attacked the same game box in the round.
+type attackScorer func(challenge *Challenge, action *Action, captures int) (beenAttackScore, attackScore float64)
+
+// refreshAttackScore sets the scores of the been attacked actions in the given round with the scorer.
+// The scores gained by the attacker team are added to its game box of the same challenge as an attack action.
+func refreshAttackScore(ctx context.Context, db *gorm.DB, round uint, replace bool, scorer attackScorer) error {
+	actionsStore := NewActionsStore(db)
+	gameBoxesStore := NewGameBoxesStore(db)
+	challengesStore := NewChallengesStore(db)
+
+	beenAttackActions, err := actionsStore.GetByType(ctx, ActionTypeBeenAttack, round)
+	if err != nil {
+		return errors.Wrap(err, "get been attacked actions")
+	}
+	if len(beenAttackActions) == 0 {
+		return nil
+	}
+
+	gameBoxes, err := gameBoxesStore.Get(ctx, GetGameBoxesOption{})
+	if err != nil {
+		return errors.Wrap(err, "get game boxes")
+	}
+	type teamChallenge struct {
+		teamID      uint
+		challengeID uint
+	}
+	teamGameBoxIDs := make(map[teamChallenge]uint, len(gameBoxes))
+	for _, gameBox := range gameBoxes {
+		teamGameBoxIDs[teamChallenge{gameBox.TeamID, gameBox.ChallengeID}] = gameBox.ID
+	}
+
+	challenges, err := challengesStore.Get(ctx)
+	if err != nil {
+		return errors.Wrap(err, "get challenges")
+	}
+	challengeSets := make(map[uint]*Challenge, len(challenges))
+	for _, challenge := range challenges {
+		challengeSets[challenge.ID] = challenge
+	}
+
+	captures := make(map[uint]int)
+	for _, action := range beenAttackActions {
+		captures[action.GameBoxID]++
+	}
+
+	// [-] Been attacked score
+	attackScores := make(map[uint]float64)
+	for _, action := range beenAttackActions {
+		challenge, ok := challengeSets[action.ChallengeID]
+		if !ok {
+			// Use the global scores for the deleted challenge.
+			challenge = &Challenge{}
+		}
+
+		beenAttackScore, attackScore := scorer(challenge, action, captures[action.GameBoxID])
+		if err := actionsStore.SetScore(ctx, SetActionScoreOptions{
+			ActionID: action.ID,
+			Score:    beenAttackScore,
+			Replace:  replace,
+		}); err != nil {
+			return errors.Wrap(err, "set been attacked score")
+		}
+
+		// The attacker team may not have the game box of the challenge.
+		gameBoxID, ok := teamGameBoxIDs[teamChallenge{action.AttackerTeamID, action.ChallengeID}]
+		if !ok {
+			continue
+		}
+		attackScores[gameBoxID] += attackScore
+	}
+
+	// [+] Attack score
+	gameBoxIDs := make([]uint, 0, len(attackScores))
+	for gameBoxID := range attackScores {
+		gameBoxIDs = append(gameBoxIDs, gameBoxID)
+	}
+	sort.Slice(gameBoxIDs, func(i, j int) bool { return gameBoxIDs[i] < gameBoxIDs[j] })
+
+	for _, gameBoxID := range gameBoxIDs {
+		if err := setActionScore(ctx, db, ActionTypeAttack, gameBoxID, round, attackScores[gameBoxID], replace); err != nil {
+			return errors.Wrap(err, "set attack score")
+		}
+	}
+
+	return nil
+}
+
+// checkScorer returns the score of the check down game box and the score of the service online game box.
+// The total is the count of the game boxes of the challenge, and the down is the count of the check down ones.
+type checkScorer func(challenge *Challenge, total, down int) (checkDownScore, serviceOnlineScore float64)
+
+// refreshCheckScore sets the scores of the check down actions in the given round with the scorer,
+// and creates the service online actions for the other game boxes.
+func refreshCheckScore(ctx context.Context, db *gorm.DB, round uint, replace bool, scorer checkScorer) error {
+	challengesStore := NewChallengesStore(db)
+	gameBoxesStore := NewGameBoxesStore(db)
+	actionsStore := NewActionsStore(db)
+
+	challenges, err := challengesStore.Get(ctx)
+	if err != nil {
+		return errors.Wrap(err, "get challenges")
+	}
+
+	allCheckDownActions, err := actionsStore.GetByType(ctx, ActionTypeCheckDown, round)
+	if err != nil {
+		return errors.Wrap(err, "get check down actions")
+	}
+
+	for _, challenge := range challenges {
+		// Get the game boxes of the challenge.
+		gameBoxes, err := gameBoxesStore.Get(ctx, GetGameBoxesOption{
+			ChallengeID: challenge.ID,
+		})
+		if err != nil {
+			return errors.Wrap(err, "get game boxes")
+		}
+
+		// Skip the invisible challenge.
+		if len(gameBoxes) == 0 || !gameBoxes[0].Visible {
+			continue
+		}
+
+		// Get the game box check down actions of the challenge.
+		checkDownActions := make([]*Action, 0)
+		for _, action := range allCheckDownActions {
+			if action.ChallengeID == challenge.ID {
+				checkDownActions = append(checkDownActions, action)
+			}
+		}
+
+		checkDownScore, serviceOnlineScore := scorer(challenge, len(gameBoxes), len(checkDownActions))
+
+		// We need save the check down game box IDs of this challenge into a map,
+		// for we can get the service online game boxes when traversal all the game boxes.
+		checkDownGameBoxIDs := make(map[uint]struct{}, len(checkDownActions))
+		// [-] Been checked down
+		for _, action := range checkDownActions {
+			checkDownGameBoxIDs[action.GameBoxID] = struct{}{}
+
+			if err := actionsStore.SetScore(ctx, SetActionScoreOptions{
+				ActionID: action.ID,
+				Score:    checkDownScore,
+				Replace:  replace,
+			}); err != nil {
+				return errors.Wrap(err, "set check down score")
+			}
+		}
+
+		// [+] Service online
+		// Remove service online actions of the challenge in given round first.
+		if err := actionsStore.Delete(ctx, DeleteActionOptions{
+			Type:        ActionTypeServiceOnline,
+			ChallengeID: challenge.ID,
+			Round:       round,
+		}); err != nil {
+			return errors.Wrap(err, "delete previous service online actions")
+		}
+
+		for _, gameBox := range gameBoxes {
+			if _, ok := checkDownGameBoxIDs[gameBox.ID]; ok {
+				continue
+			}
+
+			if err := setActionScore(ctx, db, ActionTypeServiceOnline, gameBox.ID, round, serviceOnlineScore, true); err != nil {
+				return errors.Wrap(err, "set service online action score")
+			}
+		}
+	}
+
+	return nil
+}
+
+// setActionScore sets the score of the action with the given type on the game box in the given round,
+// the action will be created if it does not exist.
+// It can not be used for the ActionTypeBeenAttack, which is created with the attacker team.
+func setActionScore(ctx context.Context, db *gorm.DB, actionType ActionType, gameBoxID, round uint, score float64, replace bool) error {
+	actionsStore := NewActionsStore(db)
+
+	action, err := actionsStore.Create(ctx, CreateActionOptions{
+		Type:      actionType,
+		GameBoxID: gameBoxID,
+		Round:     round,
+	})
+	if err == ErrDuplicateAction {
+		actions, err := actionsStore.Get(ctx, GetActionOptions{
+			Type:      actionType,
+			GameBoxID: gameBoxID,
+			Round:     round,
+		})
+		if err != nil {
+			return errors.Wrap(err, "get action")
+		}
+		if len(actions) == 0 {
+			return errors.Errorf("action not found after duplicated: type %d, game box %d, round %d", actionType, gameBoxID, round)
+		}
+		action = actions[0]
+	} else if err != nil {
+		return errors.Wrap(err, "create action")
+	}
+
+	return actionsStore.SetScore(ctx, SetActionScoreOptions{
+		ActionID: action.ID,
+		Score:    score,
+		Replace:  replace,
+	})
+}
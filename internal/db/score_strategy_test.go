@@ -0,0 +1,136 @@
+// Copyright 2021 E99p1ant. All rights reserved.
+// Use of this source code is governed by an AGPL-style
+// license that can be found in the LICENSE file.
+
+package db
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+type testScoringStrategy struct{}
+
+func (testScoringStrategy) RefreshAttackScore(context.Context, *gorm.DB, uint, bool) error {
+	return nil
+}
+
+func (testScoringStrategy) RefreshCheckScore(context.Context, *gorm.DB, uint, bool) error {
+	return nil
+}
+
+func TestGetScoringStrategy(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		want    ScoringStrategy
+		wantErr error
+	}{
+		{name: "", want: zeroSumStrategy{}},
+		{name: ScoringStrategyZeroSum, want: zeroSumStrategy{}},
+		{name: ScoringStrategyFAUST, want: faustStrategy{}},
+		{name: ScoringStrategyECSC, want: ecscStrategy{}},
+		{name: "not-exist", wantErr: ErrScoringStrategyNotExists},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := GetScoringStrategy(tc.name)
+			assert.Equal(t, tc.wantErr, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+
+	RegisterScoringStrategy("test", testScoringStrategy{})
+	got, err := GetScoringStrategy("test")
+	assert.Nil(t, err)
+	assert.Equal(t, testScoringStrategy{}, got)
+}
+
+func TestScoringStrategies(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+
+	t.Parallel()
+
+	db, cleanup := newTestDB(t)
+
+	ctx := context.Background()
+	// Three teams with the game boxes of a challenge, the game box ID is the same as the team ID.
+	teamsStore := NewTeamsStore(db)
+	for _, name := range []string{"Vidar", "E99p1ant", "Cosmos"} {
+		_, err := teamsStore.Create(ctx, CreateTeamOptions{Name: name})
+		assert.Nil(t, err)
+	}
+	challengesStore := NewChallengesStore(db)
+	_, err := challengesStore.Create(ctx, CreateChallengeOptions{Title: "Web1", BaseScore: 1000, AttackScore: 60, CheckDownScore: 30})
+	assert.Nil(t, err)
+	gameBoxesStore := NewGameBoxesStore(db)
+	for teamID := uint(1); teamID <= 3; teamID++ {
+		_, err := gameBoxesStore.Create(ctx, CreateGameBoxOptions{TeamID: teamID, ChallengeID: 1, IPAddress: "192.168.1.1", Port: 80})
+		assert.Nil(t, err)
+		err = gameBoxesStore.SetVisible(ctx, teamID, true)
+		assert.Nil(t, err)
+	}
+
+	actionsStore := NewActionsStore(db)
+
+	for _, tc := range []struct {
+		name     string
+		strategy ScoringStrategy
+		// The scores of the game boxes 1, 2 and 3.
+		want [3]float64
+	}{
+		{
+			// The been attacked score 60 is shared by two attackers,
+			// the check down score 30 is shared by two service online game boxes.
+			name:     ScoringStrategyZeroSum,
+			strategy: zeroSumStrategy{},
+			want:     [3]float64{-60 + 15, 30 + 15, 30 - 30},
+		},
+		{
+			// The attacker gains 60 * (1 + 1/2), the attacked game box loses 60 * 2^0.75,
+			// the service online game box gains 30 * sqrt(3).
+			name:     ScoringStrategyFAUST,
+			strategy: faustStrategy{},
+			want:     [3]float64{-60*math.Pow(2, 0.75) + 30*math.Sqrt(3), 90 + 30*math.Sqrt(3), 90},
+		},
+		{
+			// The attacker gains 60, the attacked game box loses 60 * sqrt(2),
+			// the service online game box gains 30 and the checked down one loses 30.
+			name:     ScoringStrategyECSC,
+			strategy: ecscStrategy{},
+			want:     [3]float64{-60*math.Sqrt(2) + 30, 60 + 30, 60 - 30},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Cleanup(func() {
+				err := cleanup("actions", "first_bloods")
+				if err != nil {
+					t.Fatal(err)
+				}
+			})
+
+			// Round 1: E99p1ant and Cosmos attacked Vidar, and Cosmos was checked down.
+			_, err := actionsStore.Create(ctx, CreateActionOptions{Type: ActionTypeBeenAttack, GameBoxID: 1, AttackerTeamID: 2, Round: 1})
+			assert.Nil(t, err)
+			_, err = actionsStore.Create(ctx, CreateActionOptions{Type: ActionTypeBeenAttack, GameBoxID: 1, AttackerTeamID: 3, Round: 1})
+			assert.Nil(t, err)
+			_, err = actionsStore.Create(ctx, CreateActionOptions{Type: ActionTypeCheckDown, GameBoxID: 3, Round: 1})
+			assert.Nil(t, err)
+
+			err = tc.strategy.RefreshAttackScore(ctx, db, 1, false)
+			assert.Nil(t, err)
+			err = tc.strategy.RefreshCheckScore(ctx, db, 1, false)
+			assert.Nil(t, err)
+
+			for i, want := range tc.want {
+				got, err := actionsStore.CountScore(ctx, CountActionScoreOptions{GameBoxID: uint(i + 1)})
+				assert.Nil(t, err)
+				assert.InDelta(t, want, got, 1e-6, "game box %d", i+1)
+			}
+		})
+	}
+}
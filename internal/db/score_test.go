@@ -3,3 +3,205 @@
 // license that can be found in the LICENSE file.
 
 package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/vidar-team/Cardinal/internal/conf"
+)
+
+func TestScores(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+
+	t.Parallel()
+
+	db, cleanup := newTestDB(t)
+	scoresStore := NewScoresStore(db)
+
+	for _, tc := range []struct {
+		name string
+		test func(t *testing.T, ctx context.Context, db *scores)
+	}{
+		{"Recalculate", testScoresRecalculate},
+		{"CalculateLateFlag", testScoresCalculateLateFlag},
+		{"FirstBlood", testScoresFirstBlood},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Cleanup(func() {
+				err := cleanup("teams", "challenges", "game_boxes", "actions", "first_bloods", "rounds", "team_round_scores")
+				if err != nil {
+					t.Fatal(err)
+				}
+			})
+
+			ctx := context.Background()
+			// Create three teams.
+			teamsStore := NewTeamsStore(db)
+			_, err := teamsStore.Create(ctx, CreateTeamOptions{Name: "Vidar"})
+			assert.Nil(t, err)
+			_, err = teamsStore.Create(ctx, CreateTeamOptions{Name: "E99p1ant"})
+			assert.Nil(t, err)
+			_, err = teamsStore.Create(ctx, CreateTeamOptions{Name: "Cosmos"})
+			assert.Nil(t, err)
+
+			// Create a challenge with its own attack and check down score.
+			challengesStore := NewChallengesStore(db)
+			_, err = challengesStore.Create(ctx, CreateChallengeOptions{Title: "Web1", BaseScore: 1000, AttackScore: 60, CheckDownScore: 30})
+			assert.Nil(t, err)
+
+			// Create visible game boxes for each team.
+			gameBoxesStore := NewGameBoxesStore(db)
+			_, err = gameBoxesStore.Create(ctx, CreateGameBoxOptions{TeamID: 1, ChallengeID: 1, IPAddress: "192.168.1.1", Port: 80, Description: "Web1 For Vidar"})
+			assert.Nil(t, err)
+			_, err = gameBoxesStore.Create(ctx, CreateGameBoxOptions{TeamID: 2, ChallengeID: 1, IPAddress: "192.168.1.2", Port: 80, Description: "Web1 For E99p1ant"})
+			assert.Nil(t, err)
+			_, err = gameBoxesStore.Create(ctx, CreateGameBoxOptions{TeamID: 3, ChallengeID: 1, IPAddress: "192.168.1.3", Port: 80, Description: "Web1 For Cosmos"})
+			assert.Nil(t, err)
+			for i := uint(1); i <= 3; i++ {
+				err := gameBoxesStore.SetVisible(ctx, i, true)
+				assert.Nil(t, err)
+			}
+
+			tc.test(t, ctx, scoresStore.(*scores))
+		})
+	}
+}
+
+func testScoresRecalculate(t *testing.T, ctx context.Context, db *scores) {
+	actionsStore := NewActionsStore(db.DB)
+	teamsStore := NewTeamsStore(db.DB)
+
+	// Round 1: E99p1ant and Cosmos attacked Vidar, and Cosmos was checked down.
+	_, err := actionsStore.Create(ctx, CreateActionOptions{Type: ActionTypeBeenAttack, GameBoxID: 1, AttackerTeamID: 2, Round: 1})
+	assert.Nil(t, err)
+	_, err = actionsStore.Create(ctx, CreateActionOptions{Type: ActionTypeBeenAttack, GameBoxID: 1, AttackerTeamID: 3, Round: 1})
+	assert.Nil(t, err)
+	_, err = actionsStore.Create(ctx, CreateActionOptions{Type: ActionTypeCheckDown, GameBoxID: 3, Round: 1})
+	assert.Nil(t, err)
+
+	// Vidar	-60 + 15 = -45
+	// E99p1ant	30 + 15 = 45
+	// Cosmos	30 - 30 = 0
+	want := []*TeamScoreDiff{
+		{TeamID: 1, Name: "Vidar", Before: 0, After: -45, Diff: -45},
+		{TeamID: 2, Name: "E99p1ant", Before: 0, After: 45, Diff: 45},
+		{TeamID: 3, Name: "Cosmos", Before: 0, After: 0, Diff: 0},
+	}
+
+	// Dry run does not change the database.
+	got, err := db.Recalculate(ctx, RecalculateScoreOptions{Round: 1, DryRun: true})
+	assert.Nil(t, err)
+	assert.Equal(t, want, got)
+
+	team, err := teamsStore.GetByID(ctx, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, float64(0), team.Score)
+
+	got, err = db.Recalculate(ctx, RecalculateScoreOptions{Round: 1})
+	assert.Nil(t, err)
+	assert.Equal(t, want, got)
+
+	team, err = teamsStore.GetByID(ctx, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, float64(-45), team.Score)
+
+	// Recalculate again, the scores should not be changed.
+	got, err = db.Recalculate(ctx, RecalculateScoreOptions{Round: 1})
+	assert.Nil(t, err)
+	want = []*TeamScoreDiff{
+		{TeamID: 1, Name: "Vidar", Before: -45, After: -45, Diff: 0},
+		{TeamID: 2, Name: "E99p1ant", Before: 45, After: 45, Diff: 0},
+		{TeamID: 3, Name: "Cosmos", Before: 0, After: 0, Diff: 0},
+	}
+	assert.Equal(t, want, got)
+}
+
+func testScoresCalculateLateFlag(t *testing.T, ctx context.Context, db *scores) {
+	conf.Game.FlagValidRounds = 2
+	t.Cleanup(func() {
+		conf.Game.FlagValidRounds = 0
+	})
+
+	actionsStore := NewActionsStore(db.DB)
+	teamsStore := NewTeamsStore(db.DB)
+
+	// Round 1: E99p1ant attacked Vidar.
+	_, err := actionsStore.Create(ctx, CreateActionOptions{Type: ActionTypeBeenAttack, GameBoxID: 1, AttackerTeamID: 2, Round: 1})
+	assert.Nil(t, err)
+	err = db.Calculate(ctx, 1)
+	assert.Nil(t, err)
+
+	team, err := teamsStore.GetByID(ctx, 2)
+	assert.Nil(t, err)
+	assert.Equal(t, float64(60), team.Score)
+
+	// Round 2: Cosmos submitted the flag of Vidar in round 1,
+	// the attack score of round 1 is shared by E99p1ant and Cosmos.
+	_, err = actionsStore.Create(ctx, CreateActionOptions{Type: ActionTypeBeenAttack, GameBoxID: 1, AttackerTeamID: 3, Round: 1})
+	assert.Nil(t, err)
+	err = db.Calculate(ctx, 2)
+	assert.Nil(t, err)
+
+	for teamID, want := range map[uint]float64{
+		1: -60,
+		2: 30,
+		3: 30,
+	} {
+		team, err := teamsStore.GetByID(ctx, teamID)
+		assert.Nil(t, err)
+		assert.Equal(t, want, team.Score)
+	}
+}
+
+func testScoresFirstBlood(t *testing.T, ctx context.Context, db *scores) {
+	conf.Game.FirstBloodBonus = 100
+	t.Cleanup(func() {
+		conf.Game.FirstBloodBonus = 0
+	})
+
+	actionsStore := NewActionsStore(db.DB)
+	teamsStore := NewTeamsStore(db.DB)
+
+	// Round 1: E99p1ant got the first blood of Web1, and then Cosmos attacked Vidar.
+	_, err := actionsStore.Create(ctx, CreateActionOptions{Type: ActionTypeBeenAttack, GameBoxID: 1, AttackerTeamID: 2, Round: 1})
+	assert.Nil(t, err)
+	_, err = actionsStore.Create(ctx, CreateActionOptions{Type: ActionTypeBeenAttack, GameBoxID: 1, AttackerTeamID: 3, Round: 1})
+	assert.Nil(t, err)
+	err = db.Calculate(ctx, 1)
+	assert.Nil(t, err)
+
+	// Round 2: Cosmos attacked E99p1ant, it is not the first blood.
+	_, err = actionsStore.Create(ctx, CreateActionOptions{Type: ActionTypeBeenAttack, GameBoxID: 2, AttackerTeamID: 3, Round: 2})
+	assert.Nil(t, err)
+	err = db.Calculate(ctx, 2)
+	assert.Nil(t, err)
+
+	for teamID, want := range map[uint]float64{
+		1: -60,
+		2: 70,
+		3: 90,
+	} {
+		team, err := teamsStore.GetByID(ctx, teamID)
+		assert.Nil(t, err)
+		assert.Equal(t, want, team.Score)
+	}
+
+	firstBloods, err := actionsStore.Get(ctx, GetActionOptions{Type: ActionTypeFirstBlood})
+	assert.Nil(t, err)
+	assert.Len(t, firstBloods, 1)
+	assert.Equal(t, uint(2), firstBloods[0].GameBoxID)
+	assert.Equal(t, uint(1), firstBloods[0].Round)
+	assert.Equal(t, float64(100), firstBloods[0].Score)
+
+	// The first blood is shown in the ranking list.
+	rankList, err := NewRanksStore(db.DB).List(ctx)
+	assert.Nil(t, err)
+	for _, rankItem := range rankList {
+		assert.Equal(t, rankItem.TeamID == 2, rankItem.GameBoxes[0].FirstBlood)
+	}
+}
@@ -0,0 +1,75 @@
+// Copyright 2021 E99p1ant. All rights reserved.
+// Use of this source code is governed by an AGPL-style
+// license that can be found in the LICENSE file.
+
+package db
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var _ SettingsStore = (*settings)(nil)
+
+// Settings is the default instance of the SettingsStore.
+var Settings SettingsStore
+
+// SettingsStore is the persistent interface for settings.
+type SettingsStore interface {
+	// Get returns the value of the setting with the given key.
+	// It returns ErrSettingNotExists when not found.
+	Get(ctx context.Context, key string) (string, error)
+	// Set sets the value of the setting with the given key,
+	// the setting will be created if it does not exist.
+	Set(ctx context.Context, key, value string) error
+	// DeleteAll deletes all the settings.
+	DeleteAll(ctx context.Context) error
+}
+
+// NewSettingsStore returns a SettingsStore instance with the given database connection.
+func NewSettingsStore(db *gorm.DB) SettingsStore {
+	return &settings{DB: db}
+}
+
+// Setting represents a setting which is changed at runtime.
+type Setting struct {
+	gorm.Model
+
+	Key   string `gorm:"uniqueIndex"`
+	Value string
+}
+
+type settings struct {
+	*gorm.DB
+}
+
+var ErrSettingNotExists = errors.New("setting does not exist")
+
+func (db *settings) Get(ctx context.Context, key string) (string, error) {
+	var setting Setting
+	if err := db.WithContext(ctx).Model(&Setting{}).Where(&Setting{Key: key}).First(&setting).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return "", ErrSettingNotExists
+		}
+		return "", errors.Wrap(err, "get")
+	}
+	return setting.Value, nil
+}
+
+func (db *settings) Set(ctx context.Context, key, value string) error {
+	return db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "key"}},
+		DoUpdates: clause.AssignmentColumns([]string{"value", "updated_at"}),
+	}).Create(&Setting{
+		Key:   key,
+		Value: value,
+	}).Error
+}
+
+func (db *settings) DeleteAll(ctx context.Context) error {
+	// The settings are hard deleted, so the key can be set again without conflict.
+	return db.WithContext(ctx).Session(&gorm.Session{AllowGlobalUpdate: true}).Unscoped().Delete(&Setting{}).Error
+}
@@ -0,0 +1,82 @@
+// Copyright 2021 E99p1ant. All rights reserved.
+// Use of this source code is governed by an AGPL-style
+// license that can be found in the LICENSE file.
+
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSettings(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+
+	t.Parallel()
+
+	db, cleanup := newTestDB(t)
+	store := NewSettingsStore(db)
+
+	for _, tc := range []struct {
+		name string
+		test func(t *testing.T, ctx context.Context, db *settings)
+	}{
+		{"Get", testSettingsGet},
+		{"Set", testSettingsSet},
+		{"DeleteAll", testSettingsDeleteAll},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Cleanup(func() {
+				err := cleanup("settings")
+				if err != nil {
+					t.Fatal(err)
+				}
+			})
+			tc.test(t, context.Background(), store.(*settings))
+		})
+	}
+}
+
+func testSettingsGet(t *testing.T, ctx context.Context, db *settings) {
+	_, err := db.Get(ctx, "clock.end_at")
+	assert.Equal(t, ErrSettingNotExists, err)
+
+	err = db.Set(ctx, "clock.end_at", "2021-10-05T12:00:00+08:00")
+	assert.Nil(t, err)
+
+	got, err := db.Get(ctx, "clock.end_at")
+	assert.Nil(t, err)
+	assert.Equal(t, "2021-10-05T12:00:00+08:00", got)
+}
+
+func testSettingsSet(t *testing.T, ctx context.Context, db *settings) {
+	err := db.Set(ctx, "clock.end_at", "2021-10-05T12:00:00+08:00")
+	assert.Nil(t, err)
+
+	// Override the existing setting.
+	err = db.Set(ctx, "clock.end_at", "2021-10-05T14:00:00+08:00")
+	assert.Nil(t, err)
+
+	got, err := db.Get(ctx, "clock.end_at")
+	assert.Nil(t, err)
+	assert.Equal(t, "2021-10-05T14:00:00+08:00", got)
+}
+
+func testSettingsDeleteAll(t *testing.T, ctx context.Context, db *settings) {
+	err := db.Set(ctx, "clock.end_at", "2021-10-05T12:00:00+08:00")
+	assert.Nil(t, err)
+
+	err = db.DeleteAll(ctx)
+	assert.Nil(t, err)
+
+	_, err = db.Get(ctx, "clock.end_at")
+	assert.Equal(t, ErrSettingNotExists, err)
+
+	// The setting can be set again after being deleted.
+	err = db.Set(ctx, "clock.end_at", "2021-10-05T12:00:00+08:00")
+	assert.Nil(t, err)
+}
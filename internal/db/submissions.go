@@ -0,0 +1,120 @@
+// Copyright 2021 E99p1ant. All rights reserved.
+// Use of this source code is governed by an AGPL-style
+// license that can be found in the LICENSE file.
+
+package db
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+var _ SubmissionsStore = (*submissions)(nil)
+
+// Submissions is the default instance of the SubmissionsStore.
+var Submissions SubmissionsStore
+
+// SubmissionsStore is the persistent interface for flag submissions.
+type SubmissionsStore interface {
+	// BatchCreate creates the submissions and persists to database.
+	BatchCreate(ctx context.Context, opts []CreateSubmissionOptions) error
+	// Get returns the submissions with the given options, order by the time submitted descending.
+	Get(ctx context.Context, opts GetSubmissionsOptions) ([]*Submission, int64, error)
+	// DeleteAll deletes all the submissions.
+	DeleteAll(ctx context.Context) error
+}
+
+// NewSubmissionsStore returns a SubmissionsStore instance with the given database connection.
+func NewSubmissionsStore(db *gorm.DB) SubmissionsStore {
+	return &submissions{DB: db}
+}
+
+// Submission represents a flag submission attempt of a team.
+type Submission struct {
+	gorm.Model
+
+	TeamID uint `gorm:"index"`
+	Flag   string
+	// Status is the result of the submission, see flagutil.SubmitStatus.
+	Status string `gorm:"index"`
+	IP     string
+	// Round is the round when the flag was submitted.
+	Round uint `gorm:"index"`
+	// GameBoxID is the game box which the flag belongs to, it is zero if the flag does not exist.
+	GameBoxID uint `gorm:"index"`
+}
+
+type submissions struct {
+	*gorm.DB
+}
+
+type CreateSubmissionOptions struct {
+	TeamID    uint
+	Flag      string
+	Status    string
+	IP        string
+	Round     uint
+	GameBoxID uint
+}
+
+func (db *submissions) BatchCreate(ctx context.Context, opts []CreateSubmissionOptions) error {
+	if len(opts) == 0 {
+		return nil
+	}
+
+	submissions := make([]*Submission, 0, len(opts))
+	for _, opt := range opts {
+		submissions = append(submissions, &Submission{
+			TeamID:    opt.TeamID,
+			Flag:      opt.Flag,
+			Status:    opt.Status,
+			IP:        opt.IP,
+			Round:     opt.Round,
+			GameBoxID: opt.GameBoxID,
+		})
+	}
+
+	if err := db.WithContext(ctx).CreateInBatches(submissions, len(submissions)).Error; err != nil {
+		return errors.Wrap(err, "batch create submissions")
+	}
+	return nil
+}
+
+type GetSubmissionsOptions struct {
+	Page      int
+	PageSize  int
+	TeamID    uint
+	Round     uint
+	GameBoxID uint
+	Status    string
+}
+
+func (db *submissions) Get(ctx context.Context, opts GetSubmissionsOptions) ([]*Submission, int64, error) {
+	var submissions []*Submission
+	var count int64
+
+	q := db.WithContext(ctx).Model(&Submission{}).Where(&Submission{
+		TeamID:    opts.TeamID,
+		Round:     opts.Round,
+		GameBoxID: opts.GameBoxID,
+		Status:    opts.Status,
+	})
+	if err := q.Count(&count).Error; err != nil {
+		return nil, 0, errors.Wrap(err, "count")
+	}
+
+	if opts.Page <= 0 {
+		opts.Page = 1
+	}
+	if opts.PageSize != 0 {
+		q = q.Offset((opts.Page - 1) * opts.PageSize).Limit(opts.PageSize)
+	}
+
+	return submissions, count, q.Order("id DESC").Find(&submissions).Error
+}
+
+func (db *submissions) DeleteAll(ctx context.Context) error {
+	return db.WithContext(ctx).Session(&gorm.Session{AllowGlobalUpdate: true}).Unscoped().Delete(&Submission{}).Error
+}
@@ -0,0 +1,114 @@
+// Copyright 2021 E99p1ant. All rights reserved.
+// Use of this source code is governed by an AGPL-style
+// license that can be found in the LICENSE file.
+
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSubmissions(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+
+	t.Parallel()
+
+	db, cleanup := newTestDB(t)
+	store := NewSubmissionsStore(db)
+
+	for _, tc := range []struct {
+		name string
+		test func(t *testing.T, ctx context.Context, db *submissions)
+	}{
+		{"BatchCreate", testSubmissionsBatchCreate},
+		{"Get", testSubmissionsGet},
+		{"DeleteAll", testSubmissionsDeleteAll},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Cleanup(func() {
+				err := cleanup("submissions")
+				if err != nil {
+					t.Fatal(err)
+				}
+			})
+			tc.test(t, context.Background(), store.(*submissions))
+		})
+	}
+}
+
+func createTestSubmissions(t *testing.T, ctx context.Context, db *submissions) {
+	err := db.BatchCreate(ctx, []CreateSubmissionOptions{
+		{TeamID: 1, Flag: "d3ctf{2-1-1}", Status: "accepted", IP: "10.0.1.1", Round: 1, GameBoxID: 2},
+		{TeamID: 1, Flag: "d3ctf{wrong}", Status: "invalid", IP: "10.0.1.1", Round: 1},
+		{TeamID: 2, Flag: "d3ctf{2-1-1}", Status: "own_flag", IP: "10.0.2.1", Round: 1, GameBoxID: 2},
+		{TeamID: 2, Flag: "d3ctf{1-1-2}", Status: "accepted", IP: "10.0.2.1", Round: 2, GameBoxID: 1},
+	})
+	assert.Nil(t, err)
+}
+
+func testSubmissionsBatchCreate(t *testing.T, ctx context.Context, db *submissions) {
+	createTestSubmissions(t, ctx, db)
+
+	// Nothing happens with no submission.
+	err := db.BatchCreate(ctx, nil)
+	assert.Nil(t, err)
+
+	got, count, err := db.Get(ctx, GetSubmissionsOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(4), count)
+	assert.Len(t, got, 4)
+}
+
+func testSubmissionsGet(t *testing.T, ctx context.Context, db *submissions) {
+	createTestSubmissions(t, ctx, db)
+
+	for _, tc := range []struct {
+		name      string
+		opts      GetSubmissionsOptions
+		wantIDs   []uint
+		wantCount int64
+	}{
+		{name: "all", opts: GetSubmissionsOptions{}, wantIDs: []uint{4, 3, 2, 1}, wantCount: 4},
+		{name: "team", opts: GetSubmissionsOptions{TeamID: 1}, wantIDs: []uint{2, 1}, wantCount: 2},
+		{name: "round", opts: GetSubmissionsOptions{Round: 2}, wantIDs: []uint{4}, wantCount: 1},
+		{name: "game box", opts: GetSubmissionsOptions{GameBoxID: 2}, wantIDs: []uint{3, 1}, wantCount: 2},
+		{name: "status", opts: GetSubmissionsOptions{Status: "accepted"}, wantIDs: []uint{4, 1}, wantCount: 2},
+		{name: "page", opts: GetSubmissionsOptions{Page: 2, PageSize: 3}, wantIDs: []uint{1}, wantCount: 4},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, count, err := db.Get(ctx, tc.opts)
+			assert.Nil(t, err)
+			assert.Equal(t, tc.wantCount, count)
+
+			ids := make([]uint, 0, len(got))
+			for _, submission := range got {
+				ids = append(ids, submission.ID)
+			}
+			assert.Equal(t, tc.wantIDs, ids)
+		})
+	}
+
+	got, _, err := db.Get(ctx, GetSubmissionsOptions{TeamID: 2, Round: 2})
+	assert.Nil(t, err)
+	assert.Len(t, got, 1)
+	assert.Equal(t, "d3ctf{1-1-2}", got[0].Flag)
+	assert.Equal(t, "10.0.2.1", got[0].IP)
+	assert.Equal(t, uint(1), got[0].GameBoxID)
+}
+
+func testSubmissionsDeleteAll(t *testing.T, ctx context.Context, db *submissions) {
+	createTestSubmissions(t, ctx, db)
+
+	err := db.DeleteAll(ctx)
+	assert.Nil(t, err)
+
+	got, count, err := db.Get(ctx, GetSubmissionsOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(0), count)
+	assert.Len(t, got, 0)
+}
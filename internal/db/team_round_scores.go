@@ -0,0 +1,137 @@
+// Copyright 2021 E99p1ant. All rights reserved.
+// Use of this source code is governed by an AGPL-style
+// license that can be found in the LICENSE file.
+
+package db
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var _ TeamRoundScoresStore = (*teamRoundScores)(nil)
+
+// TeamRoundScores is the default instance of the TeamRoundScoresStore.
+var TeamRoundScores TeamRoundScoresStore
+
+// TeamRoundScoresStore is the persistent interface for the team score snapshots of each round.
+type TeamRoundScoresStore interface {
+	// Snapshot calculates the scores of all the teams at the end of the given round,
+	// and persists them to database. The existing snapshot of the round will be replaced.
+	// The score is the sum of the action scores up to the round, the same as the team score.
+	Snapshot(ctx context.Context, round uint) error
+	// Get returns the team score snapshots with the given options, order by the round and the rank.
+	Get(ctx context.Context, opts GetTeamRoundScoresOptions) ([]*TeamRoundScore, error)
+	// GetByRound returns the ranking list at the end of the given round, order by the rank.
+	GetByRound(ctx context.Context, round uint) ([]*TeamRoundScore, error)
+	// DeleteAll deletes all the team score snapshots.
+	DeleteAll(ctx context.Context) error
+}
+
+// NewTeamRoundScoresStore returns a TeamRoundScoresStore instance with the given database connection.
+func NewTeamRoundScoresStore(db *gorm.DB) TeamRoundScoresStore {
+	return &teamRoundScores{DB: db}
+}
+
+// TeamRoundScore represents the score of a team at the end of a round.
+type TeamRoundScore struct {
+	gorm.Model
+
+	TeamID uint `gorm:"uniqueIndex:team_round_score_unique_idx"`
+	Round  uint `gorm:"uniqueIndex:team_round_score_unique_idx"`
+	Score  float64
+	Rank   uint `gorm:"-:migration"` // Ignore in migration.
+}
+
+type teamRoundScores struct {
+	*gorm.DB
+}
+
+func (db *teamRoundScores) Snapshot(ctx context.Context, round uint) error {
+	teamsStore := NewTeamsStore(db.DB)
+	gameBoxesStore := NewGameBoxesStore(db.DB)
+
+	teams, err := teamsStore.Get(ctx, GetTeamsOptions{})
+	if err != nil {
+		return errors.Wrap(err, "get teams")
+	}
+	if len(teams) == 0 {
+		return nil
+	}
+
+	gameBoxes, err := gameBoxesStore.Get(ctx, GetGameBoxesOption{
+		Visible: true,
+	})
+	if err != nil {
+		return errors.Wrap(err, "get game boxes")
+	}
+
+	// The scores of the actions after the given round are not counted,
+	// so the snapshot of a past round can be rebuilt.
+	var actionScores []struct {
+		GameBoxID uint
+		Score     float64
+	}
+	if err := db.WithContext(ctx).Model(&Action{}).Select("game_box_id, SUM(score) AS score").
+		Where("round <= ?", round).Group("game_box_id").Find(&actionScores).Error; err != nil {
+		return errors.Wrap(err, "count action scores")
+	}
+	gameBoxScores := make(map[uint]float64, len(actionScores))
+	for _, actionScore := range actionScores {
+		gameBoxScores[actionScore.GameBoxID] = actionScore.Score
+	}
+
+	teamScores := make(map[uint]float64, len(teams))
+	for _, gameBox := range gameBoxes {
+		teamScores[gameBox.TeamID] += gameBoxScores[gameBox.ID]
+	}
+
+	snapshots := make([]*TeamRoundScore, 0, len(teams))
+	for _, team := range teams {
+		snapshots = append(snapshots, &TeamRoundScore{
+			TeamID: team.ID,
+			Round:  round,
+			Score:  teamScores[team.ID],
+		})
+	}
+
+	return db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "team_id"}, {Name: "round"}},
+		DoUpdates: clause.AssignmentColumns([]string{"score", "updated_at"}),
+	}).Create(&snapshots).Error
+}
+
+type GetTeamRoundScoresOptions struct {
+	TeamID uint
+}
+
+func (db *teamRoundScores) Get(ctx context.Context, opts GetTeamRoundScoresOptions) ([]*TeamRoundScore, error) {
+	// The rank is calculated before filtering the team.
+	query := db.WithContext(ctx).Table("(?) AS team_round_score", db.ranked(ctx))
+	if opts.TeamID != 0 {
+		query = query.Where("team_id = ?", opts.TeamID)
+	}
+
+	var snapshots []*TeamRoundScore
+	return snapshots, query.Order(`round ASC, "rank" ASC, team_id ASC`).Find(&snapshots).Error
+}
+
+func (db *teamRoundScores) GetByRound(ctx context.Context, round uint) ([]*TeamRoundScore, error) {
+	var snapshots []*TeamRoundScore
+	return snapshots, db.WithContext(ctx).Table("(?) AS team_round_score", db.ranked(ctx)).
+		Where("round = ?", round).Order(`"rank" ASC, team_id ASC`).Find(&snapshots).Error
+}
+
+// ranked returns the query of the team score snapshots with the rank in each round.
+func (db *teamRoundScores) ranked(ctx context.Context) *gorm.DB {
+	return db.WithContext(ctx).Model(&TeamRoundScore{}).
+		Select([]string{"*", `RANK() OVER(PARTITION BY round ORDER BY score DESC) "rank"`})
+}
+
+func (db *teamRoundScores) DeleteAll(ctx context.Context) error {
+	// The snapshots are hard deleted, so the round can be snapshot again without conflict.
+	return db.WithContext(ctx).Session(&gorm.Session{AllowGlobalUpdate: true}).Unscoped().Delete(&TeamRoundScore{}).Error
+}
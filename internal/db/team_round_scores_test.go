@@ -0,0 +1,198 @@
+// Copyright 2021 E99p1ant. All rights reserved.
+// Use of this source code is governed by an AGPL-style
+// license that can be found in the LICENSE file.
+
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTeamRoundScores(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+
+	t.Parallel()
+
+	db, cleanup := newTestDB(t)
+	teamRoundScoresStore := NewTeamRoundScoresStore(db)
+
+	for _, tc := range []struct {
+		name string
+		test func(t *testing.T, ctx context.Context, db *teamRoundScores)
+	}{
+		{"Snapshot", testTeamRoundScoresSnapshot},
+		{"Get", testTeamRoundScoresGet},
+		{"GetByRound", testTeamRoundScoresGetByRound},
+		{"DeleteAll", testTeamRoundScoresDeleteAll},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Cleanup(func() {
+				err := cleanup("teams", "challenges", "game_boxes", "actions", "first_bloods", "team_round_scores")
+				if err != nil {
+					t.Fatal(err)
+				}
+			})
+
+			ctx := context.Background()
+			// Create two teams.
+			teamsStore := NewTeamsStore(db)
+			_, err := teamsStore.Create(ctx, CreateTeamOptions{Name: "Vidar"})
+			assert.Nil(t, err)
+			_, err = teamsStore.Create(ctx, CreateTeamOptions{Name: "E99p1ant"})
+			assert.Nil(t, err)
+
+			// Create a challenge.
+			challengesStore := NewChallengesStore(db)
+			_, err = challengesStore.Create(ctx, CreateChallengeOptions{Title: "Web1", BaseScore: 1000})
+			assert.Nil(t, err)
+
+			// Create visible game boxes for each team.
+			gameBoxesStore := NewGameBoxesStore(db)
+			_, err = gameBoxesStore.Create(ctx, CreateGameBoxOptions{TeamID: 1, ChallengeID: 1, IPAddress: "192.168.1.1", Port: 80, Description: "Web1 For Vidar"})
+			assert.Nil(t, err)
+			_, err = gameBoxesStore.Create(ctx, CreateGameBoxOptions{TeamID: 2, ChallengeID: 1, IPAddress: "192.168.1.2", Port: 80, Description: "Web1 For E99p1ant"})
+			assert.Nil(t, err)
+			for i := uint(1); i <= 2; i++ {
+				err := gameBoxesStore.SetVisible(ctx, i, true)
+				assert.Nil(t, err)
+			}
+
+			// Vidar was attacked by E99p1ant in round 1, and E99p1ant was attacked by Vidar twice in round 2.
+			actionsStore := NewActionsStore(db)
+			for _, opts := range []struct {
+				actionType ActionType
+				gameBoxID  uint
+				round      uint
+				score      float64
+			}{
+				{ActionTypeBeenAttack, 1, 1, -50},
+				{ActionTypeAttack, 2, 1, 50},
+				{ActionTypeBeenAttack, 2, 2, -50},
+				{ActionTypeAttack, 1, 2, 50},
+				{ActionTypeBeenAttack, 2, 3, -50},
+				{ActionTypeAttack, 1, 3, 50},
+			} {
+				action, err := actionsStore.Create(ctx, CreateActionOptions{
+					Type:           opts.actionType,
+					GameBoxID:      opts.gameBoxID,
+					AttackerTeamID: 3 - opts.gameBoxID,
+					Round:          opts.round,
+				})
+				assert.Nil(t, err)
+				err = actionsStore.SetScore(ctx, SetActionScoreOptions{ActionID: action.ID, Score: opts.score})
+				assert.Nil(t, err)
+			}
+
+			tc.test(t, ctx, teamRoundScoresStore.(*teamRoundScores))
+		})
+	}
+}
+
+func testTeamRoundScoresSnapshot(t *testing.T, ctx context.Context, db *teamRoundScores) {
+	err := db.Snapshot(ctx, 1)
+	assert.Nil(t, err)
+
+	got, err := db.GetByRound(ctx, 1)
+	assert.Nil(t, err)
+	assert.Len(t, got, 2)
+	assert.Equal(t, uint(2), got[0].TeamID)
+	assert.Equal(t, float64(50), got[0].Score)
+	assert.Equal(t, uint(1), got[0].Rank)
+	assert.Equal(t, uint(1), got[1].TeamID)
+	assert.Equal(t, float64(-50), got[1].Score)
+	assert.Equal(t, uint(2), got[1].Rank)
+
+	// Snapshot the round again, the existing snapshot will be replaced.
+	err = db.Snapshot(ctx, 1)
+	assert.Nil(t, err)
+	got, err = db.GetByRound(ctx, 1)
+	assert.Nil(t, err)
+	assert.Len(t, got, 2)
+
+	// The actions after the round are not counted.
+	err = db.Snapshot(ctx, 2)
+	assert.Nil(t, err)
+	got, err = db.GetByRound(ctx, 2)
+	assert.Nil(t, err)
+	assert.Len(t, got, 2)
+	assert.Equal(t, float64(0), got[0].Score)
+	assert.Equal(t, uint(1), got[0].Rank)
+	assert.Equal(t, float64(0), got[1].Score)
+	assert.Equal(t, uint(1), got[1].Rank)
+}
+
+func testTeamRoundScoresGet(t *testing.T, ctx context.Context, db *teamRoundScores) {
+	got, err := db.Get(ctx, GetTeamRoundScoresOptions{})
+	assert.Nil(t, err)
+	assert.Empty(t, got)
+
+	for round := uint(1); round <= 3; round++ {
+		err := db.Snapshot(ctx, round)
+		assert.Nil(t, err)
+	}
+
+	got, err = db.Get(ctx, GetTeamRoundScoresOptions{})
+	assert.Nil(t, err)
+	assert.Len(t, got, 6)
+
+	got, err = db.Get(ctx, GetTeamRoundScoresOptions{TeamID: 1})
+	assert.Nil(t, err)
+
+	type roundScore struct {
+		Round uint
+		Score float64
+		Rank  uint
+	}
+	gotScores := make([]roundScore, 0, len(got))
+	for _, snapshot := range got {
+		assert.Equal(t, uint(1), snapshot.TeamID)
+		gotScores = append(gotScores, roundScore{snapshot.Round, snapshot.Score, snapshot.Rank})
+	}
+	// The rank is calculated with all the teams in the round.
+	want := []roundScore{
+		{Round: 1, Score: -50, Rank: 2},
+		{Round: 2, Score: 0, Rank: 1},
+		{Round: 3, Score: 50, Rank: 1},
+	}
+	assert.Equal(t, want, gotScores)
+}
+
+func testTeamRoundScoresGetByRound(t *testing.T, ctx context.Context, db *teamRoundScores) {
+	got, err := db.GetByRound(ctx, 1)
+	assert.Nil(t, err)
+	assert.Empty(t, got)
+
+	err = db.Snapshot(ctx, 3)
+	assert.Nil(t, err)
+
+	got, err = db.GetByRound(ctx, 3)
+	assert.Nil(t, err)
+	assert.Len(t, got, 2)
+	assert.Equal(t, uint(1), got[0].TeamID)
+	assert.Equal(t, float64(50), got[0].Score)
+	assert.Equal(t, uint(1), got[0].Rank)
+	assert.Equal(t, uint(2), got[1].TeamID)
+	assert.Equal(t, float64(-50), got[1].Score)
+	assert.Equal(t, uint(2), got[1].Rank)
+}
+
+func testTeamRoundScoresDeleteAll(t *testing.T, ctx context.Context, db *teamRoundScores) {
+	err := db.Snapshot(ctx, 1)
+	assert.Nil(t, err)
+
+	err = db.DeleteAll(ctx)
+	assert.Nil(t, err)
+
+	got, err := db.Get(ctx, GetTeamRoundScoresOptions{})
+	assert.Nil(t, err)
+	assert.Empty(t, got)
+
+	// The round can be snapshot again.
+	err = db.Snapshot(ctx, 1)
+	assert.Nil(t, err)
+}
@@ -6,8 +6,11 @@ package dbutil
 
 import (
 	"time"
+
+	"github.com/vidar-team/Cardinal/internal/timeutil"
 )
 
+// Now returns the current time of the global time source in the precision of database.
 func Now() time.Time {
-	return time.Now().Truncate(time.Microsecond)
+	return timeutil.Now().Truncate(time.Microsecond)
 }
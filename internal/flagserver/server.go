@@ -0,0 +1,272 @@
+// Copyright 2021 E99p1ant. All rights reserved.
+// Use of this source code is governed by an AGPL-style
+// license that can be found in the LICENSE file.
+
+// Package flagserver implements the line-based TCP flag submission protocol.
+//
+// The client sends the team token as the first line, and then one flag per line.
+// The server replies a status line for each flag, which is the flag followed by its status, e.g. `flag{...} ACCEPTED`.
+package flagserver
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/time/rate"
+	log "unknwon.dev/clog/v2"
+
+	"github.com/vidar-team/Cardinal/internal/db"
+	"github.com/vidar-team/Cardinal/internal/flagutil"
+	"github.com/vidar-team/Cardinal/internal/ratelimit"
+)
+
+const (
+	// DefaultIdleTimeout is the default duration to close the connection which sends nothing.
+	DefaultIdleTimeout = time.Minute
+	// maxLineLength is the max length of the line sent by the client.
+	maxLineLength = 1024
+)
+
+var ErrServerClosed = errors.New("flag server closed")
+
+// Options contains the options of the flag submission server.
+type Options struct {
+	// RateLimit is the max number of the flags submitted per second in each connection.
+	// The number of the flags is unlimited if it is zero.
+	RateLimit float64
+	// IdleTimeout is the duration to close the connection which sends nothing.
+	IdleTimeout time.Duration
+
+	// Authenticate returns the team with the given token, it is db.Teams.GetByToken by default.
+	Authenticate func(ctx context.Context, token string) (*db.Team, error)
+	// Submit submits the flags for the team, it is flagutil.Submit by default.
+	Submit func(ctx context.Context, team *db.Team, ip string, flags []string) ([]*flagutil.SubmitResult, error)
+	// Reject records the flags rejected with the given status, it is flagutil.Reject by default.
+	Reject func(ctx context.Context, team *db.Team, ip string, flags []string, status flagutil.SubmitStatus) error
+}
+
+// Server is the TCP flag submission server.
+type Server struct {
+	options Options
+
+	mu       sync.Mutex
+	listener net.Listener
+	conns    map[net.Conn]struct{}
+	closed   bool
+	wg       sync.WaitGroup
+}
+
+// NewServer returns a new flag submission server with the given options.
+func NewServer(opts Options) *Server {
+	if opts.IdleTimeout == 0 {
+		opts.IdleTimeout = DefaultIdleTimeout
+	}
+	if opts.Authenticate == nil {
+		opts.Authenticate = func(ctx context.Context, token string) (*db.Team, error) {
+			return db.Teams.GetByToken(ctx, token)
+		}
+	}
+	if opts.Submit == nil {
+		opts.Submit = flagutil.Submit
+	}
+	if opts.Reject == nil {
+		opts.Reject = flagutil.Reject
+	}
+
+	return &Server{
+		options: opts,
+		conns:   make(map[net.Conn]struct{}),
+	}
+}
+
+// ListenAndServe listens on the TCP address and serves the connections.
+func (s *Server) ListenAndServe(addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return errors.Wrap(err, "listen")
+	}
+	return s.Serve(listener)
+}
+
+// Serve accepts the connections on the listener until the server is shut down.
+// It always returns a non-nil error, and the error is ErrServerClosed after Shutdown.
+func (s *Server) Serve(listener net.Listener) error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		_ = listener.Close()
+		return ErrServerClosed
+	}
+	s.listener = listener
+	s.mu.Unlock()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			s.mu.Lock()
+			closed := s.closed
+			s.mu.Unlock()
+			if closed {
+				return ErrServerClosed
+			}
+
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Temporary() {
+				time.Sleep(100 * time.Millisecond)
+				continue
+			}
+			return errors.Wrap(err, "accept")
+		}
+
+		if !s.trackConn(conn) {
+			_ = conn.Close()
+			return ErrServerClosed
+		}
+		go func() {
+			defer s.untrackConn(conn)
+			s.serveConn(conn)
+		}()
+	}
+}
+
+func (s *Server) trackConn(conn net.Conn) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return false
+	}
+	s.conns[conn] = struct{}{}
+	s.wg.Add(1)
+	return true
+}
+
+func (s *Server) untrackConn(conn net.Conn) {
+	_ = conn.Close()
+
+	s.mu.Lock()
+	delete(s.conns, conn)
+	s.mu.Unlock()
+	s.wg.Done()
+}
+
+// Shutdown stops accepting the new connections, and interrupts the idle connections.
+// The flags which are being submitted are finished before the connection closed.
+// The remaining connections are closed forcibly when the context is done.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	s.closed = true
+	var err error
+	if s.listener != nil {
+		err = s.listener.Close()
+	}
+	// Interrupt the reading, the connection is closed after the current flag replied.
+	for conn := range s.conns {
+		_ = conn.SetReadDeadline(time.Now())
+	}
+	s.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return err
+	case <-ctx.Done():
+		s.mu.Lock()
+		for conn := range s.conns {
+			_ = conn.Close()
+		}
+		s.mu.Unlock()
+		return ctx.Err()
+	}
+}
+
+func (s *Server) serveConn(conn net.Conn) {
+	ctx := context.Background()
+	ip, _, _ := net.SplitHostPort(conn.RemoteAddr().String())
+
+	scanner := bufio.NewScanner(conn)
+	scanner.Buffer(make([]byte, maxLineLength), maxLineLength)
+	readLine := func() (string, bool) {
+		// The deadline is set with the lock, so it won't override the one set by Shutdown.
+		s.mu.Lock()
+		closed := s.closed
+		if !closed {
+			_ = conn.SetReadDeadline(time.Now().Add(s.options.IdleTimeout))
+		}
+		s.mu.Unlock()
+		if closed || !scanner.Scan() {
+			return "", false
+		}
+		return strings.TrimSpace(scanner.Text()), true
+	}
+	writeLine := func(format string, v ...interface{}) bool {
+		_, err := fmt.Fprintf(conn, format+"\n", v...)
+		return err == nil
+	}
+
+	if !writeLine("Welcome to Cardinal flag submission server, please send your team token.") {
+		return
+	}
+	token, ok := readLine()
+	if !ok {
+		return
+	}
+	team, err := s.options.Authenticate(ctx, token)
+	if err != nil {
+		if err != db.ErrTeamNotExists {
+			log.Error("Failed to get team by token: %v", err)
+		}
+		writeLine("Invalid team token.")
+		return
+	}
+	if !writeLine("Hello %s, please send one flag per line.", team.Name) {
+		return
+	}
+
+	var limiter *rate.Limiter
+	if s.options.RateLimit > 0 {
+		burst := int(s.options.RateLimit)
+		if burst < 1 {
+			burst = 1
+		}
+		limiter = rate.NewLimiter(rate.Limit(s.options.RateLimit), burst)
+	}
+
+	for {
+		flag, ok := readLine()
+		if !ok {
+			return
+		}
+		if flag == "" {
+			continue
+		}
+
+		// The flag is limited by both the connection and the team.
+		status := flagutil.SubmitStatusRateLimited
+		if (limiter == nil || limiter.Allow()) && ratelimit.Allow(ctx, team) {
+			results, err := s.options.Submit(ctx, team, ip, []string{flag})
+			if err != nil {
+				log.Error("Failed to submit flag: %v", err)
+				writeLine("%s ERROR", flag)
+				return
+			}
+			status = results[0].Status
+		} else if err := s.options.Reject(ctx, team, ip, []string{flag}, status); err != nil {
+			log.Error("Failed to record rate limited flag: %v", err)
+		}
+
+		if !writeLine("%s %s", flag, strings.ToUpper(string(status))) {
+			return
+		}
+	}
+}
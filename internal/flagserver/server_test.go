@@ -0,0 +1,165 @@
+// Copyright 2021 E99p1ant. All rights reserved.
+// Use of this source code is governed by an AGPL-style
+// license that can be found in the LICENSE file.
+
+package flagserver
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/vidar-team/Cardinal/internal/db"
+	"github.com/vidar-team/Cardinal/internal/flagutil"
+)
+
+func newTestServer(t *testing.T, opts Options) (*Server, string) {
+	t.Helper()
+
+	opts.Authenticate = func(_ context.Context, token string) (*db.Team, error) {
+		if token != "t0ken" {
+			return nil, db.ErrTeamNotExists
+		}
+		return &db.Team{Name: "Vidar"}, nil
+	}
+	opts.Submit = func(_ context.Context, _ *db.Team, _ string, flags []string) ([]*flagutil.SubmitResult, error) {
+		status := flagutil.SubmitStatusInvalid
+		if flags[0] == "d3ctf{correct}" {
+			status = flagutil.SubmitStatusAccepted
+		}
+		return []*flagutil.SubmitResult{{Flag: flags[0], Status: status}}, nil
+	}
+	if opts.Reject == nil {
+		opts.Reject = func(context.Context, *db.Team, string, []string, flagutil.SubmitStatus) error {
+			return nil
+		}
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err)
+
+	server := NewServer(opts)
+	go func() { _ = server.Serve(listener) }()
+	t.Cleanup(func() { _ = server.Shutdown(context.Background()) })
+
+	return server, listener.Addr().String()
+}
+
+type testClient struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+func dial(t *testing.T, addr string) *testClient {
+	t.Helper()
+
+	conn, err := net.Dial("tcp", addr)
+	require.Nil(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+	_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	client := &testClient{Conn: conn, reader: bufio.NewReader(conn)}
+	// Skip the welcome message.
+	_ = client.readLine(t)
+	return client
+}
+
+func (c *testClient) send(t *testing.T, line string) {
+	t.Helper()
+
+	_, err := fmt.Fprintln(c, line)
+	require.Nil(t, err)
+}
+
+func (c *testClient) readLine(t *testing.T) string {
+	t.Helper()
+
+	line, err := c.reader.ReadString('\n')
+	require.Nil(t, err)
+	return line
+}
+
+func TestServer(t *testing.T) {
+	t.Run("submit", func(t *testing.T) {
+		_, addr := newTestServer(t, Options{})
+		client := dial(t, addr)
+
+		client.send(t, "t0ken")
+		assert.Equal(t, "Hello Vidar, please send one flag per line.\n", client.readLine(t))
+
+		client.send(t, "d3ctf{correct}")
+		assert.Equal(t, "d3ctf{correct} ACCEPTED\n", client.readLine(t))
+		// The empty line is ignored.
+		client.send(t, "")
+		client.send(t, " d3ctf{wrong} ")
+		assert.Equal(t, "d3ctf{wrong} INVALID\n", client.readLine(t))
+	})
+
+	t.Run("invalid token", func(t *testing.T) {
+		_, addr := newTestServer(t, Options{})
+		client := dial(t, addr)
+
+		client.send(t, "wrong")
+		assert.Equal(t, "Invalid team token.\n", client.readLine(t))
+
+		// The connection is closed.
+		_, err := client.reader.ReadString('\n')
+		assert.NotNil(t, err)
+	})
+
+	t.Run("rate limit", func(t *testing.T) {
+		rejected := make(chan string, 1)
+		_, addr := newTestServer(t, Options{
+			RateLimit: 2,
+			Reject: func(_ context.Context, _ *db.Team, _ string, flags []string, status flagutil.SubmitStatus) error {
+				assert.Equal(t, flagutil.SubmitStatusRateLimited, status)
+				rejected <- flags[0]
+				return nil
+			},
+		})
+		client := dial(t, addr)
+		client.send(t, "t0ken")
+		_ = client.readLine(t)
+
+		for i := 0; i < 2; i++ {
+			client.send(t, "d3ctf{correct}")
+			assert.Equal(t, "d3ctf{correct} ACCEPTED\n", client.readLine(t))
+		}
+		client.send(t, "d3ctf{correct}")
+		assert.Equal(t, "d3ctf{correct} RATE_LIMITED\n", client.readLine(t))
+
+		// The rate limited flag is recorded.
+		assert.Equal(t, "d3ctf{correct}", <-rejected)
+	})
+
+	t.Run("idle timeout", func(t *testing.T) {
+		_, addr := newTestServer(t, Options{IdleTimeout: 100 * time.Millisecond})
+		client := dial(t, addr)
+
+		_, err := client.reader.ReadString('\n')
+		assert.NotNil(t, err)
+	})
+
+	t.Run("shutdown", func(t *testing.T) {
+		server, addr := newTestServer(t, Options{})
+		client := dial(t, addr)
+		client.send(t, "t0ken")
+		_ = client.readLine(t)
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		assert.Nil(t, server.Shutdown(ctx))
+
+		// The idle connection is closed, and the new connection is refused.
+		_, err := client.reader.ReadString('\n')
+		assert.NotNil(t, err)
+		_, err = net.Dial("tcp", addr)
+		assert.NotNil(t, err)
+	})
+}
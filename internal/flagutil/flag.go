@@ -0,0 +1,191 @@
+// Copyright 2021 E99p1ant. All rights reserved.
+// Use of this source code is governed by an AGPL-style
+// license that can be found in the LICENSE file.
+
+package flagutil
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"strings"
+	"sync"
+	"text/template"
+
+	"github.com/pkg/errors"
+
+	"github.com/vidar-team/Cardinal/internal/conf"
+	"github.com/vidar-team/Cardinal/internal/utils"
+)
+
+const (
+	HMACSHA1   = "sha1"
+	HMACSHA256 = "sha256"
+)
+
+// templateData is the data of the flag template.
+type templateData struct {
+	TeamID    uint
+	GameBoxID uint
+	Round     uint
+	// HMAC is the hex encoded HMAC of `TeamID|GameBoxID|Round` with the security salt.
+	HMAC   string
+	Prefix string
+	Suffix string
+
+	key         []byte
+	randomCount int
+}
+
+// Random returns the hex string with the given length. It is derived from the security salt,
+// so it can't be guessed by the teams, and the flag is the same when it is generated again.
+func (d *templateData) Random(length int) string {
+	if length <= 0 {
+		return ""
+	}
+
+	d.randomCount++
+	var random strings.Builder
+	for counter := 0; random.Len() < length; counter++ {
+		h := hmac.New(sha256.New, d.key)
+		_, _ = fmt.Fprintf(h, "random|%d|%d|%d|%d|%d", d.TeamID, d.GameBoxID, d.Round, d.randomCount, counter)
+		random.WriteString(hex.EncodeToString(h.Sum(nil)))
+	}
+	return random.String()[:length]
+}
+
+var (
+	templateMu     sync.Mutex
+	templateText   string
+	parsedTemplate *template.Template
+)
+
+// flagTemplate returns the parsed flag template in the configuration.
+// It returns nil if the template is not set.
+func flagTemplate() (*template.Template, error) {
+	templateMu.Lock()
+	defer templateMu.Unlock()
+
+	if conf.Game.FlagTemplate == "" {
+		return nil, nil
+	}
+	if parsedTemplate != nil && templateText == conf.Game.FlagTemplate {
+		return parsedTemplate, nil
+	}
+
+	// The brace next to the action is the literal brace of the flag, e.g. `flag{{{.HMAC}}}`.
+	text := strings.NewReplacer(
+		"{{{", `{{"{"}}{{`,
+		"}}}", `}}{{"}"}}`,
+	).Replace(conf.Game.FlagTemplate)
+
+	tmpl, err := template.New("flag").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, errors.Wrap(err, "parse flag template")
+	}
+	templateText, parsedTemplate = conf.Game.FlagTemplate, tmpl
+	return tmpl, nil
+}
+
+// hmacHash returns the hash function of the HMAC selected in the configuration.
+func hmacHash() (func() hash.Hash, error) {
+	switch conf.Game.FlagHMAC {
+	case "", HMACSHA1:
+		return sha1.New, nil
+	case HMACSHA256:
+		return sha256.New, nil
+	default:
+		return nil, errors.Errorf("unexpected flag HMAC algorithm %q", conf.Game.FlagHMAC)
+	}
+}
+
+// Generate returns the flag of the game box in the given round.
+// The flag is rendered with the flag template in the configuration, or it is
+// FlagPrefix + hmac(TeamID + | + GameBoxID + | + Round, sha1(salt)) + FlagSuffix if the template is not set.
+func Generate(teamID, gameBoxID, round uint) (string, error) {
+	return generate(conf.App.SecuritySalt, teamID, gameBoxID, round)
+}
+
+// generate returns the flag of the game box in the given round with the given security salt.
+func generate(salt string, teamID, gameBoxID, round uint) (string, error) {
+	hashFunc, err := hmacHash()
+	if err != nil {
+		return "", err
+	}
+
+	key := []byte(utils.Sha1Encode(salt))
+	h := hmac.New(hashFunc, key)
+	_, _ = fmt.Fprintf(h, "%d|%d|%d", teamID, gameBoxID, round)
+	digest := hex.EncodeToString(h.Sum(nil))
+
+	tmpl, err := flagTemplate()
+	if err != nil {
+		return "", err
+	}
+	if tmpl == nil {
+		return conf.Game.FlagPrefix + digest + conf.Game.FlagSuffix, nil
+	}
+
+	var flag strings.Builder
+	if err := tmpl.Execute(&flag, &templateData{
+		TeamID:    teamID,
+		GameBoxID: gameBoxID,
+		Round:     round,
+		HMAC:      digest,
+		Prefix:    conf.Game.FlagPrefix,
+		Suffix:    conf.Game.FlagSuffix,
+		key:       key,
+	}); err != nil {
+		return "", errors.Wrap(err, "execute flag template")
+	}
+	return flag.String(), nil
+}
+
+// CheckFormat checks the flag template, the HMAC algorithm and the flag regular expression in the configuration.
+// The flag generated with the template should match the regular expression. The template must contain
+// .HMAC or .Random, so the flags are different for each game box and can't be guessed without the security salt.
+func CheckFormat() error {
+	flag, err := Generate(1, 1, 1)
+	if err != nil {
+		return err
+	}
+	if flag == "" {
+		return errors.New("empty flag")
+	}
+
+	otherFlag, err := Generate(2, 2, 1)
+	if err != nil {
+		return err
+	}
+	if otherFlag == flag {
+		return errors.Errorf("generated flag %q is the same for different game boxes, use .HMAC or .Random in the template", flag)
+	}
+	guessedFlag, err := generate(conf.App.SecuritySalt+"|guess", 1, 1, 1)
+	if err != nil {
+		return err
+	}
+	if guessedFlag == flag {
+		return errors.Errorf("generated flag %q does not depend on the security salt, use .HMAC or .Random in the template", flag)
+	}
+
+	re, err := conf.FlagRegexp()
+	if err != nil {
+		return err
+	}
+	if re != nil && !re.MatchString(flag) {
+		return errors.Errorf("generated flag %q does not match the regular expression", flag)
+	}
+	return nil
+}
+
+// Expired returns whether the flag of the given round can not be submitted in the current round.
+func Expired(flagRound, currentRound uint) bool {
+	validRounds := conf.Game.FlagValidRounds
+	if validRounds == 0 {
+		validRounds = 1
+	}
+	return flagRound > currentRound || currentRound-flagRound >= validRounds
+}
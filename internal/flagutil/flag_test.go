@@ -0,0 +1,165 @@
+// Copyright 2021 E99p1ant. All rights reserved.
+// Use of this source code is governed by an AGPL-style
+// license that can be found in the LICENSE file.
+
+package flagutil
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/vidar-team/Cardinal/internal/conf"
+	"github.com/vidar-team/Cardinal/internal/db"
+)
+
+func TestGenerate(t *testing.T) {
+	conf.App.SecuritySalt = "salt"
+	conf.Game.FlagPrefix = "d3ctf{"
+	conf.Game.FlagSuffix = "}"
+	t.Cleanup(func() {
+		conf.App.SecuritySalt = ""
+		conf.Game.FlagPrefix = ""
+		conf.Game.FlagSuffix = ""
+		conf.Game.FlagTemplate = ""
+		conf.Game.FlagHMAC = ""
+	})
+
+	generate := func(teamID, gameBoxID, round uint) string {
+		flag, err := Generate(teamID, gameBoxID, round)
+		assert.Nil(t, err)
+		return flag
+	}
+
+	for _, tc := range []struct {
+		name     string
+		template string
+		hmac     string
+		want     string
+	}{
+		{name: "default", want: `^d3ctf\{[0-9a-f]{40}\}$`},
+		{name: "sha256", hmac: HMACSHA256, want: `^d3ctf\{[0-9a-f]{64}\}$`},
+		{name: "random", template: "flag{{{.Random 32}}}", want: `^flag\{[0-9a-f]{32}\}$`},
+		{name: "round and HMAC", template: "FLAG_{{.Round}}_{{.HMAC}}", want: `^FLAG_1_[0-9a-f]{40}$`},
+		{name: "prefix and suffix", template: "{{.Prefix}}{{.TeamID}}_{{.Random 8}}_{{.Random 8}}{{.Suffix}}", want: `^d3ctf\{1_[0-9a-f]{8}_[0-9a-f]{8}\}$`},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			conf.Game.FlagTemplate = tc.template
+			conf.Game.FlagHMAC = tc.hmac
+
+			flag := generate(1, 1, 1)
+			assert.Regexp(t, tc.want, flag)
+
+			// The flag is the same in the same round of the game box.
+			assert.Equal(t, flag, generate(1, 1, 1))
+			assert.NotEqual(t, flag, generate(1, 1, 2))
+			assert.NotEqual(t, flag, generate(1, 2, 1))
+			assert.NotEqual(t, flag, generate(2, 1, 1))
+		})
+	}
+
+	// The random strings in the same flag are different.
+	conf.Game.FlagTemplate = "{{.Random 8}}{{.Random 8}}"
+	conf.Game.FlagHMAC = ""
+	flag := generate(1, 1, 1)
+	assert.NotEqual(t, flag[:8], flag[8:])
+
+	conf.Game.FlagTemplate = "{{.Unknown}}"
+	_, err := Generate(1, 1, 1)
+	assert.NotNil(t, err)
+
+	conf.Game.FlagTemplate = ""
+	conf.Game.FlagHMAC = "md5"
+	_, err = Generate(1, 1, 1)
+	assert.NotNil(t, err)
+}
+
+func TestCheckFormat(t *testing.T) {
+	t.Cleanup(func() {
+		conf.Game.FlagTemplate = ""
+		conf.Game.FlagRegexp = ""
+	})
+
+	conf.Game.FlagTemplate = "flag{{{.Random 32}}}"
+	conf.Game.FlagRegexp = `^flag\{[0-9a-f]{32}\}$`
+	assert.Nil(t, CheckFormat())
+
+	conf.Game.FlagRegexp = `^flag\{[0-9a-f]{16}\}$`
+	assert.NotNil(t, CheckFormat())
+
+	conf.Game.FlagRegexp = `(`
+	assert.NotNil(t, CheckFormat())
+
+	// The flags which can be guessed by the teams are rejected.
+	conf.Game.FlagRegexp = ""
+	for _, template := range []string{
+		"FLAG_{{.Round}}",
+		"flag{static}",
+		"flag{{{.TeamID}}_{{.GameBoxID}}_{{.Round}}}",
+	} {
+		conf.Game.FlagTemplate = template
+		assert.NotNil(t, CheckFormat(), template)
+	}
+
+	conf.Game.FlagTemplate = "FLAG_{{.Round}}_{{.HMAC}}"
+	assert.Nil(t, CheckFormat())
+}
+
+func TestExpired(t *testing.T) {
+	for _, tc := range []struct {
+		name         string
+		validRounds  uint
+		flagRound    uint
+		currentRound uint
+		want         bool
+	}{
+		{name: "current round by default", validRounds: 0, flagRound: 3, currentRound: 3, want: false},
+		{name: "previous round by default", validRounds: 0, flagRound: 2, currentRound: 3, want: true},
+		{name: "future round", validRounds: 3, flagRound: 4, currentRound: 3, want: true},
+		{name: "in valid rounds", validRounds: 3, flagRound: 1, currentRound: 3, want: false},
+		{name: "out of valid rounds", validRounds: 3, flagRound: 1, currentRound: 4, want: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			conf.Game.FlagValidRounds = tc.validRounds
+			t.Cleanup(func() {
+				conf.Game.FlagValidRounds = 0
+			})
+
+			assert.Equal(t, tc.want, Expired(tc.flagRound, tc.currentRound))
+		})
+	}
+}
+
+func TestGetPusher(t *testing.T) {
+	_, err := GetPusher("")
+	assert.Nil(t, err)
+	_, err = GetPusher(PusherSSH)
+	assert.Nil(t, err)
+	_, err = GetPusher("not-exist")
+	assert.Equal(t, ErrPusherNotExists, err)
+
+	var pushed string
+	RegisterPusher("test", PusherFunc(func(_ context.Context, _ *db.GameBox, flag *db.Flag) error {
+		pushed = flag.Value
+		return nil
+	}))
+	pusher, err := GetPusher("test")
+	assert.Nil(t, err)
+	err = pusher.Push(context.Background(), &db.GameBox{}, &db.Flag{Value: "d3ctf{test}"})
+	assert.Nil(t, err)
+	assert.Equal(t, "d3ctf{test}", pushed)
+}
+
+func TestRenderCommand(t *testing.T) {
+	flag := &db.Flag{
+		TeamID:      1,
+		ChallengeID: 2,
+		GameBoxID:   3,
+		Round:       4,
+		Value:       "d3ctf{test}",
+	}
+
+	got := RenderCommand("echo {{FLAG}} > /flag # {{TEAM_ID}} {{CHALLENGE_ID}} {{GAMEBOX_ID}} {{ROUND}} {{UNKNOWN}}", flag)
+	assert.Equal(t, "echo d3ctf{test} > /flag # 1 2 3 4 {{UNKNOWN}}", got)
+}
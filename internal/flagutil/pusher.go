@@ -0,0 +1,117 @@
+// Copyright 2021 E99p1ant. All rights reserved.
+// Use of this source code is governed by an AGPL-style
+// license that can be found in the LICENSE file.
+
+package flagutil
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+
+	"github.com/vidar-team/Cardinal/internal/conf"
+	"github.com/vidar-team/Cardinal/internal/db"
+	"github.com/vidar-team/Cardinal/internal/sshexec"
+)
+
+// Pusher writes the flag into the game box.
+type Pusher interface {
+	// Push writes the flag into the given game box, the challenge of the game box is loaded.
+	Push(ctx context.Context, gameBox *db.GameBox, flag *db.Flag) error
+}
+
+// PusherFunc is an adapter to allow the use of ordinary functions as the Pusher.
+type PusherFunc func(ctx context.Context, gameBox *db.GameBox, flag *db.Flag) error
+
+// Push calls f(ctx, gameBox, flag).
+func (f PusherFunc) Push(ctx context.Context, gameBox *db.GameBox, flag *db.Flag) error {
+	return f(ctx, gameBox, flag)
+}
+
+const PusherSSH = "ssh"
+
+var (
+	pushersMu sync.RWMutex
+	pushers   = map[string]Pusher{
+		PusherSSH: PusherFunc(sshPush),
+	}
+)
+
+// RegisterPusher registers the flag pusher with the given name,
+// the existing pusher with the same name will be replaced.
+func RegisterPusher(name string, pusher Pusher) {
+	pushersMu.Lock()
+	defer pushersMu.Unlock()
+	pushers[name] = pusher
+}
+
+var ErrPusherNotExists = errors.New("flag pusher does not exist")
+
+// GetPusher returns the flag pusher with the given name.
+// It returns the SSH pusher if the name is empty.
+func GetPusher(name string) (Pusher, error) {
+	if name == "" {
+		name = PusherSSH
+	}
+
+	pushersMu.RLock()
+	defer pushersMu.RUnlock()
+
+	pusher, ok := pushers[name]
+	if !ok {
+		return nil, ErrPusherNotExists
+	}
+	return pusher, nil
+}
+
+// RenderCommand replaces the placeholders in the renew flag command with the flag,
+// including `{{FLAG}}`, `{{TEAM_ID}}`, `{{CHALLENGE_ID}}`, `{{GAMEBOX_ID}}` and `{{ROUND}}`.
+func RenderCommand(command string, flag *db.Flag) string {
+	return strings.NewReplacer(
+		"{{FLAG}}", flag.Value,
+		"{{TEAM_ID}}", strconv.Itoa(int(flag.TeamID)),
+		"{{CHALLENGE_ID}}", strconv.Itoa(int(flag.ChallengeID)),
+		"{{GAMEBOX_ID}}", strconv.Itoa(int(flag.GameBoxID)),
+		"{{ROUND}}", strconv.Itoa(int(flag.Round)),
+	).Replace(command)
+}
+
+// SSHConfig returns the SSH configuration of the game box. The private key
+// set in the configuration file is used along with the password of the game box.
+func SSHConfig(gameBox *db.GameBox) (sshexec.Config, error) {
+	config := sshexec.Config{
+		Host:     gameBox.IPAddress,
+		Port:     gameBox.InternalSSHPort,
+		User:     gameBox.InternalSSHUser,
+		Password: gameBox.InternalSSHPassword,
+		HostKey:  gameBox.InternalSSHHostKey,
+	}
+
+	if conf.Game.SSHPrivateKeyFile != "" {
+		privateKey, err := os.ReadFile(conf.Game.SSHPrivateKeyFile)
+		if err != nil {
+			return sshexec.Config{}, errors.Wrap(err, "read private key")
+		}
+		config.PrivateKey = privateKey
+	}
+	return config, nil
+}
+
+// sshPush executes the renew flag command of the challenge in the game box through SSH.
+func sshPush(ctx context.Context, gameBox *db.GameBox, flag *db.Flag) error {
+	if gameBox.Challenge == nil {
+		return errors.New("challenge of the game box is not loaded")
+	}
+
+	config, err := SSHConfig(gameBox)
+	if err != nil {
+		return err
+	}
+
+	_, err = sshexec.Run(ctx, config, RenderCommand(gameBox.Challenge.RenewFlagCommand, flag))
+	return err
+}
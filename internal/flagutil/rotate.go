@@ -0,0 +1,180 @@
+// Copyright 2021 E99p1ant. All rights reserved.
+// Use of this source code is governed by an AGPL-style
+// license that can be found in the LICENSE file.
+
+package flagutil
+
+import (
+	"context"
+	"sync"
+
+	"github.com/pkg/errors"
+	log "unknwon.dev/clog/v2"
+
+	"github.com/vidar-team/Cardinal/internal/conf"
+	"github.com/vidar-team/Cardinal/internal/db"
+	"github.com/vidar-team/Cardinal/internal/sshexec"
+	"github.com/vidar-team/Cardinal/internal/timeutil"
+)
+
+// Rotate generates the flags of the given round for all the game boxes, including the ones added
+// in the middle of the game, and pushes the flags to the game boxes whose challenge renews the flag automatically.
+// The delivery status of each flag is saved, the failure of a game box does not stop the others.
+func Rotate(ctx context.Context, round uint) error {
+	pusher, err := GetPusher(conf.Game.FlagPusher)
+	if err != nil {
+		return errors.Wrapf(err, "get flag pusher %q", conf.Game.FlagPusher)
+	}
+
+	gameBoxes, err := db.GameBoxes.Get(ctx, db.GetGameBoxesOption{})
+	if err != nil {
+		return errors.Wrap(err, "get game boxes")
+	}
+	if len(gameBoxes) == 0 {
+		return nil
+	}
+
+	flagMetadatas := make([]db.FlagMetadata, 0, len(gameBoxes))
+	for _, gameBox := range gameBoxes {
+		value, err := Generate(gameBox.TeamID, gameBox.ID, round)
+		if err != nil {
+			return errors.Wrap(err, "generate flag")
+		}
+		flagMetadatas = append(flagMetadatas, db.FlagMetadata{
+			GameBoxID: gameBox.ID,
+			Round:     round,
+			Value:     value,
+		})
+	}
+	if err := db.Flags.BatchCreate(ctx, db.CreateFlagOptions{
+		Flags: flagMetadatas,
+	}); err != nil {
+		return errors.Wrap(err, "batch create flags")
+	}
+
+	flags, _, err := db.Flags.Get(ctx, db.GetFlagOptions{
+		Round: round,
+	})
+	if err != nil {
+		return errors.Wrap(err, "get flags")
+	}
+	gameBoxFlags := make(map[uint]*db.Flag, len(flags))
+	for _, flag := range flags {
+		gameBoxFlags[flag.GameBoxID] = flag
+	}
+
+	targets := make([]PushTarget, 0, len(gameBoxes))
+	for _, gameBox := range gameBoxes {
+		if gameBox.Challenge == nil || !gameBox.Challenge.AutoRenewFlag {
+			continue
+		}
+
+		flag, ok := gameBoxFlags[gameBox.ID]
+		// The flag has been delivered before Cardinal restarted.
+		if !ok || flag.Status == db.FlagStatusDelivered {
+			continue
+		}
+		targets = append(targets, PushTarget{
+			GameBox: gameBox,
+			Flag:    flag,
+		})
+	}
+
+	_, err = PushAll(ctx, pusher, targets)
+	return err
+}
+
+// DefaultPushConcurrency is the default max number of the game boxes which the flags are pushed to at the same time.
+const DefaultPushConcurrency = 10
+
+// PushTarget is the game box and the flag to be pushed into it.
+type PushTarget struct {
+	GameBox *db.GameBox
+	Flag    *db.Flag
+}
+
+// PushResult is the delivery result of the flag.
+type PushResult struct {
+	GameBoxID   uint          `json:"GameBoxID"`
+	TeamID      uint          `json:"TeamID"`
+	ChallengeID uint          `json:"ChallengeID"`
+	Round       uint          `json:"Round"`
+	Status      db.FlagStatus `json:"Status"`
+	Message     string        `json:"Message"`
+	ExitCode    int           `json:"ExitCode"`
+	Latency     int64         `json:"Latency"` // In milliseconds.
+}
+
+// PushAll pushes the flags to the game boxes concurrently with the given pusher, the number of the
+// game boxes pushing at the same time is limited by the configuration. The results are in the same
+// order as the targets. It only returns the error when the delivery status failed to be saved.
+func PushAll(ctx context.Context, pusher Pusher, targets []PushTarget) ([]*PushResult, error) {
+	concurrency := conf.Game.FlagPushConcurrency
+	if concurrency <= 0 {
+		concurrency = DefaultPushConcurrency
+	}
+
+	results := make([]*PushResult, len(targets))
+	errs := make([]error, len(targets))
+	semaphore := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, target := range targets {
+		i, target := i, target
+
+		wg.Add(1)
+		semaphore <- struct{}{}
+		go func() {
+			defer func() {
+				<-semaphore
+				wg.Done()
+			}()
+			results[i], errs[i] = Push(ctx, pusher, target.GameBox, target.Flag)
+		}()
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return results, err
+		}
+	}
+	return results, nil
+}
+
+// Push pushes the flag to the game box with the given pusher, and saves the delivery status of the flag.
+// It only returns the error when the status failed to be saved.
+func Push(ctx context.Context, pusher Pusher, gameBox *db.GameBox, flag *db.Flag) (*PushResult, error) {
+	startAt := timeutil.Now()
+	err := pusher.Push(ctx, gameBox, flag)
+	latency := timeutil.Now().Sub(startAt).Milliseconds()
+
+	opts := db.SetFlagStatusOptions{
+		Status:  db.FlagStatusDelivered,
+		Latency: latency,
+	}
+	if err != nil {
+		log.Warn("Failed to push flag of round %d to game box %d: %v", flag.Round, gameBox.ID, err)
+		opts.Status = db.FlagStatusFailed
+		opts.Message = err.Error()
+		opts.ExitCode = -1
+
+		var exitErr *sshexec.ExitError
+		if errors.As(err, &exitErr) {
+			opts.ExitCode = exitErr.Code
+		}
+	}
+
+	if err := db.Flags.SetStatus(ctx, flag.ID, opts); err != nil {
+		return nil, errors.Wrap(err, "set flag status")
+	}
+	return &PushResult{
+		GameBoxID:   gameBox.ID,
+		TeamID:      gameBox.TeamID,
+		ChallengeID: gameBox.ChallengeID,
+		Round:       flag.Round,
+		Status:      opts.Status,
+		Message:     opts.Message,
+		ExitCode:    opts.ExitCode,
+		Latency:     opts.Latency,
+	}, nil
+}
@@ -0,0 +1,242 @@
+// Copyright 2021 E99p1ant. All rights reserved.
+// Use of this source code is governed by an AGPL-style
+// license that can be found in the LICENSE file.
+
+package flagutil
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	log "unknwon.dev/clog/v2"
+
+	"github.com/vidar-team/Cardinal/internal/clock"
+	"github.com/vidar-team/Cardinal/internal/db"
+	"github.com/vidar-team/Cardinal/internal/livelog"
+	"github.com/vidar-team/Cardinal/internal/locales"
+	"github.com/vidar-team/Cardinal/internal/misc/webhook"
+	"github.com/vidar-team/Cardinal/internal/ratelimit"
+)
+
+// SubmitStatus is the result status of the submitted flag.
+type SubmitStatus string
+
+const (
+	SubmitStatusAccepted   SubmitStatus = "accepted"
+	SubmitStatusOwnFlag    SubmitStatus = "own_flag"
+	SubmitStatusExpired    SubmitStatus = "expired"
+	SubmitStatusDuplicate  SubmitStatus = "duplicate"
+	SubmitStatusInvalid    SubmitStatus = "invalid"
+	SubmitStatusNotRunning SubmitStatus = "not_running"
+	// SubmitStatusRateLimited is the status of the flag which is rejected because it is submitted too frequently.
+	SubmitStatusRateLimited SubmitStatus = "rate_limited"
+	// SubmitStatusLocked is the status of the flag which is rejected because the team is locked out
+	// for submitting too many invalid flags.
+	SubmitStatusLocked SubmitStatus = "locked"
+	// SubmitStatusError is the status of the flag which can't be checked because of the server error.
+	SubmitStatusError SubmitStatus = "error"
+)
+
+// SubmitResult is the result of the submitted flag.
+type SubmitResult struct {
+	Flag   string       `json:"Flag"`
+	Status SubmitStatus `json:"Status"`
+}
+
+// Submit submits the flags of the other teams for the given team, the results are in the same order as the flags.
+// The flags are checked in one query, and the been attacked actions are created in one transaction.
+// The team is locked out for a while if it submits too many invalid flags.
+// Every submitted flag is recorded with its result and the source IP for auditing,
+// the flags are recorded with SubmitStatusError if they can't be checked.
+func Submit(ctx context.Context, team *db.Team, ip string, flags []string) ([]*SubmitResult, error) {
+	results, flagSets, round, err := submit(ctx, team, flags)
+	if err != nil {
+		if err := Reject(ctx, team, ip, flags, SubmitStatusError); err != nil {
+			log.Error("Failed to record failed submissions: %v", err)
+		}
+		return nil, err
+	}
+
+	if err := record(ctx, team, ip, round, results, flagSets); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// Reject records the flags which are rejected before being checked with the given status,
+// e.g. the flags submitted too frequently.
+func Reject(ctx context.Context, team *db.Team, ip string, flags []string, status SubmitStatus) error {
+	results := make([]*SubmitResult, 0, len(flags))
+	for _, flag := range flags {
+		results = append(results, &SubmitResult{
+			Flag:   flag,
+			Status: status,
+		})
+	}
+
+	_, round := clock.T.State()
+	return record(ctx, team, ip, round, results, nil)
+}
+
+// record creates the submissions of the results, the game box of the flag is recorded if it is found.
+func record(ctx context.Context, team *db.Team, ip string, round uint, results []*SubmitResult, flagSets map[string]*db.Flag) error {
+	submissionOptions := make([]db.CreateSubmissionOptions, 0, len(results))
+	for _, result := range results {
+		var gameBoxID uint
+		if flag, ok := flagSets[result.Flag]; ok {
+			gameBoxID = flag.GameBoxID
+		}
+		submissionOptions = append(submissionOptions, db.CreateSubmissionOptions{
+			TeamID:    team.ID,
+			Flag:      result.Flag,
+			Status:    string(result.Status),
+			IP:        ip,
+			Round:     round,
+			GameBoxID: gameBoxID,
+		})
+	}
+	if err := db.Submissions.BatchCreate(ctx, submissionOptions); err != nil {
+		return errors.Wrap(err, "create submissions")
+	}
+	return nil
+}
+
+// submit returns the results of the flags, the flags found and the current round.
+func submit(ctx context.Context, team *db.Team, flags []string) ([]*SubmitResult, map[string]*db.Flag, uint, error) {
+	results := make([]*SubmitResult, 0, len(flags))
+	for _, flag := range flags {
+		results = append(results, &SubmitResult{
+			Flag:   flag,
+			Status: SubmitStatusInvalid,
+		})
+	}
+
+	// The flag can only be submitted when the game is running.
+	status, currentRound := clock.T.State()
+	if status != clock.StatusRunning {
+		for _, result := range results {
+			result.Status = SubmitStatusNotRunning
+		}
+		return results, nil, currentRound, nil
+	}
+
+	if ratelimit.Locked(team) {
+		for _, result := range results {
+			result.Status = SubmitStatusLocked
+		}
+		return results, nil, currentRound, nil
+	}
+
+	flagSets, err := db.Flags.BatchCheck(ctx, flags)
+	if err != nil {
+		return nil, nil, 0, errors.Wrap(err, "check flags")
+	}
+	if invalid := len(flags) - countFound(flags, flagSets); invalid > 0 {
+		ratelimit.AddInvalid(ctx, team, invalid)
+	}
+
+	actionOptions := make([]db.CreateActionOptions, 0, len(flags))
+	actionResults := make([]*SubmitResult, 0, len(flags))
+	for _, result := range results {
+		flag, ok := flagSets[result.Flag]
+		if !ok {
+			continue
+		}
+		if flag.TeamID == team.ID {
+			result.Status = SubmitStatusOwnFlag
+			continue
+		}
+		if Expired(flag.Round, currentRound) {
+			result.Status = SubmitStatusExpired
+			continue
+		}
+
+		// The action belongs to the round of the flag, so the flag can't be submitted repeatedly in the later rounds.
+		// The attack score is added to the attacker's game box by the scoring strategy.
+		actionOptions = append(actionOptions, db.CreateActionOptions{
+			Type:           db.ActionTypeBeenAttack,
+			GameBoxID:      flag.GameBoxID,
+			AttackerTeamID: team.ID,
+			Round:          flag.Round,
+		})
+		actionResults = append(actionResults, result)
+	}
+	if len(actionOptions) == 0 {
+		return results, flagSets, currentRound, nil
+	}
+
+	actions, err := db.Actions.BatchCreate(ctx, actionOptions)
+	if err == db.ErrDuplicateAction {
+		// The same flag is submitted at the same time, create the actions one by one.
+		actions = make([]*db.Action, len(actionOptions))
+		for i, opts := range actionOptions {
+			actions[i], err = db.Actions.Create(ctx, opts)
+			if err != nil && err != db.ErrDuplicateAction {
+				return nil, nil, 0, errors.Wrap(err, "create action")
+			}
+		}
+	} else if err != nil {
+		return nil, nil, 0, errors.Wrap(err, "batch create actions")
+	}
+
+	createdActions := make([]*db.Action, 0, len(actions))
+	for i, action := range actions {
+		if action == nil {
+			actionResults[i].Status = SubmitStatusDuplicate
+		} else {
+			actionResults[i].Status = SubmitStatusAccepted
+			createdActions = append(createdActions, action)
+		}
+	}
+
+	// The submission is not failed if the first blood can't be announced.
+	if err := announceFirstBloods(ctx, team, createdActions); err != nil {
+		log.Error("Failed to announce first bloods: %v", err)
+	}
+	return results, flagSets, currentRound, nil
+}
+
+// announceFirstBloods announces the newly created been attacked actions which are recorded as the first bloods
+// through the bulletin, the live log and the webhook. The bonus is awarded when the score is calculated.
+func announceFirstBloods(ctx context.Context, team *db.Team, actions []*db.Action) error {
+	for _, action := range actions {
+		if !action.FirstBlood {
+			continue
+		}
+
+		challenge, err := db.Challenges.GetByID(ctx, action.ChallengeID)
+		if err != nil {
+			return errors.Wrap(err, "get challenge")
+		}
+
+		if _, err := db.Bulletins.Create(ctx, db.CreateBulletinOptions{
+			Title: locales.T("bulletin.first_blood_title"),
+			Body: locales.T("bulletin.first_blood_body", map[string]interface{}{
+				"teamName":  team.Name,
+				"challenge": challenge.Title,
+				"round":     action.Round,
+			}),
+		}); err != nil {
+			return errors.Wrap(err, "create bulletin")
+		}
+
+		go webhook.Add(webhook.FIRST_BLOOD_HOOK, map[string]interface{}{"team": team.ID, "challenge": challenge.ID, "round": action.Round})
+
+		_ = livelog.Stream.Write(livelog.GlobalStream, livelog.NewLine("first_blood", map[string]interface{}{
+			"Team":      team.Name,
+			"Challenge": challenge.Title,
+		}))
+	}
+	return nil
+}
+
+// countFound returns the number of the flags which exist.
+func countFound(flags []string, flagSets map[string]*db.Flag) int {
+	var count int
+	for _, flag := range flags {
+		if _, ok := flagSets[flag]; ok {
+			count++
+		}
+	}
+	return count
+}
@@ -7,16 +7,24 @@ package form
 type NewChallenge struct {
 	Title            string  `validate:"required,lt=255"`
 	BaseScore        float64 `validate:"required,gte=0,lte=10000"`
+	AttackScore      float64 `validate:"gte=0,lte=10000"`
+	CheckDownScore   float64 `validate:"gte=0,lte=10000"`
 	AutoRenewFlag    bool
 	RenewFlagCommand string
+	Checker          string
+	CheckerConfig    string
 }
 
 type UpdateChallenge struct {
 	ID               uint    `validate:"required"`
 	Title            string  `validate:"required,lt=255"`
 	BaseScore        float64 `validate:"required,gte=0,lte=10000"`
+	AttackScore      float64 `validate:"gte=0,lte=10000"`
+	CheckDownScore   float64 `validate:"gte=0,lte=10000"`
 	AutoRenewFlag    bool
 	RenewFlagCommand string
+	Checker          string
+	CheckerConfig    string
 }
 
 type SetChallengeVisible struct {
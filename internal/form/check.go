@@ -0,0 +1,16 @@
+// Copyright 2021 E99p1ant. All rights reserved.
+// Use of this source code is governed by an AGPL-style
+// license that can be found in the LICENSE file.
+
+package form
+
+type CheckDown struct {
+	GameBoxes []CheckDownGameBox `validate:"required,min=1,max=1000,dive"`
+}
+
+type CheckDownGameBox struct {
+	ID uint `validate:"required"`
+	// PublicMessage is shown to the team owning the game box, PrivateMessage is only shown to the managers.
+	PublicMessage  string `validate:"max=255"`
+	PrivateMessage string
+}
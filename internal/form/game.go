@@ -0,0 +1,22 @@
+// Copyright 2021 E99p1ant. All rights reserved.
+// Use of this source code is governed by an AGPL-style
+// license that can be found in the LICENSE file.
+
+package form
+
+type ResumeGame struct {
+	Extend bool
+}
+
+type ExtendGame struct {
+	EndAt int64 `validate:"required"`
+}
+
+type NewRestTime struct {
+	StartAt int64 `validate:"required"`
+	EndAt   int64 `validate:"required,gtfield=StartAt"`
+}
+
+type RecalculateScore struct {
+	DryRun bool
+}
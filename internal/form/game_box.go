@@ -13,6 +13,7 @@ type NewGameBox []struct {
 	InternalSSHPort     uint
 	InternalSSHUser     string
 	InternalSSHPassword string
+	InternalSSHHostKey  string
 }
 
 type UpdateGameBox struct {
@@ -23,4 +24,15 @@ type UpdateGameBox struct {
 	InternalSSHPort     uint
 	InternalSSHUser     string
 	InternalSSHPassword string
+	InternalSSHHostKey  string
+}
+
+type SSHTestGameBox struct {
+	// ID is the game box to be tested, all the game boxes are tested if it is zero.
+	ID uint
+}
+
+type RefreshGameBoxFlag struct {
+	// ID is the game box to be refreshed, all the game boxes which renew the flag automatically are refreshed if it is zero.
+	ID uint
 }
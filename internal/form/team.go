@@ -23,3 +23,7 @@ type UpdateTeam struct {
 type SubmitFlag struct {
 	Flag string `validate:"required"`
 }
+
+type SubmitFlags struct {
+	Flags []string `validate:"required,max=1000"`
+}
@@ -15,6 +15,7 @@ import (
 	"github.com/vidar-team/Cardinal/internal/logger"
 	"github.com/vidar-team/Cardinal/internal/store"
 	"github.com/vidar-team/Cardinal/internal/utils"
+	log "unknwon.dev/clog/v2"
 )
 
 const (
@@ -22,6 +23,7 @@ const (
 	NEW_ROUND_HOOK   string = "new_round"
 	SUBMIT_FLAG_HOOK string = "submit_flag"
 	CHECK_DOWN_HOOK  string = "check_down"
+	FIRST_BLOOD_HOOK string = "first_blood"
 	BEGIN_HOOK       string = "game_begin"
 	PAUSE_HOOK       string = "game_pause"
 	END_HOOK         string = "game_end"
@@ -53,7 +55,7 @@ func NewWebHook(c *gin.Context) (int, interface{}) {
 
 	// Check type
 	switch inputForm.Type {
-	case ANY_HOOK, NEW_ROUND_HOOK, SUBMIT_FLAG_HOOK, CHECK_DOWN_HOOK, BEGIN_HOOK, PAUSE_HOOK, END_HOOK:
+	case ANY_HOOK, NEW_ROUND_HOOK, SUBMIT_FLAG_HOOK, CHECK_DOWN_HOOK, FIRST_BLOOD_HOOK, BEGIN_HOOK, PAUSE_HOOK, END_HOOK:
 	default:
 		return utils.MakeErrJSON(400, 40035,
 			locales.I18n.T(c.GetString("lang"), "webhook.error_type"),
@@ -113,7 +115,7 @@ func EditWebHook(c *gin.Context) (int, interface{}) {
 
 	// Check type
 	switch inputForm.Type {
-	case ANY_HOOK, NEW_ROUND_HOOK, SUBMIT_FLAG_HOOK, CHECK_DOWN_HOOK, BEGIN_HOOK, PAUSE_HOOK, END_HOOK:
+	case ANY_HOOK, NEW_ROUND_HOOK, SUBMIT_FLAG_HOOK, CHECK_DOWN_HOOK, FIRST_BLOOD_HOOK, BEGIN_HOOK, PAUSE_HOOK, END_HOOK:
 	default:
 		return utils.MakeErrJSON(400, 40035,
 			locales.I18n.T(c.GetString("lang"), "webhook.error_type"),
@@ -192,7 +194,8 @@ func Add(webHookType string, webHookData interface{}) {
 func sendWebHook(webHookType string, webHookData interface{}) {
 	webHookStore, ok := store.Get("webHook")
 	if !ok {
-		logger.New(logger.IMPORTANT, "webhook_cache", "WebHook 缓存获取失败！")
+		// The webhooks have not been loaded into the cache.
+		log.Warn("WebHook store not found, skip sending %q webhook.", webHookType)
 		return
 	}
 	webHooks, ok := webHookStore.([]dbold.WebHook)
@@ -49,7 +49,16 @@ func Title() []string {
 }
 
 // SetTitle saves the visible challenges' headers into cache.
+// The headers are not changed when the ranking list for teams is frozen.
 func SetTitle(ctx context.Context) error {
+	frozen, err := IsFrozen(ctx)
+	if err != nil {
+		return errors.Wrap(err, "check frozen")
+	}
+	if frozen {
+		return loadFrozen(ctx)
+	}
+
 	titles, err := db.Ranks.VisibleChallengeTitle(ctx)
 	if err != nil {
 		return errors.Wrap(err, "get visible challenge title")
@@ -59,6 +68,7 @@ func SetTitle(ctx context.Context) error {
 }
 
 // SetRankList calculates the ranking list for teams and managers.
+// The ranking list for teams is not changed when it is frozen.
 func SetRankList(ctx context.Context) error {
 	rankList, err := db.Ranks.List(ctx)
 	if err != nil {
@@ -66,13 +76,34 @@ func SetRankList(ctx context.Context) error {
 	}
 	store.Set(CacheKeyRankForManager, rankList)
 
+	frozen, err := IsFrozen(ctx)
+	if err != nil {
+		return errors.Wrap(err, "check frozen")
+	}
+	if frozen {
+		return loadFrozen(ctx)
+	}
+
 	// Team accounts can't get the score of the game boxes.
+	// The items are copied to keep the game box scores in the ranking list for managers.
+	teamRankList := make([]*db.RankItem, 0, len(rankList))
 	for _, rankItem := range rankList {
+		teamRankItem := *rankItem
+		teamRankItem.GameBoxes = make(db.GameBoxInfoList, 0, len(rankItem.GameBoxes))
 		for _, gameBox := range rankItem.GameBoxes {
-			gameBox.Score = 0
+			teamGameBox := *gameBox
+			teamGameBox.Score = 0
+			teamRankItem.GameBoxes = append(teamRankItem.GameBoxes, &teamGameBox)
 		}
+		teamRankList = append(teamRankList, &teamRankItem)
 	}
-	store.Set(CacheKeyRankForTeam, rankList)
+	store.Set(CacheKeyRankForTeam, teamRankList)
 
+	// Persist the latest ranking list for teams before it is frozen.
+	if _, ok := FreezeAt(); ok {
+		if err := saveFrozen(ctx); err != nil {
+			return errors.Wrap(err, "save frozen rank")
+		}
+	}
 	return nil
 }
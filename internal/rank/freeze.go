@@ -0,0 +1,162 @@
+// Copyright 2021 E99p1ant. All rights reserved.
+// Use of this source code is governed by an AGPL-style
+// license that can be found in the LICENSE file.
+
+package rank
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/vidar-team/Cardinal/internal/conf"
+	"github.com/vidar-team/Cardinal/internal/db"
+	"github.com/vidar-team/Cardinal/internal/store"
+	"github.com/vidar-team/Cardinal/internal/timeutil"
+)
+
+const (
+	settingKeyRevealed = "rank.revealed"
+	settingKeyFrozen   = "rank.frozen"
+)
+
+// frozenRank is the ranking list for teams persisted before it is frozen,
+// so it can be restored after Cardinal restarted.
+type frozenRank struct {
+	Title []string
+	Rank  []*db.RankItem
+}
+
+// FreezeAt returns the time when the ranking list for teams is frozen.
+// It returns false if the freeze time is not configured.
+func FreezeAt() (time.Time, bool) {
+	if conf.Game.FreezeAt == nil {
+		return time.Time{}, false
+	}
+	return conf.Game.FreezeAt.In(time.Local), true
+}
+
+var (
+	revealedMu     sync.RWMutex
+	revealedLoaded bool
+	// revealedFreezeAt is the freeze time of the ranking list which was revealed, the ranking list
+	// is frozen again if the freeze time is changed in the configuration.
+	revealedFreezeAt string
+)
+
+// Init loads the revealed state of the ranking list into memory.
+func Init(ctx context.Context) error {
+	value, err := db.Settings.Get(ctx, settingKeyRevealed)
+	if err != nil && err != db.ErrSettingNotExists {
+		return errors.Wrap(err, "get revealed setting")
+	}
+	setRevealed(value)
+	return nil
+}
+
+func setRevealed(freezeAt string) {
+	revealedMu.Lock()
+	defer revealedMu.Unlock()
+	revealedLoaded = true
+	revealedFreezeAt = freezeAt
+}
+
+// isRevealed returns whether the ranking list frozen at the given time has been revealed.
+func isRevealed(ctx context.Context, freezeAt time.Time) (bool, error) {
+	revealedMu.RLock()
+	loaded := revealedLoaded
+	revealedMu.RUnlock()
+	if !loaded {
+		if err := Init(ctx); err != nil {
+			return false, err
+		}
+	}
+
+	revealedMu.RLock()
+	defer revealedMu.RUnlock()
+	return revealedFreezeAt == freezeAt.Format(time.RFC3339), nil
+}
+
+// IsFrozen returns whether the ranking list for teams is frozen now.
+func IsFrozen(ctx context.Context) (bool, error) {
+	freezeAt, ok := FreezeAt()
+	if !ok || timeutil.Now().Before(freezeAt) {
+		return false, nil
+	}
+
+	revealed, err := isRevealed(ctx, freezeAt)
+	if err != nil {
+		return false, err
+	}
+	return !revealed, nil
+}
+
+var ErrNotFrozen = errors.New("rank list is not frozen")
+
+// Reveal unfreezes the ranking list for teams, and refreshes it with the latest scores.
+// It returns ErrNotFrozen if the ranking list is not frozen now.
+func Reveal(ctx context.Context) error {
+	frozen, err := IsFrozen(ctx)
+	if err != nil {
+		return errors.Wrap(err, "check frozen")
+	}
+	if !frozen {
+		return ErrNotFrozen
+	}
+
+	freezeAt, _ := FreezeAt()
+	value := freezeAt.Format(time.RFC3339)
+	if err := db.Settings.Set(ctx, settingKeyRevealed, value); err != nil {
+		return errors.Wrap(err, "set revealed setting")
+	}
+	setRevealed(value)
+
+	if err := SetTitle(ctx); err != nil {
+		return errors.Wrap(err, "set title")
+	}
+	if err := SetRankList(ctx); err != nil {
+		return errors.Wrap(err, "set rank list")
+	}
+	return nil
+}
+
+// saveFrozen persists the ranking list for teams in cache,
+// it will be served when the ranking list is frozen.
+func saveFrozen(ctx context.Context) error {
+	frozen, err := json.Marshal(frozenRank{
+		Title: Title(),
+		Rank:  ForTeam(),
+	})
+	if err != nil {
+		return errors.Wrap(err, "marshal")
+	}
+	return db.Settings.Set(ctx, settingKeyFrozen, string(frozen))
+}
+
+// loadFrozen loads the persisted ranking list for teams into cache if it is not cached.
+func loadFrozen(ctx context.Context) error {
+	_, titleCached := store.Get(CacheKeyRankTitle)
+	_, rankCached := store.Get(CacheKeyRankForTeam)
+	if titleCached && rankCached {
+		return nil
+	}
+
+	value, err := db.Settings.Get(ctx, settingKeyFrozen)
+	if err == db.ErrSettingNotExists {
+		// The ranking list was never refreshed before it is frozen.
+		return nil
+	} else if err != nil {
+		return errors.Wrap(err, "get frozen setting")
+	}
+
+	var frozen frozenRank
+	if err := json.Unmarshal([]byte(value), &frozen); err != nil {
+		return errors.Wrap(err, "unmarshal")
+	}
+	store.Set(CacheKeyRankTitle, frozen.Title)
+	store.Set(CacheKeyRankForTeam, frozen.Rank)
+	return nil
+}
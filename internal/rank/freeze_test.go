@@ -0,0 +1,173 @@
+// Copyright 2021 E99p1ant. All rights reserved.
+// Use of this source code is governed by an AGPL-style
+// license that can be found in the LICENSE file.
+
+package rank
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pelletier/go-toml"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/vidar-team/Cardinal/internal/conf"
+	"github.com/vidar-team/Cardinal/internal/db"
+	"github.com/vidar-team/Cardinal/internal/store"
+	"github.com/vidar-team/Cardinal/internal/timeutil"
+)
+
+// memorySettings is the in-memory db.SettingsStore.
+type memorySettings struct {
+	sync.Mutex
+	values map[string]string
+}
+
+func (s *memorySettings) Get(_ context.Context, key string) (string, error) {
+	s.Lock()
+	defer s.Unlock()
+	value, ok := s.values[key]
+	if !ok {
+		return "", db.ErrSettingNotExists
+	}
+	return value, nil
+}
+
+func (s *memorySettings) Set(_ context.Context, key, value string) error {
+	s.Lock()
+	defer s.Unlock()
+	s.values[key] = value
+	return nil
+}
+
+func (s *memorySettings) DeleteAll(context.Context) error {
+	s.Lock()
+	defer s.Unlock()
+	s.values = make(map[string]string)
+	return nil
+}
+
+// staticRanks is the db.RanksStore returning the given ranking list.
+type staticRanks struct {
+	title []string
+	list  []*db.RankItem
+}
+
+func (r *staticRanks) List(context.Context) ([]*db.RankItem, error) {
+	return r.list, nil
+}
+
+func (r *staticRanks) VisibleChallengeTitle(context.Context) ([]string, error) {
+	return r.title, nil
+}
+
+func newRankList(scores ...float64) []*db.RankItem {
+	list := make([]*db.RankItem, 0, len(scores))
+	for i, score := range scores {
+		list = append(list, &db.RankItem{
+			TeamID: uint(i + 1),
+			Rank:   uint(i + 1),
+			Score:  score,
+			GameBoxes: db.GameBoxInfoList{
+				{ChallengeID: 1, Score: score},
+			},
+		})
+	}
+	return list
+}
+
+// restart drops the ranking list in cache and the revealed state in memory.
+func restart(ctx context.Context, t *testing.T) {
+	store.Init()
+	err := Init(ctx)
+	assert.Nil(t, err)
+}
+
+func TestFreeze(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2021, 10, 3, 11, 0, 0, 0, time.Local)
+	freezeAt := toml.LocalDateTimeOf(now.Add(time.Hour))
+
+	clock := timeutil.NewFakeClock(now)
+	defer timeutil.SetClock(clock)()
+
+	originalSettings, originalRanks, originalFreezeAt := db.Settings, db.Ranks, conf.Game.FreezeAt
+	t.Cleanup(func() {
+		db.Settings, db.Ranks, conf.Game.FreezeAt = originalSettings, originalRanks, originalFreezeAt
+		setRevealed("")
+	})
+	settings := &memorySettings{values: make(map[string]string)}
+	ranks := &staticRanks{title: []string{"Web1"}, list: newRankList(1000, 900)}
+	db.Settings, db.Ranks, conf.Game.FreezeAt = settings, ranks, &freezeAt
+	restart(ctx, t)
+
+	refresh := func() {
+		err := SetTitle(ctx)
+		assert.Nil(t, err)
+		err = SetRankList(ctx)
+		assert.Nil(t, err)
+	}
+
+	// The ranking list is not frozen before the freeze time, and it can't be revealed.
+	refresh()
+	frozen, err := IsFrozen(ctx)
+	assert.Nil(t, err)
+	assert.False(t, frozen)
+	assert.Equal(t, float64(1000), ForTeam()[0].Score)
+	// The game box scores are only shown to the managers.
+	assert.Equal(t, float64(0), ForTeam()[0].GameBoxes[0].Score)
+	assert.Equal(t, float64(1000), ForManager()[0].GameBoxes[0].Score)
+
+	err = Reveal(ctx)
+	assert.Equal(t, ErrNotFrozen, err)
+	_, err = settings.Get(ctx, settingKeyRevealed)
+	assert.Equal(t, db.ErrSettingNotExists, err)
+
+	// The ranking list for teams is frozen after the freeze time, the one for managers is still updated.
+	clock.Set(now.Add(2 * time.Hour))
+	ranks.title = []string{"Web1", "Pwn1"}
+	ranks.list = newRankList(800, 1200)
+	refresh()
+	frozen, err = IsFrozen(ctx)
+	assert.Nil(t, err)
+	assert.True(t, frozen)
+	assert.Equal(t, []string{"Web1"}, Title())
+	assert.Equal(t, float64(1000), ForTeam()[0].Score)
+	assert.Equal(t, float64(800), ForManager()[0].Score)
+
+	// The frozen ranking list is loaded after restarted.
+	restart(ctx, t)
+	assert.Empty(t, ForTeam())
+	refresh()
+	assert.Equal(t, []string{"Web1"}, Title())
+	assert.Len(t, ForTeam(), 2)
+	assert.Equal(t, float64(1000), ForTeam()[0].Score)
+	assert.Equal(t, float64(900), ForTeam()[1].Score)
+	assert.Equal(t, float64(800), ForManager()[0].Score)
+
+	// The latest ranking list is shown to teams after revealed, and it is kept after restarted.
+	err = Reveal(ctx)
+	assert.Nil(t, err)
+	frozen, err = IsFrozen(ctx)
+	assert.Nil(t, err)
+	assert.False(t, frozen)
+	assert.Equal(t, []string{"Web1", "Pwn1"}, Title())
+	assert.Equal(t, float64(800), ForTeam()[0].Score)
+	assert.Equal(t, float64(0), ForTeam()[0].GameBoxes[0].Score)
+
+	restart(ctx, t)
+	frozen, err = IsFrozen(ctx)
+	assert.Nil(t, err)
+	assert.False(t, frozen)
+
+	err = Reveal(ctx)
+	assert.Equal(t, ErrNotFrozen, err)
+
+	// The ranking list is frozen again if the freeze time is changed.
+	freezeAt = toml.LocalDateTimeOf(now.Add(90 * time.Minute))
+	frozen, err = IsFrozen(ctx)
+	assert.Nil(t, err)
+	assert.True(t, frozen)
+}
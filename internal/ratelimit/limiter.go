@@ -0,0 +1,131 @@
+// Copyright 2021 E99p1ant. All rights reserved.
+// Use of this source code is governed by an AGPL-style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+
+	"github.com/vidar-team/Cardinal/internal/timeutil"
+)
+
+// Options contains the options of the limiter.
+type Options struct {
+	// Rate is the number of the tokens added to the bucket of each team per second.
+	// The team is not limited if it is zero.
+	Rate float64
+	// Burst is the size of the bucket, it is the rate rounded up by default.
+	Burst int
+
+	// LockoutThreshold is the number of the invalid flags in the window to lock out the team.
+	// The team is never locked out if it is zero.
+	LockoutThreshold int
+	// LockoutWindow is the duration in which the invalid flags are counted.
+	LockoutWindow time.Duration
+	// LockoutDuration is the duration of the lockout.
+	LockoutDuration time.Duration
+}
+
+// Limiter limits the frequency of the requests of each team with the token bucket,
+// and locks out the team which submits too many invalid flags.
+type Limiter struct {
+	options Options
+
+	mu          sync.Mutex
+	buckets     map[uint]*rate.Limiter
+	invalids    map[uint][]time.Time
+	lockedUntil map[uint]time.Time
+}
+
+// New returns a new limiter with the given options.
+func New(opts Options) *Limiter {
+	if opts.Burst <= 0 {
+		opts.Burst = int(math.Ceil(opts.Rate))
+	}
+
+	return &Limiter{
+		options:     opts,
+		buckets:     make(map[uint]*rate.Limiter),
+		invalids:    make(map[uint][]time.Time),
+		lockedUntil: make(map[uint]time.Time),
+	}
+}
+
+// Allow takes a token from the bucket of the team, it returns false if the bucket is empty.
+func (l *Limiter) Allow(teamID uint) bool {
+	return l.AllowN(teamID, 1)
+}
+
+// AllowN takes n tokens from the bucket of the team, it returns false if there are not enough tokens.
+// No token is taken if it returns false.
+func (l *Limiter) AllowN(teamID uint, n int) bool {
+	if l.options.Rate <= 0 {
+		return true
+	}
+
+	l.mu.Lock()
+	bucket, ok := l.buckets[teamID]
+	if !ok {
+		bucket = rate.NewLimiter(rate.Limit(l.options.Rate), l.options.Burst)
+		l.buckets[teamID] = bucket
+	}
+	l.mu.Unlock()
+
+	return bucket.AllowN(timeutil.Now(), n)
+}
+
+// LockedUntil returns the end time of the lockout if the team is locked out.
+func (l *Limiter) LockedUntil(teamID uint) (time.Time, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	until, ok := l.lockedUntil[teamID]
+	if !ok {
+		return time.Time{}, false
+	}
+	if !timeutil.Now().Before(until) {
+		delete(l.lockedUntil, teamID)
+		return time.Time{}, false
+	}
+	return until, true
+}
+
+// AddInvalid records the invalid flags submitted by the team.
+// It returns true if the team is locked out because of these flags.
+func (l *Limiter) AddInvalid(teamID uint, count int) bool {
+	if l.options.LockoutThreshold <= 0 || count <= 0 {
+		return false
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := timeutil.Now()
+	windowStart := now.Add(-l.options.LockoutWindow)
+
+	// Drop the invalid flags out of the window.
+	invalids := l.invalids[teamID]
+	i := 0
+	for i < len(invalids) && !invalids[i].After(windowStart) {
+		i++
+	}
+	invalids = invalids[i:]
+
+	for j := 0; j < count; j++ {
+		invalids = append(invalids, now)
+	}
+
+	if len(invalids) < l.options.LockoutThreshold {
+		l.invalids[teamID] = invalids
+		return false
+	}
+
+	delete(l.invalids, teamID)
+	l.lockedUntil[teamID] = now.Add(l.options.LockoutDuration)
+	return true
+}
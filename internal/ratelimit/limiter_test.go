@@ -0,0 +1,101 @@
+// Copyright 2021 E99p1ant. All rights reserved.
+// Use of this source code is governed by an AGPL-style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/vidar-team/Cardinal/internal/timeutil"
+)
+
+func TestLimiter_Allow(t *testing.T) {
+	clock := timeutil.NewFakeClock(time.Date(2021, 10, 1, 8, 0, 0, 0, time.UTC))
+	t.Cleanup(timeutil.SetClock(clock))
+
+	t.Run("unlimited", func(t *testing.T) {
+		limiter := New(Options{})
+		for i := 0; i < 100; i++ {
+			assert.True(t, limiter.Allow(1))
+		}
+	})
+
+	t.Run("token bucket", func(t *testing.T) {
+		limiter := New(Options{Rate: 2, Burst: 3})
+		for i := 0; i < 3; i++ {
+			assert.True(t, limiter.Allow(1))
+		}
+		assert.False(t, limiter.Allow(1))
+
+		// The buckets of the teams are separated.
+		assert.True(t, limiter.Allow(2))
+
+		// Two tokens are added in a second.
+		clock.Advance(time.Second)
+		assert.True(t, limiter.Allow(1))
+		assert.True(t, limiter.Allow(1))
+		assert.False(t, limiter.Allow(1))
+	})
+
+	t.Run("batch", func(t *testing.T) {
+		limiter := New(Options{Rate: 2, Burst: 3})
+		assert.True(t, limiter.AllowN(1, 2))
+
+		// No token is taken if there are not enough tokens.
+		assert.False(t, limiter.AllowN(1, 2))
+		assert.True(t, limiter.Allow(1))
+		assert.False(t, limiter.Allow(1))
+
+		// The batch larger than the bucket is never allowed.
+		clock.Advance(time.Minute)
+		assert.False(t, limiter.AllowN(1, 4))
+		assert.True(t, limiter.AllowN(1, 3))
+	})
+}
+
+func TestLimiter_Lockout(t *testing.T) {
+	clock := timeutil.NewFakeClock(time.Date(2021, 10, 1, 8, 0, 0, 0, time.UTC))
+	t.Cleanup(timeutil.SetClock(clock))
+
+	t.Run("disabled", func(t *testing.T) {
+		limiter := New(Options{})
+		assert.False(t, limiter.AddInvalid(1, 100))
+		_, locked := limiter.LockedUntil(1)
+		assert.False(t, locked)
+	})
+
+	t.Run("lockout", func(t *testing.T) {
+		limiter := New(Options{
+			LockoutThreshold: 5,
+			LockoutWindow:    time.Minute,
+			LockoutDuration:  5 * time.Minute,
+		})
+
+		assert.False(t, limiter.AddInvalid(1, 3))
+		// The invalid flags out of the window are not counted.
+		clock.Advance(time.Minute)
+		assert.False(t, limiter.AddInvalid(1, 3))
+		_, locked := limiter.LockedUntil(1)
+		assert.False(t, locked)
+
+		assert.True(t, limiter.AddInvalid(1, 2))
+		until, locked := limiter.LockedUntil(1)
+		assert.True(t, locked)
+		assert.Equal(t, clock.Now().Add(5*time.Minute), until)
+
+		// The other teams are not locked out.
+		_, locked = limiter.LockedUntil(2)
+		assert.False(t, locked)
+
+		clock.Advance(5 * time.Minute)
+		_, locked = limiter.LockedUntil(1)
+		assert.False(t, locked)
+
+		// The count is reset after the lockout.
+		assert.False(t, limiter.AddInvalid(1, 1))
+	})
+}
@@ -0,0 +1,109 @@
+// Copyright 2021 E99p1ant. All rights reserved.
+// Use of this source code is governed by an AGPL-style
+// license that can be found in the LICENSE file.
+
+// Package ratelimit limits the flag submission of the teams, the rejections are logged for the managers.
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	log "unknwon.dev/clog/v2"
+
+	"github.com/vidar-team/Cardinal/internal/conf"
+	"github.com/vidar-team/Cardinal/internal/db"
+	"github.com/vidar-team/Cardinal/internal/locales"
+	"github.com/vidar-team/Cardinal/internal/timeutil"
+)
+
+// logInterval is the min interval of logging the rate limited requests of a team,
+// so the database won't be flooded by the logs.
+const logInterval = time.Minute
+
+var (
+	mu             sync.RWMutex
+	defaultLimiter = New(Options{})
+
+	loggedMu sync.Mutex
+	loggedAt = make(map[uint]time.Time)
+)
+
+// Init sets up the default limiter with the configuration.
+func Init() {
+	mu.Lock()
+	defer mu.Unlock()
+
+	defaultLimiter = New(Options{
+		Rate:             conf.Game.FlagSubmitRate,
+		Burst:            conf.Game.FlagSubmitBurst,
+		LockoutThreshold: conf.Game.FlagLockoutThreshold,
+		LockoutWindow:    time.Duration(conf.Game.FlagLockoutWindow) * time.Second,
+		LockoutDuration:  time.Duration(conf.Game.FlagLockoutDuration) * time.Second,
+	})
+}
+
+func limiter() *Limiter {
+	mu.RLock()
+	defer mu.RUnlock()
+	return defaultLimiter
+}
+
+// Allow takes a token from the bucket of the team, it returns false if the request of the team should be rejected.
+// The rejection is logged at most once a minute for each team.
+func Allow(ctx context.Context, team *db.Team) bool {
+	return AllowN(ctx, team, 1)
+}
+
+// AllowN takes n tokens from the bucket of the team for the n flags submitted at once,
+// it returns false if the submission should be rejected.
+func AllowN(ctx context.Context, team *db.Team, n int) bool {
+	if limiter().AllowN(team.ID, n) {
+		return true
+	}
+
+	loggedMu.Lock()
+	now := timeutil.Now()
+	shouldLog := now.Sub(loggedAt[team.ID]) >= logInterval
+	if shouldLog {
+		loggedAt[team.ID] = now
+	}
+	loggedMu.Unlock()
+
+	if shouldLog {
+		createLog(ctx, db.LogLevelWarning, locales.T("log.flag_rate_limited", map[string]interface{}{
+			"teamName": team.Name,
+		}))
+	}
+	return false
+}
+
+// Locked returns whether the team is locked out.
+func Locked(team *db.Team) bool {
+	_, locked := limiter().LockedUntil(team.ID)
+	return locked
+}
+
+// AddInvalid records the invalid flags submitted by the team, the lockout of the team is logged.
+func AddInvalid(ctx context.Context, team *db.Team, count int) {
+	if !limiter().AddInvalid(team.ID, count) {
+		return
+	}
+
+	until, _ := limiter().LockedUntil(team.ID)
+	createLog(ctx, db.LogLevelImportant, locales.T("log.flag_locked", map[string]interface{}{
+		"teamName": team.Name,
+		"until":    until.Format("2006-01-02 15:04:05"),
+	}))
+}
+
+func createLog(ctx context.Context, level db.LogLevel, body string) {
+	if err := db.Logs.Create(ctx, db.CreateLogOptions{
+		Level: level,
+		Type:  db.LogTypeFlagSubmission,
+		Body:  body,
+	}); err != nil {
+		log.Error("Failed to create flag submission log: %v", err)
+	}
+}
@@ -0,0 +1,110 @@
+// Copyright 2021 E99p1ant. All rights reserved.
+// Use of this source code is governed by an AGPL-style
+// license that can be found in the LICENSE file.
+
+package route
+
+import (
+	log "unknwon.dev/clog/v2"
+
+	"github.com/vidar-team/Cardinal/internal/clock"
+	"github.com/vidar-team/Cardinal/internal/conf"
+	"github.com/vidar-team/Cardinal/internal/context"
+	"github.com/vidar-team/Cardinal/internal/db"
+	"github.com/vidar-team/Cardinal/internal/i18n"
+)
+
+type AttackHandler struct{}
+
+func NewAttackHandler() *AttackHandler {
+	return &AttackHandler{}
+}
+
+// Matrix returns the attacker x victim matrix of the given round, it is cumulative if the round is not given.
+func (*AttackHandler) Matrix(ctx context.Context) error {
+	round := ctx.QueryInt("round")
+	challengeID := ctx.QueryInt("challengeID")
+
+	matrix, err := db.Attacks.Matrix(ctx.Request().Context(), db.GetAttackStatsOptions{
+		Round:       uint(round),
+		ChallengeID: uint(challengeID),
+	})
+	if err != nil {
+		log.Error("Failed to get attack matrix: %v", err)
+		return ctx.ServerError()
+	}
+	return ctx.Success(matrix)
+}
+
+// FirstBloods returns the first attack of each challenge.
+func (*AttackHandler) FirstBloods(ctx context.Context) error {
+	firstBloods, err := db.Attacks.FirstBloods(ctx.Request().Context(), db.GetAttackStatsOptions{})
+	if err != nil {
+		log.Error("Failed to get first bloods: %v", err)
+		return ctx.ServerError()
+	}
+	return ctx.Success(firstBloods)
+}
+
+// Stats returns the most attacked teams and the most effective attackers.
+func (*AttackHandler) Stats(ctx context.Context) error {
+	opts := db.GetAttackStatsOptions{
+		Round:       uint(ctx.QueryInt("round")),
+		ChallengeID: uint(ctx.QueryInt("challengeID")),
+		Limit:       ctx.QueryInt("limit"),
+	}
+
+	mostAttacked, err := db.Attacks.MostAttacked(ctx.Request().Context(), opts)
+	if err != nil {
+		log.Error("Failed to get most attacked teams: %v", err)
+		return ctx.ServerError()
+	}
+	mostEffectiveAttackers, err := db.Attacks.MostEffectiveAttackers(ctx.Request().Context(), opts)
+	if err != nil {
+		log.Error("Failed to get most effective attackers: %v", err)
+		return ctx.ServerError()
+	}
+
+	return ctx.Success(map[string]interface{}{
+		"MostAttacked":           mostAttacked,
+		"MostEffectiveAttackers": mostEffectiveAttackers,
+	})
+}
+
+// TeamMatrix returns the cumulative attack matrix and the first bloods for teams.
+// Only the finished rounds before the last AttackMatrixDelay rounds are counted,
+// so the teams can't learn the attacks of the current round.
+func (*AttackHandler) TeamMatrix(ctx context.Context, l *i18n.Locale) error {
+	if !conf.Game.TeamAttackMatrix {
+		return ctx.Error(40300, l.T("attack.matrix_not_public"))
+	}
+
+	var round uint
+	if finished := clock.T.FinishedRound(); finished > conf.Game.AttackMatrixDelay {
+		round = finished - conf.Game.AttackMatrixDelay
+	}
+	if round == 0 {
+		return ctx.Success(map[string]interface{}{
+			"Round":       0,
+			"Matrix":      []*db.AttackMatrixItem{},
+			"FirstBloods": []*db.Action{},
+		})
+	}
+
+	matrix, err := db.Attacks.Matrix(ctx.Request().Context(), db.GetAttackStatsOptions{MaxRound: round})
+	if err != nil {
+		log.Error("Failed to get attack matrix: %v", err)
+		return ctx.ServerError()
+	}
+	firstBloods, err := db.Attacks.FirstBloods(ctx.Request().Context(), db.GetAttackStatsOptions{MaxRound: round})
+	if err != nil {
+		log.Error("Failed to get first bloods: %v", err)
+		return ctx.ServerError()
+	}
+
+	return ctx.Success(map[string]interface{}{
+		"Round":       round,
+		"Matrix":      matrix,
+		"FirstBloods": firstBloods,
+	})
+}
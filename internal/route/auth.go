@@ -98,6 +98,26 @@ func (*AuthHandler) ManagerAuthenticator(ctx context.Context, session session.Se
 	return nil
 }
 
+// CheckAccountTokenAuthenticator authenticates the check account with the token in the query.
+func (*AuthHandler) CheckAccountTokenAuthenticator(ctx context.Context) error {
+	token := ctx.Query("token")
+	manager, err := db.Managers.GetByToken(ctx.Request().Context(), token)
+	if err != nil {
+		if err == db.ErrManagerNotExists {
+			return ctx.Error(40300, "")
+		}
+
+		log.Error("Failed to get manager by token: %v", err)
+		return ctx.ServerError()
+	}
+	if !manager.IsCheckAccount {
+		return ctx.Error(40300, "")
+	}
+
+	ctx.Map(manager)
+	return nil
+}
+
 func (*AuthHandler) ManagerLogin(ctx context.Context, session session.Session, f form.ManagerLogin) error {
 	manager, err := db.Managers.Authenticate(ctx.Request().Context(), f.Name, f.Password)
 	if err == db.ErrBadCredentials {
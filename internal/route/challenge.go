@@ -29,8 +29,12 @@ func (*ChallengeHandler) List(ctx context.Context) error {
 		Title            string    `json:"Title"`
 		Visible          bool      `json:"Visible"`
 		BaseScore        float64   `json:"BaseScore"`
+		AttackScore      float64   `json:"AttackScore"`
+		CheckDownScore   float64   `json:"CheckDownScore"`
 		AutoRenewFlag    bool      `json:"AutoRenewFlag"`
 		RenewFlagCommand string    `json:"RenewFlagCommand"`
+		Checker          string    `json:"Checker"`
+		CheckerConfig    string    `json:"CheckerConfig"`
 	}
 
 	challenges, err := db.Challenges.Get(ctx.Request().Context())
@@ -61,8 +65,12 @@ func (*ChallengeHandler) List(ctx context.Context) error {
 			Title:            c.Title,
 			Visible:          challengeVisible,
 			BaseScore:        c.BaseScore,
+			AttackScore:      c.AttackScore,
+			CheckDownScore:   c.CheckDownScore,
 			AutoRenewFlag:    c.AutoRenewFlag,
 			RenewFlagCommand: c.RenewFlagCommand,
+			Checker:          c.Checker,
+			CheckerConfig:    c.CheckerConfig,
 		})
 	}
 
@@ -74,8 +82,12 @@ func (*ChallengeHandler) New(ctx context.Context, f form.NewChallenge, l *i18n.L
 	_, err := db.Challenges.Create(ctx.Request().Context(), db.CreateChallengeOptions{
 		Title:            f.Title,
 		BaseScore:        f.BaseScore,
+		AttackScore:      f.AttackScore,
+		CheckDownScore:   f.CheckDownScore,
 		AutoRenewFlag:    f.AutoRenewFlag,
 		RenewFlagCommand: f.RenewFlagCommand,
+		Checker:          f.Checker,
+		CheckerConfig:    f.CheckerConfig,
 	})
 	if err != nil {
 		if err == db.ErrChallengeAlreadyExists {
@@ -105,8 +117,12 @@ func (*ChallengeHandler) Update(ctx context.Context, f form.UpdateChallenge, l *
 	err = db.Challenges.Update(ctx.Request().Context(), f.ID, db.UpdateChallengeOptions{
 		Title:            f.Title,
 		BaseScore:        f.BaseScore,
+		AttackScore:      f.AttackScore,
+		CheckDownScore:   f.CheckDownScore,
 		AutoRenewFlag:    f.AutoRenewFlag,
 		RenewFlagCommand: f.RenewFlagCommand,
+		Checker:          f.Checker,
+		CheckerConfig:    f.CheckerConfig,
 	})
 	if err != nil {
 		log.Error("Failed to update challenge: %v", err)
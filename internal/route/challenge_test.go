@@ -96,6 +96,8 @@ func testListChallenges(t *testing.T, router *flamego.Flame, managerToken string
             "Visible": false,
             "BaseScore": 1000,
             "AutoRenewFlag": true,
+            "Checker": "",
+            "CheckerConfig": "",
             "RenewFlagCommand": "echo \"d3ctf{sh0whub_f1ag}\" > /flag",
             "ID": 1,
             "Title": "ShowHub"
@@ -105,6 +107,8 @@ func testListChallenges(t *testing.T, router *flamego.Flame, managerToken string
             "Visible": false,
             "BaseScore": 1000,
             "AutoRenewFlag": false,
+            "Checker": "",
+            "CheckerConfig": "",
             "RenewFlagCommand": "",
             "ID": 2
         }
@@ -202,7 +206,7 @@ func testUpdateChallenge(t *testing.T, router *flamego.Flame, managerToken strin
 	assert.JSONEq(t, `{"error": 40400, "msg":"Challenge Not Found!"}`, w.Body.String())
 
 	// Update challenge.
-	req, err = http.NewRequest(http.MethodPut, "/api/manager/challenge", strings.NewReader(`{"ID": 1, "Title": "ShowHub_Revenge", "BaseScore": 1500}`))
+	req, err = http.NewRequest(http.MethodPut, "/api/manager/challenge", strings.NewReader(`{"ID": 1, "Title": "ShowHub_Revenge", "BaseScore": 1500, "AttackScore": 100, "CheckDownScore": 20}`))
 	assert.Nil(t, err)
 	req.Header.Set("Authorization", managerToken)
 	w = httptest.NewRecorder()
@@ -226,7 +230,11 @@ func testUpdateChallenge(t *testing.T, router *flamego.Flame, managerToken strin
             "Title": "ShowHub_Revenge",
             "Visible": false,
             "BaseScore": 1500,
+            "AttackScore": 100,
+            "CheckDownScore": 20,
             "AutoRenewFlag": false,
+            "Checker": "",
+            "CheckerConfig": "",
             "RenewFlagCommand": ""
         },
         {
@@ -234,6 +242,8 @@ func testUpdateChallenge(t *testing.T, router *flamego.Flame, managerToken strin
             "Visible": false,
             "BaseScore": 1000,
             "AutoRenewFlag": false,
+            "Checker": "",
+            "CheckerConfig": "",
             "RenewFlagCommand": "",
             "ID": 2
         }
@@ -317,6 +327,8 @@ func testDeleteChallenge(t *testing.T, router *flamego.Flame, managerToken strin
             "Visible": false,
             "BaseScore": 1000,
             "AutoRenewFlag": false,
+            "Checker": "",
+            "CheckerConfig": "",
             "RenewFlagCommand": "",
             "ID": 2
         }
@@ -412,6 +424,8 @@ func testSetChallengeVisible(t *testing.T, router *flamego.Flame, managerToken s
             "BaseScore": 1000,
 			"Visible": true,
             "AutoRenewFlag": true,
+            "Checker": "",
+            "CheckerConfig": "",
             "RenewFlagCommand": "echo \"d3ctf{sh0whub_f1ag}\" > /flag"
         },
         {
@@ -420,6 +434,8 @@ func testSetChallengeVisible(t *testing.T, router *flamego.Flame, managerToken s
             "Visible": false,
             "BaseScore": 1500,
             "AutoRenewFlag": false,
+            "Checker": "",
+            "CheckerConfig": "",
             "RenewFlagCommand": ""
         }
     ],
@@ -454,6 +470,8 @@ func testSetChallengeVisible(t *testing.T, router *flamego.Flame, managerToken s
             "BaseScore": 1000,
 			"Visible": true,
             "AutoRenewFlag": true,
+            "Checker": "",
+            "CheckerConfig": "",
             "RenewFlagCommand": "echo \"d3ctf{sh0whub_f1ag}\" > /flag"
         },
         {
@@ -462,6 +480,8 @@ func testSetChallengeVisible(t *testing.T, router *flamego.Flame, managerToken s
             "Visible": true,
             "BaseScore": 1500,
             "AutoRenewFlag": false,
+            "Checker": "",
+            "CheckerConfig": "",
             "RenewFlagCommand": ""
         }
     ],
@@ -0,0 +1,238 @@
+// Copyright 2021 E99p1ant. All rights reserved.
+// Use of this source code is governed by an AGPL-style
+// license that can be found in the LICENSE file.
+
+package route
+
+import (
+	"time"
+
+	log "unknwon.dev/clog/v2"
+
+	"github.com/vidar-team/Cardinal/internal/checker"
+	"github.com/vidar-team/Cardinal/internal/clock"
+	"github.com/vidar-team/Cardinal/internal/context"
+	"github.com/vidar-team/Cardinal/internal/db"
+	"github.com/vidar-team/Cardinal/internal/form"
+	"github.com/vidar-team/Cardinal/internal/i18n"
+	"github.com/vidar-team/Cardinal/internal/rank"
+)
+
+type CheckHandler struct{}
+
+func NewCheckHandler() *CheckHandler {
+	return &CheckHandler{}
+}
+
+// CheckDownStatus is the result status of the reported game box.
+type CheckDownStatus string
+
+const (
+	CheckDownStatusAccepted   CheckDownStatus = "accepted"
+	CheckDownStatusDuplicate  CheckDownStatus = "duplicate"
+	CheckDownStatusNotFound   CheckDownStatus = "not_found"
+	CheckDownStatusNotVisible CheckDownStatus = "not_visible"
+)
+
+// CheckDown checks down the game boxes reported by the check account in the current round,
+// and returns the status of each game box.
+func (*CheckHandler) CheckDown(ctx context.Context, f form.CheckDown, l *i18n.Locale) error {
+	// The game box can only be checked down when the game is running.
+	status, round := clock.T.State()
+	if status != clock.StatusRunning {
+		return ctx.Error(40300, l.T("general.not_begin"))
+	}
+
+	type result struct {
+		GameBoxID uint            `json:"GameBoxID"`
+		Status    CheckDownStatus `json:"Status"`
+	}
+
+	var checkedDown bool
+	results := make([]*result, 0, len(f.GameBoxes))
+	for _, report := range f.GameBoxes {
+		r := &result{GameBoxID: report.ID}
+		results = append(results, r)
+
+		gameBox, err := db.GameBoxes.GetByID(ctx.Request().Context(), report.ID)
+		if err != nil {
+			if err == db.ErrGameBoxNotExists {
+				r.Status = CheckDownStatusNotFound
+				continue
+			}
+			log.Error("Failed to get game box: %v", err)
+			return ctx.ServerError()
+		}
+		if !gameBox.Visible {
+			r.Status = CheckDownStatusNotVisible
+			continue
+		}
+
+		ok, err := checker.Down(ctx.Request().Context(), gameBox, round)
+		if err != nil {
+			log.Error("Failed to check down game box: %v", err)
+			return ctx.ServerError()
+		}
+		if !ok {
+			r.Status = CheckDownStatusDuplicate
+			continue
+		}
+		r.Status = CheckDownStatusAccepted
+		checkedDown = true
+
+		// Record the messages of the report, so that the team knows why the game box is down.
+		if err := db.CheckResults.BatchCreate(ctx.Request().Context(), []db.CreateCheckResultOptions{
+			{
+				TeamID:         gameBox.TeamID,
+				ChallengeID:    gameBox.ChallengeID,
+				GameBoxID:      gameBox.ID,
+				Round:          round,
+				Status:         db.CheckStatusDown,
+				PublicMessage:  report.PublicMessage,
+				PrivateMessage: report.PrivateMessage,
+			},
+		}); err != nil {
+			log.Error("Failed to create check result: %v", err)
+			return ctx.ServerError()
+		}
+	}
+
+	// Update the game box status in the ranking list.
+	if checkedDown {
+		if err := rank.SetRankList(ctx.Request().Context()); err != nil {
+			log.Error("Failed to set rank list: %v", err)
+			return ctx.ServerError()
+		}
+	}
+
+	return ctx.Success(results)
+}
+
+// Results returns the check results of the game boxes with the given filters.
+func (*CheckHandler) Results(ctx context.Context) error {
+	page := ctx.QueryInt("page")
+	pageSize := ctx.QueryInt("pageSize")
+	teamID := ctx.QueryInt("teamID")
+	challengeID := ctx.QueryInt("challengeID")
+	gameBoxID := ctx.QueryInt("gameBoxID")
+	round := ctx.QueryInt("round")
+	status := ctx.Query("status")
+
+	results, totalCount, err := db.CheckResults.Get(ctx.Request().Context(), db.GetCheckResultsOptions{
+		Page:        page,
+		PageSize:    pageSize,
+		TeamID:      uint(teamID),
+		ChallengeID: uint(challengeID),
+		GameBoxID:   uint(gameBoxID),
+		Round:       uint(round),
+		Status:      db.CheckStatus(status),
+	})
+	if err != nil {
+		log.Error("Failed to get check results: %v", err)
+		return ctx.ServerError()
+	}
+
+	return ctx.Success(map[string]interface{}{
+		"List":  results,
+		"Count": totalCount,
+	})
+}
+
+// SLA returns the SLA grouped by the game box, the team or the challenge.
+func (*CheckHandler) SLA(ctx context.Context) error {
+	groupBy := ctx.Query("groupBy")
+	teamID := ctx.QueryInt("teamID")
+	challengeID := ctx.QueryInt("challengeID")
+
+	slas, err := db.CheckResults.SLA(ctx.Request().Context(), db.GetCheckSLAOptions{
+		GroupBy:     db.CheckSLAGroup(groupBy),
+		TeamID:      uint(teamID),
+		ChallengeID: uint(challengeID),
+	})
+	if err != nil {
+		log.Error("Failed to get SLA: %v", err)
+		return ctx.ServerError()
+	}
+	return ctx.Success(slas)
+}
+
+// TeamTimeline returns the round-by-round check results and the SLA of the team's visible game boxes.
+func (*CheckHandler) TeamTimeline(ctx context.Context, team *db.Team) error {
+	gameBoxes, err := db.GameBoxes.Get(ctx.Request().Context(), db.GetGameBoxesOption{
+		TeamID:  team.ID,
+		Visible: true,
+	})
+	if err != nil {
+		log.Error("Failed to get team game boxes: %v", err)
+		return ctx.ServerError()
+	}
+
+	results, _, err := db.CheckResults.Get(ctx.Request().Context(), db.GetCheckResultsOptions{
+		TeamID: team.ID,
+	})
+	if err != nil {
+		log.Error("Failed to get check results: %v", err)
+		return ctx.ServerError()
+	}
+
+	slas, err := db.CheckResults.SLA(ctx.Request().Context(), db.GetCheckSLAOptions{
+		TeamID: team.ID,
+	})
+	if err != nil {
+		log.Error("Failed to get SLA: %v", err)
+		return ctx.ServerError()
+	}
+
+	type round struct {
+		Round     uint           `json:"Round"`
+		Status    db.CheckStatus `json:"Status"`
+		Message   string         `json:"Message"`
+		Latency   int64          `json:"Latency"`
+		CheckedAt time.Time      `json:"CheckedAt"`
+	}
+	type timeline struct {
+		GameBoxID   uint     `json:"GameBoxID"`
+		ChallengeID uint     `json:"ChallengeID"`
+		Challenge   string   `json:"Challenge"`
+		SLA         float64  `json:"SLA"`
+		Rounds      []*round `json:"Rounds"`
+	}
+
+	timelines := make([]*timeline, 0, len(gameBoxes))
+	gameBoxTimelines := make(map[uint]*timeline, len(gameBoxes))
+	for _, gameBox := range gameBoxes {
+		t := &timeline{
+			GameBoxID:   gameBox.ID,
+			ChallengeID: gameBox.ChallengeID,
+			Rounds:      []*round{},
+		}
+		if gameBox.Challenge != nil {
+			t.Challenge = gameBox.Challenge.Title
+		}
+		timelines = append(timelines, t)
+		gameBoxTimelines[gameBox.ID] = t
+	}
+
+	for _, sla := range slas {
+		if t, ok := gameBoxTimelines[sla.GameBoxID]; ok {
+			t.SLA = sla.SLA
+		}
+	}
+
+	// The results are in the order of the rounds.
+	for _, result := range results {
+		t, ok := gameBoxTimelines[result.GameBoxID]
+		if !ok {
+			continue
+		}
+		t.Rounds = append(t.Rounds, &round{
+			Round:     result.Round,
+			Status:    result.Status,
+			Message:   result.PublicMessage, // The private message is only shown to the managers.
+			Latency:   result.Latency,
+			CheckedAt: result.UpdatedAt,
+		})
+	}
+
+	return ctx.Success(timelines)
+}
@@ -0,0 +1,238 @@
+// Copyright 2021 E99p1ant. All rights reserved.
+// Use of this source code is governed by an AGPL-style
+// license that can be found in the LICENSE file.
+
+package route
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Cardinal-Platform/testify/assert"
+	"github.com/flamego/flamego"
+	jsoniter "github.com/json-iterator/go"
+
+	"github.com/vidar-team/Cardinal/internal/clock"
+	"github.com/vidar-team/Cardinal/internal/db"
+	"github.com/vidar-team/Cardinal/internal/form"
+	"github.com/vidar-team/Cardinal/internal/livelog"
+	"github.com/vidar-team/Cardinal/internal/store"
+)
+
+func TestCheck(t *testing.T) {
+	router, managerToken, cleanup := NewTestRoute(t)
+
+	// The check down game box is written to the live log and the webhook.
+	store.Init()
+	livelog.Init()
+
+	// Create two teams with the game boxes of one challenge.
+	createTeam(t, managerToken, router, form.NewTeam{
+		{Name: "Vidar"},
+		{Name: "E99p1ant"},
+	})
+	createChallenge(t, managerToken, router, form.NewChallenge{
+		Title:     "Web1",
+		BaseScore: 1000,
+	})
+	ctx := context.Background()
+	for teamID := uint(1); teamID <= 2; teamID++ {
+		_, err := db.GameBoxes.Create(ctx, db.CreateGameBoxOptions{TeamID: teamID, ChallengeID: 1, IPAddress: "192.168.1.1", Port: 80})
+		assert.Nil(t, err)
+	}
+	// Only the game box of Vidar is visible.
+	err := db.GameBoxes.SetVisible(ctx, 1, true)
+	assert.Nil(t, err)
+
+	for _, tc := range []struct {
+		name string
+		test func(t *testing.T, router *flamego.Flame, managerToken string)
+	}{
+		{"CheckDown", testCheckDown},
+		{"Results", testCheckResults},
+		{"Token", testCheckAccountToken},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Cleanup(func() {
+				err := cleanup("actions", "check_results")
+				if err != nil {
+					t.Fatal(err)
+				}
+			})
+
+			tc.test(t, router, managerToken)
+		})
+	}
+}
+
+func testCheckDown(t *testing.T, router *flamego.Flame, _ string) {
+	checkDown := func(token, body string) *httptest.ResponseRecorder {
+		req, err := http.NewRequest(http.MethodPost, "/api/manager/checkDown?token="+token, strings.NewReader(body))
+		assert.Nil(t, err)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// The token is the mocked random hex string.
+	token := "mocked_randstr_hex"
+
+	// The manager is not a check account.
+	w := checkDown(token, `{"GameBoxes": [{"ID": 1}]}`)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	ctx := context.Background()
+	err := db.Managers.Update(ctx, 1, db.UpdateManagerOptions{IsCheckAccount: true})
+	assert.Nil(t, err)
+	t.Cleanup(func() {
+		_ = db.Managers.Update(ctx, 1, db.UpdateManagerOptions{IsCheckAccount: false})
+	})
+
+	// Bad token.
+	w = checkDown("bad_token", `{"GameBoxes": [{"ID": 1}]}`)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	// The game is not running.
+	w = checkDown(token, `{"GameBoxes": [{"ID": 1}]}`)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.JSONEq(t, `{"error":40300,"msg":"The Game is not ready."}`, w.Body.String())
+
+	originalClock := clock.T
+	t.Cleanup(func() {
+		clock.T = originalClock
+	})
+	now := time.Now()
+	clock.T = &clock.Clock{
+		StartAt:       now.Add(-30 * time.Minute),
+		EndAt:         now.Add(time.Hour),
+		RoundDuration: time.Hour,
+		RunTime:       [][]time.Time{{now.Add(-30 * time.Minute), now.Add(time.Hour)}},
+	}
+
+	// Empty game boxes.
+	w = checkDown(token, `{"GameBoxes": []}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// The game box ID is required.
+	w = checkDown(token, `{"GameBoxes": [{"PublicMessage": "service unavailable"}]}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = checkDown(token, `{"GameBoxes": [
+        {"ID": 1, "PublicMessage": "service unavailable", "PrivateMessage": "GET /: connection reset"},
+        {"ID": 2},
+        {"ID": 3},
+        {"ID": 1, "PublicMessage": "duplicate"}
+    ]}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	want := `{
+    "error": 0,
+    "data": [
+        {"GameBoxID": 1, "Status": "accepted"},
+        {"GameBoxID": 2, "Status": "not_visible"},
+        {"GameBoxID": 3, "Status": "not_found"},
+        {"GameBoxID": 1, "Status": "duplicate"}
+    ]
+}`
+	assert.JSONEq(t, want, w.Body.String())
+
+	gameBox, err := db.GameBoxes.GetByID(ctx, 1)
+	assert.Nil(t, err)
+	assert.True(t, gameBox.IsDown)
+
+	actions, err := db.Actions.GetByType(ctx, db.ActionTypeCheckDown, 1)
+	assert.Nil(t, err)
+	assert.Len(t, actions, 1)
+
+	results, _, err := db.CheckResults.Get(ctx, db.GetCheckResultsOptions{GameBoxID: 1})
+	assert.Nil(t, err)
+	assert.Len(t, results, 1)
+	assert.Equal(t, db.CheckStatusDown, results[0].Status)
+	assert.Equal(t, "service unavailable", results[0].PublicMessage)
+	assert.Equal(t, "GET /: connection reset", results[0].PrivateMessage)
+}
+
+func testCheckResults(t *testing.T, router *flamego.Flame, managerToken string) {
+	ctx := context.Background()
+	err := db.CheckResults.BatchCreate(ctx, []db.CreateCheckResultOptions{
+		{TeamID: 1, ChallengeID: 1, GameBoxID: 1, Round: 1, Status: db.CheckStatusOK, Latency: 20},
+		{TeamID: 1, ChallengeID: 1, GameBoxID: 1, Round: 2, Status: db.CheckStatusMumble, PublicMessage: "login failed", PrivateMessage: "POST /login: 500", Latency: 30},
+		{TeamID: 2, ChallengeID: 1, GameBoxID: 2, Round: 1, Status: db.CheckStatusError, PrivateMessage: "checker crashed", Latency: 10},
+	})
+	assert.Nil(t, err)
+
+	get := func(url string) *httptest.ResponseRecorder {
+		req, err := http.NewRequest(http.MethodGet, url, nil)
+		assert.Nil(t, err)
+		req.Header.Set("Authorization", managerToken)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get("/api/manager/checks/sla")
+	assert.Equal(t, http.StatusOK, w.Code)
+	want := `{
+    "error": 0,
+    "data": [
+        {"GameBoxID": 1, "TeamID": 1, "ChallengeID": 1, "Total": 2, "Up": 1, "SLA": 50}
+    ]
+}`
+	assert.JSONEq(t, want, w.Body.String())
+
+	w = get("/api/manager/checks/sla?groupBy=challenge")
+	assert.Equal(t, http.StatusOK, w.Code)
+	want = `{
+    "error": 0,
+    "data": [
+        {"GameBoxID": 0, "TeamID": 0, "ChallengeID": 1, "Total": 2, "Up": 1, "SLA": 50}
+    ]
+}`
+	assert.JSONEq(t, want, w.Body.String())
+
+	w = get("/api/manager/checks?gameBoxID=1&status=MUMBLE")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var resp struct {
+		Data struct {
+			List  []*db.CheckResult
+			Count int64
+		} `json:"data"`
+	}
+	err = jsoniter.NewDecoder(w.Body).Decode(&resp)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), resp.Data.Count)
+	assert.Equal(t, uint(2), resp.Data.List[0].Round)
+	assert.Equal(t, "login failed", resp.Data.List[0].PublicMessage)
+	assert.Equal(t, "POST /login: 500", resp.Data.List[0].PrivateMessage)
+}
+
+func testCheckAccountToken(t *testing.T, router *flamego.Flame, managerToken string) {
+	request := func(method, url string) *httptest.ResponseRecorder {
+		req, err := http.NewRequest(method, url, nil)
+		assert.Nil(t, err)
+		req.Header.Set("Authorization", managerToken)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// The token is the mocked random hex string.
+	w := request(http.MethodGet, "/api/manager/account/token?id=1")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"error":0,"data":"mocked_randstr_hex"}`, w.Body.String())
+
+	w = request(http.MethodPost, "/api/manager/account/token/reset?id=1")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"error":0,"data":"mocked_randstr_hex"}`, w.Body.String())
+
+	w = request(http.MethodGet, "/api/manager/account/token?id=2")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.JSONEq(t, `{"error":40400,"msg":"Admin Account Not Found!"}`, w.Body.String())
+
+	w = request(http.MethodPost, "/api/manager/account/token/reset?id=2")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.JSONEq(t, `{"error":40400,"msg":"Admin Account Not Found!"}`, w.Body.String())
+}
@@ -5,15 +5,12 @@
 package route
 
 import (
-	"fmt"
-
 	log "unknwon.dev/clog/v2"
 
 	"github.com/vidar-team/Cardinal/internal/clock"
-	"github.com/vidar-team/Cardinal/internal/conf"
 	"github.com/vidar-team/Cardinal/internal/context"
 	"github.com/vidar-team/Cardinal/internal/db"
-	"github.com/vidar-team/Cardinal/internal/utils"
+	"github.com/vidar-team/Cardinal/internal/flagutil"
 )
 
 type FlagHandler struct{}
@@ -29,6 +26,7 @@ func (*FlagHandler) Get(ctx context.Context) error {
 	challengeID := ctx.QueryInt("challengeID")
 	gameBoxID := ctx.QueryInt("gameBoxID")
 	round := ctx.QueryInt("round")
+	status := ctx.Query("status")
 
 	flags, totalCount, err := db.Flags.Get(ctx.Request().Context(), db.GetFlagOptions{
 		Page:        page,
@@ -37,6 +35,7 @@ func (*FlagHandler) Get(ctx context.Context) error {
 		ChallengeID: uint(challengeID),
 		GameBoxID:   uint(gameBoxID),
 		Round:       uint(round),
+		Status:      db.FlagStatus(status),
 	})
 	if err != nil {
 		log.Error("Failed to get flags: %v", err)
@@ -59,21 +58,21 @@ func (*FlagHandler) BatchCreate(ctx context.Context) error {
 		return ctx.ServerError()
 	}
 
-	flagPrefix := conf.Game.FlagPrefix
-	flagSuffix := conf.Game.FlagSuffix
-	salt := utils.Sha1Encode(conf.App.SecuritySalt)
 	totalRound := clock.T.TotalRound
 	log.Trace("Total Round: %d", totalRound)
 
 	flagMetadatas := make([]db.FlagMetadata, 0, int(totalRound)*len(gameBoxes))
 	for round := uint(1); round <= totalRound; round++ {
-		// Flag = FlagPrefix + hmacSha1(TeamID + | + GameBoxID + | + Round, sha1(salt)) + FlagSuffix
 		for _, gameBox := range gameBoxes {
-			flag := flagPrefix + utils.HmacSha1Encode(fmt.Sprintf("%d|%d|%d", gameBox.TeamID, gameBox.ID, round), salt) + flagSuffix
+			value, err := flagutil.Generate(gameBox.TeamID, gameBox.ID, round)
+			if err != nil {
+				log.Error("Failed to generate flag: %v", err)
+				return ctx.ServerError()
+			}
 			flagMetadatas = append(flagMetadatas, db.FlagMetadata{
 				GameBoxID: gameBox.ID,
 				Round:     round,
-				Value:     flag,
+				Value:     value,
 			})
 		}
 	}
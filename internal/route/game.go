@@ -0,0 +1,130 @@
+// Copyright 2021 E99p1ant. All rights reserved.
+// Use of this source code is governed by an AGPL-style
+// license that can be found in the LICENSE file.
+
+package route
+
+import (
+	"time"
+
+	log "unknwon.dev/clog/v2"
+
+	"github.com/vidar-team/Cardinal/internal/clock"
+	"github.com/vidar-team/Cardinal/internal/context"
+	"github.com/vidar-team/Cardinal/internal/db"
+	"github.com/vidar-team/Cardinal/internal/form"
+	"github.com/vidar-team/Cardinal/internal/i18n"
+	"github.com/vidar-team/Cardinal/internal/rank"
+)
+
+// GameHandler is the game clock request handler.
+type GameHandler struct{}
+
+// NewGameHandler creates and returns a new game Handler.
+func NewGameHandler() *GameHandler {
+	return &GameHandler{}
+}
+
+// Rounds returns the timeline of the rounds which have been started.
+func (*GameHandler) Rounds(ctx context.Context) error {
+	rounds, err := db.Rounds.Get(ctx.Request().Context())
+	if err != nil {
+		log.Error("Failed to get rounds: %v", err)
+		return ctx.ServerError()
+	}
+
+	return ctx.Success(rounds)
+}
+
+// Pause pauses the game until it is resumed.
+func (*GameHandler) Pause(ctx context.Context, l *i18n.Locale) error {
+	return saveClock(ctx, l, clock.T.Pause)
+}
+
+// Resume resumes the paused game.
+func (*GameHandler) Resume(ctx context.Context, f form.ResumeGame, l *i18n.Locale) error {
+	return saveClock(ctx, l, func() (clock.Snapshot, error) {
+		return clock.T.Resume(f.Extend)
+	})
+}
+
+// ExtendEndAt postpones the end time of the game.
+func (*GameHandler) ExtendEndAt(ctx context.Context, f form.ExtendGame, l *i18n.Locale) error {
+	return saveClock(ctx, l, func() (clock.Snapshot, error) {
+		return clock.T.ExtendEndAt(time.Unix(f.EndAt, 0))
+	})
+}
+
+// AddRestTime inserts a rest time period into the game.
+func (*GameHandler) AddRestTime(ctx context.Context, f form.NewRestTime, l *i18n.Locale) error {
+	return saveClock(ctx, l, func() (clock.Snapshot, error) {
+		return clock.T.AddRestTime(time.Unix(f.StartAt, 0), time.Unix(f.EndAt, 0))
+	})
+}
+
+// RecalculateScore recalculates the scores of all the finished rounds,
+// and returns the team scores before and after the recalculation.
+func (*GameHandler) RecalculateScore(ctx context.Context, f form.RecalculateScore) error {
+	round := clock.T.FinishedRound()
+	teams, err := db.Scores.Recalculate(ctx.Request().Context(), db.RecalculateScoreOptions{
+		Round:  round,
+		DryRun: f.DryRun,
+	})
+	if err != nil {
+		log.Error("Failed to recalculate scores: %v", err)
+		return ctx.ServerError()
+	}
+
+	if !f.DryRun {
+		if err := rank.SetRankList(ctx.Request().Context()); err != nil {
+			log.Error("Failed to refresh rank list: %v", err)
+			return ctx.ServerError()
+		}
+	}
+
+	return ctx.Success(map[string]interface{}{
+		"Round":  round,
+		"DryRun": f.DryRun,
+		"Teams":  teams,
+	})
+}
+
+// saveClock changes the game clock and persists it if it has been changed successfully,
+// otherwise it responses the error of the change. The change is rolled back if it fails to be persisted.
+func saveClock(ctx context.Context, l *i18n.Locale, change func() (clock.Snapshot, error)) error {
+	previous := clock.T.Snapshot()
+	snapshot, err := change()
+	switch err {
+	case nil:
+	case clock.ErrGameNotRunning:
+		return ctx.Error(40000, l.T("timer.not_running"))
+	case clock.ErrGamePaused:
+		return ctx.Error(40000, l.T("timer.paused"))
+	case clock.ErrGameNotPaused:
+		return ctx.Error(40000, l.T("timer.not_paused"))
+	case clock.ErrGameEnded:
+		return ctx.Error(40000, l.T("timer.end"))
+	case clock.ErrEndTimeOrder:
+		return ctx.Error(40000, l.T("timer.end_at_error"))
+	case clock.ErrRestTimeInPast:
+		return ctx.Error(40000, l.T("timer.rest_time_past_error"))
+	default:
+		// The other errors are from the time configuration check.
+		log.Warn("Failed to change the game clock: %v", err)
+		return ctx.Error(40000, l.T("timer.rest_time_error"))
+	}
+
+	if err := clock.T.Save(ctx.Request().Context(), snapshot); err != nil {
+		log.Error("Failed to save the game clock: %v", err)
+		if err := clock.T.Rollback(previous); err != nil {
+			log.Error("Failed to roll back the game clock: %v", err)
+		}
+		return ctx.ServerError()
+	}
+
+	return ctx.Success(map[string]interface{}{
+		"EndAt":      snapshot.EndAt.Unix(),
+		"TotalRound": snapshot.TotalRound,
+		"Status":     snapshot.Status,
+	})
+}
@@ -7,10 +7,14 @@ package route
 import (
 	log "unknwon.dev/clog/v2"
 
+	"github.com/vidar-team/Cardinal/internal/clock"
+	"github.com/vidar-team/Cardinal/internal/conf"
 	"github.com/vidar-team/Cardinal/internal/context"
 	"github.com/vidar-team/Cardinal/internal/db"
+	"github.com/vidar-team/Cardinal/internal/flagutil"
 	"github.com/vidar-team/Cardinal/internal/form"
 	"github.com/vidar-team/Cardinal/internal/i18n"
+	"github.com/vidar-team/Cardinal/internal/sshexec"
 )
 
 // GameBoxHandler is the game box request handler.
@@ -59,6 +63,7 @@ func (*GameBoxHandler) New(ctx context.Context, f form.NewGameBox, l *i18n.Local
 				Port:     option.InternalSSHPort,
 				User:     option.InternalSSHUser,
 				Password: option.InternalSSHPassword,
+				HostKey:  option.InternalSSHHostKey,
 			},
 		})
 	}
@@ -86,6 +91,7 @@ func (*GameBoxHandler) Update(ctx context.Context, f form.UpdateGameBox, l *i18n
 			Port:     f.InternalSSHPort,
 			User:     f.InternalSSHUser,
 			Password: f.InternalSSHPassword,
+			HostKey:  f.InternalSSHHostKey,
 		},
 	})
 	if err == db.ErrGameBoxNotExists {
@@ -111,16 +117,145 @@ func (*GameBoxHandler) ResetAll(ctx context.Context) error {
 	return nil
 }
 
+// loadGameBoxes returns the game box with the given id, or all the game boxes if the id is zero.
+func loadGameBoxes(ctx context.Context, id uint) ([]*db.GameBox, error) {
+	if id == 0 {
+		return db.GameBoxes.Get(ctx.Request().Context(), db.GetGameBoxesOption{})
+	}
+
+	gameBox, err := db.GameBoxes.GetByID(ctx.Request().Context(), id)
+	if err != nil {
+		return nil, err
+	}
+	return []*db.GameBox{gameBox}, nil
+}
+
 // SSHTest tests the game box SSH configuration,
 // which try to connect to the game box instance within SSH.
-func (*GameBoxHandler) SSHTest(ctx context.Context) error {
-	// TODO
-	return nil
+func (*GameBoxHandler) SSHTest(ctx context.Context, f form.SSHTestGameBox, l *i18n.Locale) error {
+	gameBoxes, err := loadGameBoxes(ctx, f.ID)
+	if err != nil {
+		if err == db.ErrGameBoxNotExists {
+			return ctx.Error(40400, l.T("gamebox.not_found"))
+		}
+		log.Error("Failed to get game boxes: %v", err)
+		return ctx.ServerError()
+	}
+
+	tasks := make([]sshexec.Task, 0, len(gameBoxes))
+	for _, gameBox := range gameBoxes {
+		config, err := flagutil.SSHConfig(gameBox)
+		if err != nil {
+			log.Error("Failed to get SSH config of game box %d: %v", gameBox.ID, err)
+			return ctx.ServerError()
+		}
+		tasks = append(tasks, sshexec.Task{
+			Config:  config,
+			Command: "true",
+		})
+	}
+
+	concurrency := conf.Game.FlagPushConcurrency
+	if concurrency <= 0 {
+		concurrency = flagutil.DefaultPushConcurrency
+	}
+	results := sshexec.RunAll(ctx.Request().Context(), tasks, concurrency)
+
+	type testResult struct {
+		GameBoxID   uint   `json:"GameBoxID"`
+		TeamID      uint   `json:"TeamID"`
+		ChallengeID uint   `json:"ChallengeID"`
+		Success     bool   `json:"Success"`
+		Message     string `json:"Message"`
+		Latency     int64  `json:"Latency"` // In milliseconds.
+	}
+
+	testResults := make([]*testResult, 0, len(results))
+	for i, result := range results {
+		gameBox := gameBoxes[i]
+		var message string
+		if result.Err != nil {
+			message = result.Err.Error()
+		}
+		testResults = append(testResults, &testResult{
+			GameBoxID:   gameBox.ID,
+			TeamID:      gameBox.TeamID,
+			ChallengeID: gameBox.ChallengeID,
+			Success:     result.Err == nil,
+			Message:     message,
+			Latency:     result.Latency.Milliseconds(),
+		})
+	}
+	return ctx.Success(testResults)
 }
 
 // RefreshFlag refreshes the game box flag if the `RenewFlagCommand` was set in challenge.
-// It will connect to the game box instance and run the command to refresh the flag.
-func (*GameBoxHandler) RefreshFlag(ctx context.Context) error {
-	// TODO
-	return nil
+// It will connect to the game box instance and run the command to refresh the flag of the current round.
+func (*GameBoxHandler) RefreshFlag(ctx context.Context, f form.RefreshGameBoxFlag, l *i18n.Locale) error {
+	// The flag can't be scored after the game ends.
+	status, round := clock.T.State()
+	switch status {
+	case clock.StatusWait:
+		return ctx.Error(40000, l.T("general.not_begin"))
+	case clock.StatusEnd:
+		return ctx.Error(40000, l.T("timer.end"))
+	}
+
+	pusher, err := flagutil.GetPusher(conf.Game.FlagPusher)
+	if err != nil {
+		log.Error("Failed to get flag pusher %q: %v", conf.Game.FlagPusher, err)
+		return ctx.ServerError()
+	}
+
+	gameBoxes, err := loadGameBoxes(ctx, f.ID)
+	if err != nil {
+		if err == db.ErrGameBoxNotExists {
+			return ctx.Error(40400, l.T("gamebox.not_found"))
+		}
+		log.Error("Failed to get game boxes: %v", err)
+		return ctx.ServerError()
+	}
+
+	flags, _, err := db.Flags.Get(ctx.Request().Context(), db.GetFlagOptions{
+		GameBoxID: f.ID,
+		Round:     round,
+	})
+	if err != nil {
+		log.Error("Failed to get flags: %v", err)
+		return ctx.ServerError()
+	}
+	gameBoxFlags := make(map[uint]*db.Flag, len(flags))
+	for _, flag := range flags {
+		gameBoxFlags[flag.GameBoxID] = flag
+	}
+
+	targets := make([]flagutil.PushTarget, 0, len(gameBoxes))
+	for _, gameBox := range gameBoxes {
+		if gameBox.Challenge == nil || gameBox.Challenge.RenewFlagCommand == "" {
+			if f.ID != 0 {
+				return ctx.Error(40000, l.T("challenge.empty_command"))
+			}
+			continue
+		}
+		if f.ID == 0 && !gameBox.Challenge.AutoRenewFlag {
+			continue
+		}
+
+		flag, ok := gameBoxFlags[gameBox.ID]
+		if !ok {
+			log.Warn("Flag of round %d for game box %d does not exist", round, gameBox.ID)
+			continue
+		}
+		targets = append(targets, flagutil.PushTarget{
+			GameBox: gameBox,
+			Flag:    flag,
+		})
+	}
+
+	results, err := flagutil.PushAll(ctx.Request().Context(), pusher, targets)
+	if err != nil {
+		log.Error("Failed to push flags: %v", err)
+		return ctx.ServerError()
+	}
+	return ctx.Success(results)
 }
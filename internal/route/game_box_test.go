@@ -11,11 +11,13 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/Cardinal-Platform/testify/assert"
 	"github.com/flamego/flamego"
 	jsoniter "github.com/json-iterator/go"
 
+	"github.com/vidar-team/Cardinal/internal/clock"
 	"github.com/vidar-team/Cardinal/internal/form"
 )
 
@@ -144,6 +146,8 @@ func testListGameBoxes(t *testing.T, router *flamego.Flame, managerToken string)
                     "AutoRenewFlag": true,
                     "BaseScore": 1000,
                     "ID": 1,
+                    "Checker": "",
+                    "CheckerConfig": "",
                     "RenewFlagCommand": "echo {{FLAG}} \u003e /flag",
                     "Title": "Web1"
                 },
@@ -174,6 +178,8 @@ func testListGameBoxes(t *testing.T, router *flamego.Flame, managerToken string)
                     "AutoRenewFlag": false,
                     "BaseScore": 1500,
                     "ID": 2,
+                    "Checker": "",
+                    "CheckerConfig": "",
                     "RenewFlagCommand": "",
                     "Title": "Web2"
                 },
@@ -204,6 +210,8 @@ func testListGameBoxes(t *testing.T, router *flamego.Flame, managerToken string)
                     "AutoRenewFlag": true,
                     "BaseScore": 1000,
                     "ID": 1,
+                    "Checker": "",
+                    "CheckerConfig": "",
                     "RenewFlagCommand": "echo {{FLAG}} \u003e /flag",
                     "Title": "Web1"
                 },
@@ -234,6 +242,8 @@ func testListGameBoxes(t *testing.T, router *flamego.Flame, managerToken string)
                     "AutoRenewFlag": false,
                     "BaseScore": 1500,
                     "ID": 2,
+                    "Checker": "",
+                    "CheckerConfig": "",
                     "RenewFlagCommand": "",
                     "Title": "Web2"
                 },
@@ -301,6 +311,8 @@ func testNewGameBox(t *testing.T, router *flamego.Flame, managerToken string) {
                     "AutoRenewFlag": true,
                     "BaseScore": 1000,
                     "ID": 1,
+                    "Checker": "",
+                    "CheckerConfig": "",
                     "RenewFlagCommand": "echo {{FLAG}} \u003e /flag",
                     "Title": "Web1"
                 },
@@ -360,6 +372,8 @@ func testNewGameBox(t *testing.T, router *flamego.Flame, managerToken string) {
                     "AutoRenewFlag": true,
                     "BaseScore": 1000,
                     "ID": 1,
+                    "Checker": "",
+                    "CheckerConfig": "",
                     "RenewFlagCommand": "echo {{FLAG}} \u003e /flag",
                     "Title": "Web1"
                 },
@@ -430,6 +444,8 @@ func testNewGameBox(t *testing.T, router *flamego.Flame, managerToken string) {
                     "AutoRenewFlag": true,
                     "BaseScore": 1000,
                     "ID": 1,
+                    "Checker": "",
+                    "CheckerConfig": "",
                     "RenewFlagCommand": "echo {{FLAG}} \u003e /flag",
                     "Title": "Web1"
                 },
@@ -460,6 +476,8 @@ func testNewGameBox(t *testing.T, router *flamego.Flame, managerToken string) {
                     "AutoRenewFlag": false,
                     "BaseScore": 1500,
                     "ID": 2,
+                    "Checker": "",
+                    "CheckerConfig": "",
                     "RenewFlagCommand": "",
                     "Title": "Web2"
                 },
@@ -490,6 +508,8 @@ func testNewGameBox(t *testing.T, router *flamego.Flame, managerToken string) {
                     "AutoRenewFlag": false,
                     "BaseScore": 1500,
                     "ID": 2,
+                    "Checker": "",
+                    "CheckerConfig": "",
                     "RenewFlagCommand": "",
                     "Title": "Web2"
                 },
@@ -582,6 +602,8 @@ func testUpdateGameBox(t *testing.T, router *flamego.Flame, managerToken string)
                     "AutoRenewFlag": true,
                     "BaseScore": 1000,
                     "ID": 1,
+                    "Checker": "",
+                    "CheckerConfig": "",
                     "RenewFlagCommand": "echo {{FLAG}} \u003e /flag",
                     "Title": "Web1"
                 },
@@ -637,6 +659,8 @@ func testUpdateGameBox(t *testing.T, router *flamego.Flame, managerToken string)
                     "AutoRenewFlag": true,
                     "BaseScore": 1000,
                     "ID": 1,
+                    "Checker": "",
+                    "CheckerConfig": "",
                     "RenewFlagCommand": "echo {{FLAG}} \u003e /flag",
                     "Title": "Web1"
                 },
@@ -694,7 +718,43 @@ func testSSHTestGameBox(t *testing.T, router *flamego.Flame, managerToken string
 }
 
 func testRefreshFlagGameBox(t *testing.T, router *flamego.Flame, managerToken string) {
+	originalClock := clock.T
+	t.Cleanup(func() {
+		clock.T = originalClock
+	})
+
+	refreshFlag := func() *httptest.ResponseRecorder {
+		req, err := http.NewRequest(http.MethodPost, "/api/manager/gameBox/refreshFlag", strings.NewReader(`{"ID": 1}`))
+		assert.Nil(t, err)
+		req.Header.Set("Authorization", managerToken)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
 
+	// The game is not started.
+	now := time.Now()
+	clock.T = &clock.Clock{
+		StartAt:       now.Add(time.Hour),
+		EndAt:         now.Add(2 * time.Hour),
+		RoundDuration: time.Hour,
+		RunTime:       [][]time.Time{{now.Add(time.Hour), now.Add(2 * time.Hour)}},
+	}
+	w := refreshFlag()
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.JSONEq(t, `{"error":40000,"msg":"The Game is not ready."}`, w.Body.String())
+
+	// The flags of the ended game can't be scored.
+	clock.T = &clock.Clock{
+		StartAt:       now.Add(-2 * time.Hour),
+		EndAt:         now.Add(-time.Hour),
+		RoundDuration: time.Hour,
+		RunTime:       [][]time.Time{{now.Add(-2 * time.Hour), now.Add(-time.Hour)}},
+		TotalRound:    1,
+	}
+	w = refreshFlag()
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.JSONEq(t, `{"error":40000,"msg":"The Game is Over."}`, w.Body.String())
 }
 
 func createGameBox(t *testing.T, managerToken string, router *flamego.Flame, f form.NewGameBox) {
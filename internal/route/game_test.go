@@ -0,0 +1,92 @@
+// Copyright 2021 E99p1ant. All rights reserved.
+// Use of this source code is governed by an AGPL-style
+// license that can be found in the LICENSE file.
+
+package route
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Cardinal-Platform/testify/assert"
+	"github.com/flamego/flamego"
+
+	"github.com/vidar-team/Cardinal/internal/db"
+)
+
+func TestGame(t *testing.T) {
+	router, managerToken, cleanup := NewTestRoute(t)
+
+	for _, tc := range []struct {
+		name string
+		test func(t *testing.T, router *flamego.Flame, managerToken string)
+	}{
+		{"Rounds", testGameRounds},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Cleanup(func() {
+				err := cleanup("rounds")
+				if err != nil {
+					t.Fatal(err)
+				}
+			})
+
+			tc.test(t, router, managerToken)
+		})
+	}
+}
+
+func testGameRounds(t *testing.T, router *flamego.Flame, managerToken string) {
+	// Empty rounds.
+	req, err := http.NewRequest(http.MethodGet, "/api/manager/rounds", nil)
+	assert.Nil(t, err)
+
+	req.Header.Set("Authorization", managerToken)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"error":0,"data":[]}`, w.Body.String())
+
+	// Two rounds, the first one is finished.
+	ctx := context.Background()
+	startAt := time.Date(2021, 10, 3, 12, 0, 0, 0, time.Local)
+	err = db.Rounds.Create(ctx, db.CreateRoundOptions{Number: 1, StartedAt: startAt})
+	assert.Nil(t, err)
+	err = db.Rounds.Finish(ctx, 1, startAt.Add(time.Hour))
+	assert.Nil(t, err)
+	err = db.Rounds.SetScoreCalculated(ctx, 1)
+	assert.Nil(t, err)
+	err = db.Rounds.Create(ctx, db.CreateRoundOptions{Number: 2, StartedAt: startAt.Add(time.Hour)})
+	assert.Nil(t, err)
+
+	req, err = http.NewRequest(http.MethodGet, "/api/manager/rounds", nil)
+	assert.Nil(t, err)
+
+	req.Header.Set("Authorization", managerToken)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	want := `{
+    "data": [
+        {
+            "ID": 1,
+            "Number": 1,
+            "Status": "finished",
+            "ScoreCalculated": true
+        },
+        {
+            "ID": 2,
+            "Number": 2,
+            "EndedAt": null,
+            "Status": "running",
+            "ScoreCalculated": false
+        }
+    ],
+    "error": 0
+}
+`
+	assert.JSONPartialEq(t, want, w.Body.String())
+}
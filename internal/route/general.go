@@ -5,11 +5,10 @@
 package route
 
 import (
-	"time"
-
 	"github.com/vidar-team/Cardinal/internal/clock"
 	"github.com/vidar-team/Cardinal/internal/conf"
 	"github.com/vidar-team/Cardinal/internal/context"
+	"github.com/vidar-team/Cardinal/internal/timeutil"
 )
 
 // GeneralHandler is the general request handler.
@@ -36,7 +35,7 @@ func (*GeneralHandler) Init(c context.Context) error {
 
 func (*GeneralHandler) Time(c context.Context) error {
 	return c.Success(map[string]interface{}{
-		"CurrentTime":         time.Now().Unix(),
+		"CurrentTime":         timeutil.Now().Unix(),
 		"StartAt":             clock.T.StartAt.Unix(),
 		"EndAt":               clock.T.EndAt.Unix(),
 		"RoundDuration":       clock.T.RoundDuration.Seconds(),
@@ -5,7 +5,11 @@
 package route
 
 import (
+	log "unknwon.dev/clog/v2"
+
 	"github.com/vidar-team/Cardinal/internal/context"
+	"github.com/vidar-team/Cardinal/internal/db"
+	"github.com/vidar-team/Cardinal/internal/i18n"
 	"github.com/vidar-team/Cardinal/internal/rank"
 )
 
@@ -28,3 +32,46 @@ func (*ManagerHandler) Logs() {
 func (*ManagerHandler) Rank(ctx context.Context) error {
 	return ctx.Success(rank.ForManager())
 }
+
+// RevealRank unfreezes the ranking list for teams.
+func (*ManagerHandler) RevealRank(ctx context.Context, l *i18n.Locale) error {
+	if err := rank.Reveal(ctx.Request().Context()); err != nil {
+		if err == rank.ErrNotFrozen {
+			return ctx.Error(40000, l.T("rank.not_frozen"))
+		}
+		log.Error("Failed to reveal rank: %v", err)
+		return ctx.ServerError()
+	}
+	return ctx.Success()
+}
+
+// Token returns the token of the manager with the given id,
+// which is used by the check account to report the check down game boxes.
+func (*ManagerHandler) Token(ctx context.Context, l *i18n.Locale) error {
+	id := uint(ctx.QueryInt("id"))
+
+	manager, err := db.Managers.GetByID(ctx.Request().Context(), id)
+	if err != nil {
+		if err == db.ErrManagerNotExists {
+			return ctx.Error(40400, l.T("manager.not_found"))
+		}
+		log.Error("Failed to get manager by ID: %v", err)
+		return ctx.ServerError()
+	}
+	return ctx.Success(manager.Token)
+}
+
+// ResetToken generates a new token for the manager with the given id, the old token can't be used any more.
+func (*ManagerHandler) ResetToken(ctx context.Context, l *i18n.Locale) error {
+	id := uint(ctx.QueryInt("id"))
+
+	token, err := db.Managers.ResetToken(ctx.Request().Context(), id)
+	if err != nil {
+		if err == db.ErrManagerNotExists {
+			return ctx.Error(40400, l.T("manager.not_found"))
+		}
+		log.Error("Failed to reset manager token: %v", err)
+		return ctx.Error(50000, l.T("manager.update_token_fail"))
+	}
+	return ctx.Success(token)
+}
@@ -41,20 +41,31 @@ func NewRouter() *flamego.Flame {
 
 	general := NewGeneralHandler()
 	auth := NewAuthHandler()
+	attack := NewAttackHandler()
 	bulletin := NewBulletinHandler()
+	check := NewCheckHandler()
 	challenge := NewChallengeHandler()
 	flag := NewFlagHandler()
 	gameBox := NewGameBoxHandler()
 	team := NewTeamHandler()
 	manager := NewManagerHandler()
+	game := NewGameHandler()
+	score := NewScoreHandler()
+	scoreboard := NewScoreboardHandler()
+	submission := NewSubmissionHandler()
 
 	f.Group("/api", func() {
 		f.Any("/", general.Hello)
 		f.Get("/init", general.Init)
 		f.Get("/time", general.Time)
 		f.Get("/asteroid")
+		f.Get("/scoreboard", scoreboard.Public)
+		f.Get("/scoreboard/ctftime", scoreboard.PublicCTFtime)
 
-		f.Post("/submitFlag", form.Bind(form.SubmitFlag{}), auth.TeamTokenAuthenticator, team.SubmitFlag)
+		f.Group("", func() {
+			f.Post("/submitFlag", form.Bind(form.SubmitFlag{}), team.SubmitFlag)
+			f.Post("/submitFlags", form.Bind(form.SubmitFlags{}), team.SubmitFlags)
+		}, auth.TeamTokenAuthenticator, team.SubmitRateLimiter)
 
 		f.Group("/team", func() {
 			f.Post("/login", form.Bind(form.TeamLogin{}), auth.TeamLogin)
@@ -68,6 +79,9 @@ func NewRouter() *flamego.Flame {
 				})
 				f.Get("/bulletins", team.Bulletins)
 				f.Get("/rank", team.Rank)
+				f.Get("/scores/history", score.TeamHistory)
+				f.Get("/checks", check.TeamTimeline)
+				f.Get("/attacks", attack.TeamMatrix)
 				f.Get("/liveLog")
 			}, auth.TeamAuthenticator)
 		})
@@ -76,10 +90,25 @@ func NewRouter() *flamego.Flame {
 			f.Post("/login", form.Bind(form.ManagerLogin{}), auth.ManagerLogin)
 			f.Get("/logout", auth.ManagerLogout)
 
+			// The check account reports the check down game boxes with its token.
+			f.Post("/checkDown", form.Bind(form.CheckDown{}), auth.CheckAccountTokenAuthenticator, check.CheckDown)
+
 			f.Group("", func() {
 				f.Get("/panel")
 				f.Get("/logs")
 				f.Get("/rank", manager.Rank)
+				f.Post("/rank/reveal", manager.RevealRank)
+				f.Get("/scoreboard/export", scoreboard.Export)
+
+				// Game
+				f.Get("/rounds", game.Rounds)
+				f.Post("/game/pause", game.Pause)
+				f.Post("/game/resume", form.Bind(form.ResumeGame{}), game.Resume)
+				f.Put("/game/endAt", form.Bind(form.ExtendGame{}), game.ExtendEndAt)
+				f.Post("/game/restTime", form.Bind(form.NewRestTime{}), game.AddRestTime)
+				f.Post("/scores/recalculate", form.Bind(form.RecalculateScore{}), game.RecalculateScore)
+				f.Get("/scores/history", score.History)
+				f.Get("/scores/rank", score.RoundRank)
 
 				// Challenge
 				f.Get("/challenges", challenge.List)
@@ -101,12 +130,22 @@ func NewRouter() *flamego.Flame {
 				f.Post("/gameBoxes", form.Bind(form.NewGameBox{}), gameBox.New)
 				f.Put("/gameBox", form.Bind(form.UpdateGameBox{}), gameBox.Update)
 				f.Delete("/gameBox", gameBox.Delete)
-				f.Post("/gameBox/sshTest")
-				f.Post("/gameBox/refreshFlag")
+				f.Post("/gameBox/sshTest", form.Bind(form.SSHTestGameBox{}), gameBox.SSHTest)
+				f.Post("/gameBox/refreshFlag", form.Bind(form.RefreshGameBoxFlag{}), gameBox.RefreshFlag)
 
 				// Flag
 				f.Get("/flags", flag.Get)
 				f.Post("/flags", flag.BatchCreate)
+				f.Get("/submissions", submission.List)
+
+				// Check
+				f.Get("/checks", check.Results)
+				f.Get("/checks/sla", check.SLA)
+
+				// Attack
+				f.Get("/attacks/matrix", attack.Matrix)
+				f.Get("/attacks/firstBloods", attack.FirstBloods)
+				f.Get("/attacks/stats", attack.Stats)
 
 				// Bulletins
 				f.Get("/bulletins", bulletin.List)
@@ -132,10 +171,9 @@ func NewRouter() *flamego.Flame {
 					f.Post("")
 					f.Put("")
 					f.Delete("")
+					f.Get("/token", manager.Token)
+					f.Post("/token/reset", manager.ResetToken)
 				})
-
-				// Check
-				f.Get("/checkDown")
 			}, auth.ManagerAuthenticator)
 		})
 	})
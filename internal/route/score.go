@@ -0,0 +1,168 @@
+// Copyright 2021 E99p1ant. All rights reserved.
+// Use of this source code is governed by an AGPL-style
+// license that can be found in the LICENSE file.
+
+package route
+
+import (
+	log "unknwon.dev/clog/v2"
+
+	"github.com/vidar-team/Cardinal/internal/context"
+	"github.com/vidar-team/Cardinal/internal/db"
+	"github.com/vidar-team/Cardinal/internal/i18n"
+	"github.com/vidar-team/Cardinal/internal/rank"
+)
+
+// ScoreHandler is the score history request handler.
+type ScoreHandler struct{}
+
+// NewScoreHandler creates and returns a new score Handler.
+func NewScoreHandler() *ScoreHandler {
+	return &ScoreHandler{}
+}
+
+type roundScore struct {
+	Round uint    `json:"Round"`
+	Score float64 `json:"Score"`
+	Rank  uint    `json:"Rank"`
+}
+
+// TeamHistory returns the score and the rank of the current team at the end of each round.
+func (*ScoreHandler) TeamHistory(ctx context.Context, team *db.Team) error {
+	snapshots, err := db.TeamRoundScores.Get(ctx.Request().Context(), db.GetTeamRoundScoresOptions{
+		TeamID: team.ID,
+	})
+	if err != nil {
+		log.Error("Failed to get team round scores: %v", err)
+		return ctx.ServerError()
+	}
+
+	// The scores of the rounds which finished after the ranking list is frozen are hidden.
+	frozen, err := rank.IsFrozen(ctx.Request().Context())
+	if err != nil {
+		log.Error("Failed to check rank frozen: %v", err)
+		return ctx.ServerError()
+	}
+	var visibleRounds map[uint]struct{}
+	if frozen {
+		rounds, err := db.Rounds.Get(ctx.Request().Context())
+		if err != nil {
+			log.Error("Failed to get rounds: %v", err)
+			return ctx.ServerError()
+		}
+
+		freezeAt, _ := rank.FreezeAt()
+		visibleRounds = make(map[uint]struct{}, len(rounds))
+		for _, round := range rounds {
+			if round.EndedAt != nil && !round.EndedAt.After(freezeAt) {
+				visibleRounds[round.Number] = struct{}{}
+			}
+		}
+	}
+
+	scores := make([]*roundScore, 0, len(snapshots))
+	for _, snapshot := range snapshots {
+		if frozen {
+			if _, ok := visibleRounds[snapshot.Round]; !ok {
+				continue
+			}
+		}
+		scores = append(scores, &roundScore{
+			Round: snapshot.Round,
+			Score: snapshot.Score,
+			Rank:  snapshot.Rank,
+		})
+	}
+	return ctx.Success(scores)
+}
+
+// History returns the score and the rank of all the teams at the end of each round.
+func (*ScoreHandler) History(ctx context.Context) error {
+	teams, err := db.Teams.Get(ctx.Request().Context(), db.GetTeamsOptions{})
+	if err != nil {
+		log.Error("Failed to get teams: %v", err)
+		return ctx.ServerError()
+	}
+
+	snapshots, err := db.TeamRoundScores.Get(ctx.Request().Context(), db.GetTeamRoundScoresOptions{})
+	if err != nil {
+		log.Error("Failed to get team round scores: %v", err)
+		return ctx.ServerError()
+	}
+
+	type teamHistory struct {
+		TeamID   uint          `json:"TeamID"`
+		TeamName string        `json:"TeamName"`
+		Scores   []*roundScore `json:"Scores"`
+	}
+
+	histories := make([]*teamHistory, 0, len(teams))
+	teamHistories := make(map[uint]*teamHistory, len(teams))
+	for _, team := range teams {
+		history := &teamHistory{
+			TeamID:   team.ID,
+			TeamName: team.Name,
+			Scores:   []*roundScore{},
+		}
+		histories = append(histories, history)
+		teamHistories[team.ID] = history
+	}
+
+	for _, snapshot := range snapshots {
+		history, ok := teamHistories[snapshot.TeamID]
+		if !ok {
+			continue
+		}
+		history.Scores = append(history.Scores, &roundScore{
+			Round: snapshot.Round,
+			Score: snapshot.Score,
+			Rank:  snapshot.Rank,
+		})
+	}
+	return ctx.Success(histories)
+}
+
+// RoundRank returns the ranking list at the end of the given round.
+func (*ScoreHandler) RoundRank(ctx context.Context, l *i18n.Locale) error {
+	round := uint(ctx.QueryInt("round"))
+
+	snapshots, err := db.TeamRoundScores.GetByRound(ctx.Request().Context(), round)
+	if err != nil {
+		log.Error("Failed to get team round scores: %v", err)
+		return ctx.ServerError()
+	}
+	if len(snapshots) == 0 {
+		return ctx.Error(40400, l.T("general.not_found"))
+	}
+
+	teams, err := db.Teams.Get(ctx.Request().Context(), db.GetTeamsOptions{})
+	if err != nil {
+		log.Error("Failed to get teams: %v", err)
+		return ctx.ServerError()
+	}
+	teamNames := make(map[uint]string, len(teams))
+	for _, team := range teams {
+		teamNames[team.ID] = team.Name
+	}
+
+	type rankItem struct {
+		TeamID   uint    `json:"TeamID"`
+		TeamName string  `json:"TeamName"`
+		Score    float64 `json:"Score"`
+		Rank     uint    `json:"Rank"`
+	}
+
+	rankList := make([]*rankItem, 0, len(snapshots))
+	for _, snapshot := range snapshots {
+		rankList = append(rankList, &rankItem{
+			TeamID:   snapshot.TeamID,
+			TeamName: teamNames[snapshot.TeamID],
+			Score:    snapshot.Score,
+			Rank:     snapshot.Rank,
+		})
+	}
+	return ctx.Success(map[string]interface{}{
+		"Round": round,
+		"Rank":  rankList,
+	})
+}
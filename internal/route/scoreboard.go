@@ -0,0 +1,137 @@
+// Copyright 2021 E99p1ant. All rights reserved.
+// Use of this source code is governed by an AGPL-style
+// license that can be found in the LICENSE file.
+
+package route
+
+import (
+	"encoding/csv"
+	"net/http"
+	"strconv"
+
+	jsoniter "github.com/json-iterator/go"
+	log "unknwon.dev/clog/v2"
+
+	"github.com/vidar-team/Cardinal/internal/conf"
+	"github.com/vidar-team/Cardinal/internal/context"
+	"github.com/vidar-team/Cardinal/internal/db"
+	"github.com/vidar-team/Cardinal/internal/i18n"
+	"github.com/vidar-team/Cardinal/internal/rank"
+)
+
+// publicScoreboardMaxAge is the seconds of the public scoreboard can be cached,
+// the ranking list is only refreshed when a round starts or ends.
+const publicScoreboardMaxAge = 30
+
+// ScoreboardHandler is the scoreboard request handler.
+type ScoreboardHandler struct{}
+
+// NewScoreboardHandler creates and returns a new scoreboard Handler.
+func NewScoreboardHandler() *ScoreboardHandler {
+	return &ScoreboardHandler{}
+}
+
+// Public returns the ranking list for teams without authentication if the public scoreboard is enabled.
+// It is the same as the ranking list for teams, so it is frozen together.
+func (*ScoreboardHandler) Public(ctx context.Context, l *i18n.Locale) error {
+	if !conf.Game.PublicScoreboard {
+		return ctx.Error(40400, l.T("general.not_found"))
+	}
+
+	ctx.ResponseWriter().Header().Set("Cache-Control", "public, max-age="+strconv.Itoa(publicScoreboardMaxAge))
+	return ctx.Success(map[string]interface{}{
+		"Title": rank.Title(),
+		"Rank":  rank.ForTeam(),
+	})
+}
+
+// PublicCTFtime returns the ranking list for teams in the CTFtime scoreboard JSON format
+// without authentication if the public scoreboard is enabled, it can be used as the CTFtime scoreboard feed.
+func (*ScoreboardHandler) PublicCTFtime(ctx context.Context, l *i18n.Locale) error {
+	if !conf.Game.PublicScoreboard {
+		return ctx.Error(40400, l.T("general.not_found"))
+	}
+
+	ctx.ResponseWriter().Header().Set("Cache-Control", "public, max-age="+strconv.Itoa(publicScoreboardMaxAge))
+	return exportCTFtime(ctx, rank.ForTeam())
+}
+
+// Export exports the latest ranking list in the given format,
+// the format can be `ctftime` (default) or `csv`.
+func (*ScoreboardHandler) Export(ctx context.Context, l *i18n.Locale) error {
+	rankList, err := db.Ranks.List(ctx.Request().Context())
+	if err != nil {
+		log.Error("Failed to get rank list: %v", err)
+		return ctx.ServerError()
+	}
+
+	switch ctx.Query("format") {
+	case "", "ctftime":
+		return exportCTFtime(ctx, rankList)
+	case "csv":
+		// The challenge titles are the source of `rank.Title()`,
+		// which is not refreshed when the ranking list for teams is frozen.
+		titles, err := db.Ranks.VisibleChallengeTitle(ctx.Request().Context())
+		if err != nil {
+			log.Error("Failed to get visible challenge title: %v", err)
+			return ctx.ServerError()
+		}
+		return exportCSV(ctx, titles, rankList)
+	default:
+		return ctx.Error(40000, l.T("general.error_query"))
+	}
+}
+
+// exportCTFtime writes the ranking list in the CTFtime scoreboard JSON format.
+// https://ctftime.org/json-scoreboard-feed
+func exportCTFtime(ctx context.Context, rankList []*db.RankItem) error {
+	type standing struct {
+		Pos   uint    `json:"pos"`
+		Team  string  `json:"team"`
+		Score float64 `json:"score"`
+	}
+
+	standings := make([]*standing, 0, len(rankList))
+	for _, rankItem := range rankList {
+		standings = append(standings, &standing{
+			Pos:   rankItem.Rank,
+			Team:  rankItem.TeamName,
+			Score: rankItem.Score,
+		})
+	}
+
+	ctx.ResponseWriter().Header().Set("Content-Type", "application/json")
+	ctx.ResponseWriter().WriteHeader(http.StatusOK)
+	if err := jsoniter.NewEncoder(ctx.ResponseWriter()).Encode(map[string]interface{}{
+		"standings": standings,
+	}); err != nil {
+		log.Error("Failed to encode: %v", err)
+	}
+	return nil
+}
+
+// exportCSV writes the ranking list in CSV format, with the score of each challenge.
+func exportCSV(ctx context.Context, titles []string, rankList []*db.RankItem) error {
+	ctx.ResponseWriter().Header().Set("Content-Type", "text/csv; charset=utf-8")
+	ctx.ResponseWriter().Header().Set("Content-Disposition", `attachment; filename="scoreboard.csv"`)
+	ctx.ResponseWriter().WriteHeader(http.StatusOK)
+
+	w := csv.NewWriter(ctx.ResponseWriter())
+	_ = w.Write(append([]string{"pos", "team", "score"}, titles...))
+	for _, rankItem := range rankList {
+		record := []string{
+			strconv.Itoa(int(rankItem.Rank)),
+			rankItem.TeamName,
+			strconv.FormatFloat(rankItem.Score, 'f', -1, 64),
+		}
+		for _, gameBox := range rankItem.GameBoxes {
+			record = append(record, strconv.FormatFloat(gameBox.Score, 'f', -1, 64))
+		}
+		_ = w.Write(record)
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		log.Error("Failed to write CSV: %v", err)
+	}
+	return nil
+}
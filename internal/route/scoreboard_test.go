@@ -0,0 +1,110 @@
+// Copyright 2021 E99p1ant. All rights reserved.
+// Use of this source code is governed by an AGPL-style
+// license that can be found in the LICENSE file.
+
+package route
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Cardinal-Platform/testify/assert"
+	"github.com/flamego/flamego"
+
+	"github.com/vidar-team/Cardinal/internal/conf"
+	"github.com/vidar-team/Cardinal/internal/db"
+	"github.com/vidar-team/Cardinal/internal/store"
+)
+
+func TestScoreboard(t *testing.T) {
+	router, managerToken, cleanup := NewTestRoute(t)
+	store.Init()
+
+	for _, tc := range []struct {
+		name string
+		test func(t *testing.T, router *flamego.Flame, managerToken string)
+	}{
+		{"Public", testScoreboardPublic},
+		{"Export", testScoreboardExport},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Cleanup(func() {
+				err := cleanup("teams")
+				if err != nil {
+					t.Fatal(err)
+				}
+			})
+
+			tc.test(t, router, managerToken)
+		})
+	}
+}
+
+func testScoreboardPublic(t *testing.T, router *flamego.Flame, _ string) {
+	// The public scoreboard is disabled.
+	conf.Game.PublicScoreboard = false
+	req, err := http.NewRequest(http.MethodGet, "/api/scoreboard", nil)
+	assert.Nil(t, err)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	req, err = http.NewRequest(http.MethodGet, "/api/scoreboard/ctftime", nil)
+	assert.Nil(t, err)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	conf.Game.PublicScoreboard = true
+	t.Cleanup(func() {
+		conf.Game.PublicScoreboard = false
+	})
+
+	req, err = http.NewRequest(http.MethodGet, "/api/scoreboard", nil)
+	assert.Nil(t, err)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "public, max-age=30", w.Header().Get("Cache-Control"))
+	assert.JSONEq(t, `{"error": 0, "data": {"Title": [], "Rank": []}}`, w.Body.String())
+
+	req, err = http.NewRequest(http.MethodGet, "/api/scoreboard/ctftime", nil)
+	assert.Nil(t, err)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"standings": []}`, w.Body.String())
+}
+
+func testScoreboardExport(t *testing.T, router *flamego.Flame, managerToken string) {
+	_, err := db.Teams.Create(context.Background(), db.CreateTeamOptions{Name: "Vidar"})
+	assert.Nil(t, err)
+
+	// CTFtime format by default.
+	req, err := http.NewRequest(http.MethodGet, "/api/manager/scoreboard/export", nil)
+	assert.Nil(t, err)
+	req.Header.Set("Authorization", managerToken)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"standings": [{"pos": 1, "team": "Vidar", "score": 0}]}`, w.Body.String())
+
+	req, err = http.NewRequest(http.MethodGet, "/api/manager/scoreboard/export?format=csv", nil)
+	assert.Nil(t, err)
+	req.Header.Set("Authorization", managerToken)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "text/csv; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Equal(t, "pos,team,score\n1,Vidar,0\n", w.Body.String())
+
+	// Unknown format.
+	req, err = http.NewRequest(http.MethodGet, "/api/manager/scoreboard/export?format=xml", nil)
+	assert.Nil(t, err)
+	req.Header.Set("Authorization", managerToken)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
@@ -0,0 +1,46 @@
+// Copyright 2021 E99p1ant. All rights reserved.
+// Use of this source code is governed by an AGPL-style
+// license that can be found in the LICENSE file.
+
+package route
+
+import (
+	log "unknwon.dev/clog/v2"
+
+	"github.com/vidar-team/Cardinal/internal/context"
+	"github.com/vidar-team/Cardinal/internal/db"
+)
+
+type SubmissionHandler struct{}
+
+func NewSubmissionHandler() *SubmissionHandler {
+	return &SubmissionHandler{}
+}
+
+// List returns the flag submission records with the given filters.
+func (*SubmissionHandler) List(ctx context.Context) error {
+	page := ctx.QueryInt("page")
+	pageSize := ctx.QueryInt("pageSize")
+	teamID := ctx.QueryInt("teamID")
+	round := ctx.QueryInt("round")
+	gameBoxID := ctx.QueryInt("gameBoxID")
+	status := ctx.Query("status")
+
+	submissions, totalCount, err := db.Submissions.Get(ctx.Request().Context(), db.GetSubmissionsOptions{
+		Page:      page,
+		PageSize:  pageSize,
+		TeamID:    uint(teamID),
+		Round:     uint(round),
+		GameBoxID: uint(gameBoxID),
+		Status:    status,
+	})
+	if err != nil {
+		log.Error("Failed to get submissions: %v", err)
+		return ctx.ServerError()
+	}
+
+	return ctx.Success(map[string]interface{}{
+		"List":  submissions,
+		"Count": totalCount,
+	})
+}
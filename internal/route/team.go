@@ -5,7 +5,10 @@
 package route
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
+	"io"
 	"time"
 
 	"github.com/thanhpk/randstr"
@@ -14,9 +17,11 @@ import (
 	"github.com/vidar-team/Cardinal/internal/clock"
 	"github.com/vidar-team/Cardinal/internal/context"
 	"github.com/vidar-team/Cardinal/internal/db"
+	"github.com/vidar-team/Cardinal/internal/flagutil"
 	"github.com/vidar-team/Cardinal/internal/form"
 	"github.com/vidar-team/Cardinal/internal/i18n"
 	"github.com/vidar-team/Cardinal/internal/rank"
+	"github.com/vidar-team/Cardinal/internal/ratelimit"
 )
 
 type TeamHandler struct{}
@@ -169,37 +174,85 @@ func (*TeamHandler) ResetPassword(ctx context.Context, l *i18n.Locale) error {
 }
 
 // SubmitFlag submits a flag.
-func (*TeamHandler) SubmitFlag(ctx context.Context, team *db.Team, f form.SubmitFlag) error {
-	flagStr := f.Flag
-
-	flag, err := db.Flags.Check(ctx.Request().Context(), flagStr)
+func (*TeamHandler) SubmitFlag(ctx context.Context, team *db.Team, f form.SubmitFlag, l *i18n.Locale) error {
+	results, err := flagutil.Submit(ctx.Request().Context(), team, ctx.ClientIP(), []string{f.Flag})
 	if err != nil {
-		if err == db.ErrFlagNotExists {
-			return ctx.Error(40000, "error flag")
-		}
-		log.Error("Failed to check flag: %v", err)
+		log.Error("Failed to submit flag: %v", err)
 		return ctx.ServerError()
 	}
 
-	// The team can only submit the other teams' current round flag.
-	if flag.TeamID == team.ID || flag.Round != clock.T.CurrentRound {
+	switch results[0].Status {
+	case flagutil.SubmitStatusAccepted:
+		return ctx.Success()
+	case flagutil.SubmitStatusNotRunning:
+		return ctx.Error(40000, l.T("timer.not_running"))
+	case flagutil.SubmitStatusDuplicate:
+		return ctx.Error(40000, l.T("flag.repeat"))
+	case flagutil.SubmitStatusLocked:
+		return ctx.Error(42900, l.T("flag.locked"))
+	default:
 		return ctx.Error(40000, "error flag")
 	}
+}
 
-	if _, err := db.Actions.Create(ctx.Request().Context(), db.CreateActionOptions{
-		Type:           db.ActionTypeAttack,
-		GameBoxID:      flag.GameBoxID,
-		AttackerTeamID: team.ID,
-		Round:          flag.Round,
-	}); err != nil {
-		log.Error("Failed to create new attack game box actions: %v", err)
+// SubmitFlags submits the flags in batch, and returns the status of each flag.
+func (*TeamHandler) SubmitFlags(ctx context.Context, team *db.Team, f form.SubmitFlags, l *i18n.Locale) error {
+	results, err := flagutil.Submit(ctx.Request().Context(), team, ctx.ClientIP(), f.Flags)
+	if err != nil {
+		log.Error("Failed to submit flags: %v", err)
 		return ctx.ServerError()
 	}
+	return ctx.Success(results)
+}
 
-	return ctx.Success()
+// SubmitRateLimiter rejects the flag submission if the team submits flags too frequently.
+// A token is taken for each flag, the rejected flags are recorded.
+func (*TeamHandler) SubmitRateLimiter(ctx context.Context, team *db.Team, l *i18n.Locale) error {
+	// The body is read here since the form is not bound yet, it is restored for the form binding.
+	body, err := io.ReadAll(ctx.Request().Request.Body)
+	if err != nil {
+		return ctx.Error(40000, l.T("general.error_payload"))
+	}
+	ctx.Request().Request.Body = io.NopCloser(bytes.NewReader(body))
+
+	// The malformed payload is counted as a flag, it is rejected by the form binding later.
+	var f struct {
+		Flag  string
+		Flags []string
+	}
+	_ = json.Unmarshal(body, &f)
+	flags := f.Flags
+	if len(flags) == 0 {
+		flags = []string{f.Flag}
+	}
+
+	if ratelimit.AllowN(ctx.Request().Context(), team, len(flags)) {
+		return nil
+	}
+
+	if err := flagutil.Reject(ctx.Request().Context(), team, ctx.ClientIP(), flags, flagutil.SubmitStatusRateLimited); err != nil {
+		log.Error("Failed to record rate limited flags: %v", err)
+	}
+	return ctx.Error(42900, l.T("flag.rate_limited"))
 }
 
 func (*TeamHandler) Info(ctx context.Context, team *db.Team) error {
+	frozen, err := rank.IsFrozen(ctx.Request().Context())
+	if err != nil {
+		log.Error("Failed to check rank frozen: %v", err)
+		return ctx.ServerError()
+	}
+
+	// The score and the rank of the team are the same as the frozen ranking list.
+	if frozen {
+		for _, rankItem := range rank.ForTeam() {
+			if rankItem.TeamID == team.ID {
+				team.Score = rankItem.Score
+				team.Rank = rankItem.Rank
+				break
+			}
+		}
+	}
 	return ctx.Success(team)
 }
 
@@ -212,7 +265,67 @@ func (*TeamHandler) GameBoxes(ctx context.Context, team *db.Team) error {
 		log.Error("Failed to get team game boxes: %v", err)
 		return ctx.ServerError()
 	}
-	return ctx.Success(gameBoxes)
+
+	// The check results of the current round, the private messages are only shown to the managers.
+	checkResults := make(map[uint]*db.CheckResult)
+	if _, round := clock.T.State(); round != 0 {
+		results, _, err := db.CheckResults.Get(ctx.Request().Context(), db.GetCheckResultsOptions{
+			TeamID: team.ID,
+			Round:  round,
+		})
+		if err != nil {
+			log.Error("Failed to get check results: %v", err)
+			return ctx.ServerError()
+		}
+		for _, result := range results {
+			checkResults[result.GameBoxID] = result
+		}
+	}
+
+	frozen, err := rank.IsFrozen(ctx.Request().Context())
+	if err != nil {
+		log.Error("Failed to check rank frozen: %v", err)
+		return ctx.ServerError()
+	}
+
+	// The status of the game boxes is the same as the frozen ranking list,
+	// and the score is hidden since it is not in the frozen ranking list.
+	frozenGameBoxes := make(map[uint]*db.GameBoxInfo)
+	if frozen {
+		for _, rankItem := range rank.ForTeam() {
+			if rankItem.TeamID == team.ID {
+				for _, info := range rankItem.GameBoxes {
+					frozenGameBoxes[info.ChallengeID] = info
+				}
+				break
+			}
+		}
+	}
+
+	type gameBox struct {
+		*db.GameBox
+		Score        *float64       `json:"Score"`
+		CheckStatus  db.CheckStatus `json:"CheckStatus"`
+		CheckMessage string         `json:"CheckMessage"`
+	}
+	teamGameBoxes := make([]*gameBox, 0, len(gameBoxes))
+	for _, box := range gameBoxes {
+		teamGameBox := &gameBox{GameBox: box}
+		if frozen {
+			box.IsDown, box.IsCaptured = false, false
+			if info, ok := frozenGameBoxes[box.ChallengeID]; ok {
+				box.IsDown, box.IsCaptured = info.IsDown, info.IsCaptured
+			}
+		} else {
+			teamGameBox.Score = &box.Score
+		}
+		if result, ok := checkResults[box.ID]; ok {
+			teamGameBox.CheckStatus = result.Status
+			teamGameBox.CheckMessage = result.PublicMessage
+		}
+		teamGameBoxes = append(teamGameBoxes, teamGameBox)
+	}
+	return ctx.Success(teamGameBoxes)
 }
 
 func (*TeamHandler) Bulletins(ctx context.Context) error {
@@ -225,8 +338,15 @@ func (*TeamHandler) Bulletins(ctx context.Context) error {
 }
 
 func (*TeamHandler) Rank(ctx context.Context) error {
+	frozen, err := rank.IsFrozen(ctx.Request().Context())
+	if err != nil {
+		log.Error("Failed to check rank frozen: %v", err)
+		return ctx.ServerError()
+	}
+
 	return ctx.Success(map[string]interface{}{
-		"Title": rank.Title(),
-		"Rank":  rank.ForTeam(),
+		"Title":  rank.Title(),
+		"Rank":   rank.ForTeam(),
+		"Frozen": frozen,
 	})
 }
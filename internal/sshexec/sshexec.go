@@ -0,0 +1,268 @@
+// Copyright 2021 E99p1ant. All rights reserved.
+// Use of this source code is governed by an AGPL-style
+// license that can be found in the LICENSE file.
+
+package sshexec
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/ssh"
+
+	"github.com/vidar-team/Cardinal/internal/timeutil"
+)
+
+// DefaultTimeout is the default timeout of connecting to the SSH server and executing the command.
+const DefaultTimeout = 10 * time.Second
+
+// Config contains the configuration to connect to the SSH server.
+type Config struct {
+	Host     string
+	Port     uint
+	User     string
+	Password string
+	// PrivateKey is the PEM encoded private key for the public key authentication.
+	PrivateKey []byte
+	// HostKey pins the host key of the SSH server, it can be the SHA256 fingerprint
+	// such as `SHA256:...`, or the public key in the authorized_keys format.
+	// The host key is not checked if it is empty.
+	HostKey string
+	// Timeout is the max duration of connecting, handshaking and executing the command,
+	// it is DefaultTimeout if not set. It is applied as the deadline of the connection,
+	// so the server which never responds can't block the caller.
+	Timeout time.Duration
+}
+
+// Result is the result of the command executed through SSH.
+type Result struct {
+	Stdout string
+	Stderr string
+	// ExitCode is -1 if the command was not executed or did not exit normally.
+	ExitCode int
+	Latency  time.Duration
+}
+
+var (
+	ErrHostKeyMismatch = errors.New("host key mismatch")
+	ErrTimeout         = errors.New("timeout")
+)
+
+// clientConfig returns the SSH client configuration of the config.
+func (c Config) clientConfig() (*ssh.ClientConfig, error) {
+	var auth []ssh.AuthMethod
+	if len(c.PrivateKey) != 0 {
+		signer, err := ssh.ParsePrivateKey(c.PrivateKey)
+		if err != nil {
+			return nil, errors.Wrap(err, "parse private key")
+		}
+		auth = append(auth, ssh.PublicKeys(signer))
+	}
+	if c.Password != "" {
+		auth = append(auth, ssh.Password(c.Password))
+	}
+
+	hostKeyCallback := ssh.InsecureIgnoreHostKey()
+	if c.HostKey != "" {
+		var err error
+		hostKeyCallback, err = pinnedHostKey(c.HostKey)
+		if err != nil {
+			return nil, errors.Wrap(err, "parse host key")
+		}
+	}
+
+	timeout := c.Timeout
+	if timeout == 0 {
+		timeout = DefaultTimeout
+	}
+
+	return &ssh.ClientConfig{
+		User:            c.User,
+		Auth:            auth,
+		HostKeyCallback: hostKeyCallback,
+		Timeout:         timeout,
+	}, nil
+}
+
+// pinnedHostKey returns the callback which only accepts the given host key.
+func pinnedHostKey(hostKey string) (ssh.HostKeyCallback, error) {
+	hostKey = strings.TrimSpace(hostKey)
+	if strings.HasPrefix(hostKey, "SHA256:") {
+		return func(_ string, _ net.Addr, key ssh.PublicKey) error {
+			if ssh.FingerprintSHA256(key) != hostKey {
+				return ErrHostKeyMismatch
+			}
+			return nil
+		}, nil
+	}
+
+	pinned, _, _, _, err := ssh.ParseAuthorizedKey([]byte(hostKey))
+	if err != nil {
+		return nil, err
+	}
+	return func(_ string, _ net.Addr, key ssh.PublicKey) error {
+		if !bytes.Equal(key.Marshal(), pinned.Marshal()) {
+			return ErrHostKeyMismatch
+		}
+		return nil
+	}, nil
+}
+
+// Run connects to the SSH server and executes the command. The connection is closed when
+// the context is done. The result is always returned with the output and the latency,
+// and the error is returned if the command can not be executed or exits with non-zero code.
+func Run(ctx context.Context, config Config, command string) (*Result, error) {
+	startAt := timeutil.Now()
+	result := &Result{ExitCode: -1}
+	err := run(ctx, config, command, result)
+	result.Latency = timeutil.Now().Sub(startAt)
+	if err != nil && ctx.Err() != nil {
+		return result, ctx.Err()
+	}
+	return result, err
+}
+
+func run(ctx context.Context, config Config, command string, result *Result) error {
+	clientConfig, err := config.clientConfig()
+	if err != nil {
+		return err
+	}
+
+	addr := net.JoinHostPort(config.Host, strconv.Itoa(int(config.Port)))
+	deadline := time.Now().Add(clientConfig.Timeout)
+	dialer := net.Dialer{Deadline: deadline}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return errors.Wrap(err, "dial")
+	}
+	defer func() { _ = conn.Close() }()
+
+	// The deadline covers the handshake and the command, the SSH library has no timeout for them.
+	if err := conn.SetDeadline(deadline); err != nil {
+		return errors.Wrap(err, "set deadline")
+	}
+	timeout := func(err error) error {
+		if time.Now().After(deadline) {
+			return ErrTimeout
+		}
+		return err
+	}
+
+	// Close the connection when the context is done, so the handshake and the command are interrupted.
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			_ = conn.Close()
+		case <-done:
+		}
+	}()
+
+	// The SSH library does not wrap the error of the host key callback, keep it to be returned.
+	var hostKeyErr error
+	hostKeyCallback := clientConfig.HostKeyCallback
+	clientConfig.HostKeyCallback = func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		hostKeyErr = hostKeyCallback(hostname, remote, key)
+		return hostKeyErr
+	}
+
+	sshConn, chans, reqs, err := ssh.NewClientConn(conn, addr, clientConfig)
+	if err != nil {
+		if hostKeyErr != nil {
+			return hostKeyErr
+		}
+		return timeout(errors.Wrap(err, "handshake"))
+	}
+	client := ssh.NewClient(sshConn, chans, reqs)
+	defer func() { _ = client.Close() }()
+
+	session, err := client.NewSession()
+	if err != nil {
+		return timeout(errors.Wrap(err, "new session"))
+	}
+	defer func() { _ = session.Close() }()
+
+	var stdout, stderr bytes.Buffer
+	session.Stdout = &stdout
+	session.Stderr = &stderr
+	err = session.Run(command)
+	result.Stdout = stdout.String()
+	result.Stderr = stderr.String()
+	if err != nil {
+		var exitErr *ssh.ExitError
+		if errors.As(err, &exitErr) {
+			result.ExitCode = exitErr.ExitStatus()
+			return &ExitError{Code: result.ExitCode, Stderr: result.Stderr}
+		}
+		return timeout(errors.Wrap(err, "run command"))
+	}
+
+	result.ExitCode = 0
+	return nil
+}
+
+// Task is the command to be executed in the SSH server.
+type Task struct {
+	Config  Config
+	Command string
+}
+
+// TaskResult is the result of the task, Err is nil if the command exits with zero code.
+type TaskResult struct {
+	*Result
+	Err error
+}
+
+// RunAll runs the tasks concurrently, there are at most `concurrency` tasks running at the same time.
+// The results are in the same order as the tasks.
+func RunAll(ctx context.Context, tasks []Task, concurrency int) []*TaskResult {
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+
+	results := make([]*TaskResult, len(tasks))
+	semaphore := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, task := range tasks {
+		i, task := i, task
+
+		wg.Add(1)
+		semaphore <- struct{}{}
+		go func() {
+			defer func() {
+				<-semaphore
+				wg.Done()
+			}()
+
+			result, err := Run(ctx, task.Config, task.Command)
+			results[i] = &TaskResult{
+				Result: result,
+				Err:    err,
+			}
+		}()
+	}
+	wg.Wait()
+
+	return results
+}
+
+// ExitError is returned when the command exits with non-zero code.
+type ExitError struct {
+	Code   int
+	Stderr string
+}
+
+func (e *ExitError) Error() string {
+	if e.Stderr == "" {
+		return fmt.Sprintf("exit with code %d", e.Code)
+	}
+	return fmt.Sprintf("exit with code %d: %s", e.Code, strings.TrimSpace(e.Stderr))
+}
@@ -0,0 +1,275 @@
+// Copyright 2021 E99p1ant. All rights reserved.
+// Use of this source code is governed by an AGPL-style
+// license that can be found in the LICENSE file.
+
+package sshexec
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+)
+
+// testServer is an in-process SSH server for testing. It accepts the password `passw0rd`
+// and the given public key, and handles the `exec` requests:
+//   - `exit <code>` writes `error` to stderr and exits with the code.
+//   - `sleep` blocks until the connection is closed.
+//   - others are echoed to stdout.
+type testServer struct {
+	addr    *net.TCPAddr
+	hostKey ssh.PublicKey
+}
+
+func newTestServer(t *testing.T, clientKey ssh.PublicKey) *testServer {
+	t.Helper()
+
+	_, hostPrivateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.Nil(t, err)
+	hostSigner, err := ssh.NewSignerFromKey(hostPrivateKey)
+	require.Nil(t, err)
+
+	config := &ssh.ServerConfig{
+		PasswordCallback: func(conn ssh.ConnMetadata, password []byte) (*ssh.Permissions, error) {
+			if conn.User() == "root" && string(password) == "passw0rd" {
+				return nil, nil
+			}
+			return nil, assert.AnError
+		},
+		PublicKeyCallback: func(conn ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			if clientKey != nil && bytes.Equal(key.Marshal(), clientKey.Marshal()) {
+				return nil, nil
+			}
+			return nil, assert.AnError
+		},
+	}
+	config.AddHostKey(hostSigner)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err)
+	t.Cleanup(func() { _ = listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go serveTestConn(conn, config)
+		}
+	}()
+
+	return &testServer{
+		addr:    listener.Addr().(*net.TCPAddr),
+		hostKey: hostSigner.PublicKey(),
+	}
+}
+
+func serveTestConn(conn net.Conn, config *ssh.ServerConfig) {
+	defer func() { _ = conn.Close() }()
+
+	_, chans, reqs, err := ssh.NewServerConn(conn, config)
+	if err != nil {
+		return
+	}
+	go ssh.DiscardRequests(reqs)
+
+	for newChannel := range chans {
+		if newChannel.ChannelType() != "session" {
+			_ = newChannel.Reject(ssh.UnknownChannelType, "unknown channel type")
+			continue
+		}
+		channel, requests, err := newChannel.Accept()
+		if err != nil {
+			return
+		}
+
+		go func() {
+			defer func() { _ = channel.Close() }()
+
+			for req := range requests {
+				if req.Type != "exec" {
+					_ = req.Reply(false, nil)
+					continue
+				}
+				var payload struct{ Command string }
+				_ = ssh.Unmarshal(req.Payload, &payload)
+				_ = req.Reply(true, nil)
+
+				var exitStatus uint32
+				switch command := payload.Command; {
+				case strings.HasPrefix(command, "exit "):
+					code, _ := strconv.Atoi(strings.TrimPrefix(command, "exit "))
+					exitStatus = uint32(code)
+					_, _ = channel.Stderr().Write([]byte("error"))
+				case command == "sleep":
+					time.Sleep(time.Minute)
+				default:
+					_, _ = channel.Write([]byte(command))
+				}
+				_, _ = channel.SendRequest("exit-status", false, ssh.Marshal(struct{ Status uint32 }{exitStatus}))
+				return
+			}
+		}()
+	}
+}
+
+func (s *testServer) config() Config {
+	return Config{
+		Host:     s.addr.IP.String(),
+		Port:     uint(s.addr.Port),
+		User:     "root",
+		Password: "passw0rd",
+	}
+}
+
+func TestRun(t *testing.T) {
+	_, clientPrivateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.Nil(t, err)
+	clientSigner, err := ssh.NewSignerFromKey(clientPrivateKey)
+	require.Nil(t, err)
+	der, err := x509.MarshalPKCS8PrivateKey(clientPrivateKey)
+	require.Nil(t, err)
+	clientPEM := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+
+	server := newTestServer(t, clientSigner.PublicKey())
+
+	t.Run("password", func(t *testing.T) {
+		result, err := Run(context.Background(), server.config(), "echo")
+		assert.Nil(t, err)
+		assert.Equal(t, 0, result.ExitCode)
+		assert.Equal(t, "echo", result.Stdout)
+		assert.NotZero(t, result.Latency)
+	})
+
+	t.Run("wrong password", func(t *testing.T) {
+		config := server.config()
+		config.Password = "wrong"
+		result, err := Run(context.Background(), config, "echo")
+		assert.NotNil(t, err)
+		assert.Equal(t, -1, result.ExitCode)
+	})
+
+	t.Run("private key", func(t *testing.T) {
+		config := server.config()
+		config.Password = ""
+		config.PrivateKey = clientPEM
+		result, err := Run(context.Background(), config, "echo")
+		assert.Nil(t, err)
+		assert.Equal(t, 0, result.ExitCode)
+	})
+
+	t.Run("exit code", func(t *testing.T) {
+		result, err := Run(context.Background(), server.config(), "exit 3")
+		assert.Equal(t, &ExitError{Code: 3, Stderr: "error"}, err)
+		assert.Equal(t, 3, result.ExitCode)
+		assert.Equal(t, "error", result.Stderr)
+	})
+
+	t.Run("host key fingerprint", func(t *testing.T) {
+		config := server.config()
+		config.HostKey = ssh.FingerprintSHA256(server.hostKey)
+		_, err := Run(context.Background(), config, "echo")
+		assert.Nil(t, err)
+	})
+
+	t.Run("host key authorized key", func(t *testing.T) {
+		config := server.config()
+		config.HostKey = string(ssh.MarshalAuthorizedKey(server.hostKey))
+		_, err := Run(context.Background(), config, "echo")
+		assert.Nil(t, err)
+	})
+
+	t.Run("host key mismatch", func(t *testing.T) {
+		config := server.config()
+		config.HostKey = ssh.FingerprintSHA256(clientSigner.PublicKey())
+		_, err := Run(context.Background(), config, "echo")
+		assert.True(t, errors.Is(err, ErrHostKeyMismatch))
+	})
+
+	t.Run("context timeout", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+		result, err := Run(ctx, server.config(), "sleep")
+		assert.Equal(t, context.DeadlineExceeded, err)
+		assert.Equal(t, -1, result.ExitCode)
+	})
+
+	t.Run("command timeout", func(t *testing.T) {
+		config := server.config()
+		config.Timeout = 100 * time.Millisecond
+		result, err := Run(context.Background(), config, "sleep")
+		assert.Equal(t, ErrTimeout, err)
+		assert.Equal(t, -1, result.ExitCode)
+	})
+
+	t.Run("handshake timeout", func(t *testing.T) {
+		// The server accepts the connection but never responds.
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		require.Nil(t, err)
+		t.Cleanup(func() { _ = listener.Close() })
+		go func() {
+			var conns []net.Conn
+			defer func() {
+				for _, conn := range conns {
+					_ = conn.Close()
+				}
+			}()
+			for {
+				conn, err := listener.Accept()
+				if err != nil {
+					return
+				}
+				conns = append(conns, conn)
+			}
+		}()
+
+		addr := listener.Addr().(*net.TCPAddr)
+		config := Config{
+			Host:     addr.IP.String(),
+			Port:     uint(addr.Port),
+			User:     "root",
+			Password: "passw0rd",
+			Timeout:  100 * time.Millisecond,
+		}
+		startAt := time.Now()
+		_, err = Run(context.Background(), config, "echo")
+		assert.Equal(t, ErrTimeout, err)
+		assert.Less(t, time.Since(startAt), 5*time.Second)
+	})
+}
+
+func TestRunAll(t *testing.T) {
+	server := newTestServer(t, nil)
+
+	tasks := make([]Task, 0, 10)
+	for i := 0; i < 10; i++ {
+		tasks = append(tasks, Task{
+			Config:  server.config(),
+			Command: "exit " + strconv.Itoa(i),
+		})
+	}
+
+	results := RunAll(context.Background(), tasks, 3)
+	assert.Len(t, results, 10)
+	for i, result := range results {
+		assert.Equal(t, i, result.ExitCode)
+		if i == 0 {
+			assert.Nil(t, result.Err)
+		} else {
+			assert.Equal(t, &ExitError{Code: i, Stderr: "error"}, result.Err)
+		}
+	}
+}
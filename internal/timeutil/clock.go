@@ -0,0 +1,68 @@
+// Copyright 2021 E99p1ant. All rights reserved.
+// Use of this source code is governed by an AGPL-style
+// license that can be found in the LICENSE file.
+
+package timeutil
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock is the source of the current time.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+	// After waits for the duration to elapse and then sends the current time on the returned channel.
+	After(d time.Duration) <-chan time.Time
+}
+
+var _ Clock = (*realClock)(nil)
+
+// realClock is the clock of the system time.
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+var (
+	mu    sync.RWMutex
+	clock Clock = realClock{}
+)
+
+// SetClock replaces the global time source with the given clock,
+// it returns a function to restore the previous one.
+func SetClock(c Clock) (restore func()) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	previous := clock
+	clock = c
+	return func() {
+		mu.Lock()
+		defer mu.Unlock()
+		clock = previous
+	}
+}
+
+func current() Clock {
+	mu.RLock()
+	defer mu.RUnlock()
+	return clock
+}
+
+// Now returns the current time of the global time source.
+func Now() time.Time {
+	return current().Now()
+}
+
+// After waits for the duration to elapse on the global time source,
+// and then sends the current time on the returned channel.
+func After(d time.Duration) <-chan time.Time {
+	return current().After(d)
+}
@@ -0,0 +1,90 @@
+// Copyright 2021 E99p1ant. All rights reserved.
+// Use of this source code is governed by an AGPL-style
+// license that can be found in the LICENSE file.
+
+package timeutil
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+var _ Clock = (*FakeClock)(nil)
+
+// FakeClock is a manually controlled clock used for testing.
+// The time only moves when Advance or Set is called.
+type FakeClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	waiters []*waiter
+}
+
+type waiter struct {
+	until time.Time
+	ch    chan time.Time
+}
+
+// NewFakeClock returns a FakeClock which starts at the given time.
+func NewFakeClock(now time.Time) *FakeClock {
+	return &FakeClock{now: now}
+}
+
+func (c *FakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *FakeClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ch := make(chan time.Time, 1)
+	if d <= 0 {
+		ch <- c.now
+		return ch
+	}
+
+	c.waiters = append(c.waiters, &waiter{until: c.now.Add(d), ch: ch})
+	return ch
+}
+
+// Advance moves the time forward by the given duration,
+// and fires the waiters whose time is up.
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	now := c.now.Add(d)
+	c.mu.Unlock()
+
+	c.Set(now)
+}
+
+// Set sets the time to the given time, and fires the waiters whose time is up.
+func (c *FakeClock) Set(now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = now
+
+	sort.Slice(c.waiters, func(i, j int) bool {
+		return c.waiters[i].until.Before(c.waiters[j].until)
+	})
+
+	waiters := c.waiters[:0]
+	for _, w := range c.waiters {
+		if now.Before(w.until) {
+			waiters = append(waiters, w)
+			continue
+		}
+		w.ch <- now
+	}
+	c.waiters = waiters
+}
+
+// Waiters returns the count of the waiters which are waiting for the time.
+func (c *FakeClock) Waiters() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.waiters)
+}
@@ -0,0 +1,50 @@
+// Copyright 2021 E99p1ant. All rights reserved.
+// Use of this source code is governed by an AGPL-style
+// license that can be found in the LICENSE file.
+
+package timeutil
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFakeClock(t *testing.T) {
+	start := time.Date(2021, 10, 3, 12, 0, 0, 0, time.Local)
+	c := NewFakeClock(start)
+	assert.Equal(t, start, c.Now())
+
+	short := c.After(time.Minute)
+	long := c.After(time.Hour)
+	assert.Equal(t, 2, c.Waiters())
+
+	c.Advance(30 * time.Second)
+	assert.Equal(t, start.Add(30*time.Second), c.Now())
+	select {
+	case <-short:
+		t.Fatal("unexpected fired waiter")
+	default:
+	}
+
+	c.Advance(30 * time.Second)
+	assert.Equal(t, start.Add(time.Minute), <-short)
+	assert.Equal(t, 1, c.Waiters())
+
+	c.Set(start.Add(2 * time.Hour))
+	assert.Equal(t, start.Add(2*time.Hour), <-long)
+	assert.Equal(t, 0, c.Waiters())
+
+	// Non-positive duration fires immediately.
+	assert.Equal(t, start.Add(2*time.Hour), <-c.After(0))
+}
+
+func TestSetClock(t *testing.T) {
+	start := time.Date(2021, 10, 3, 12, 0, 0, 0, time.Local)
+	restore := SetClock(NewFakeClock(start))
+	assert.Equal(t, start, Now())
+
+	restore()
+	assert.NotEqual(t, start, Now())
+}